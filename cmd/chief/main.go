@@ -1,16 +1,19 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/minicodemonkey/chief/internal/cmd"
 	"github.com/minicodemonkey/chief/internal/config"
 	"github.com/minicodemonkey/chief/internal/git"
+	"github.com/minicodemonkey/chief/internal/loop"
 	"github.com/minicodemonkey/chief/internal/prd"
 	"github.com/minicodemonkey/chief/internal/tui"
 )
@@ -26,6 +29,9 @@ type TUIOptions struct {
 	Merge         bool
 	Force         bool
 	NoRetry       bool
+	JSONFile      string      // Append NDJSON events to this file
+	PRDBudget     loop.Budget // Per-run budget from --max-cost etc.
+	StoryBudget   loop.Budget // Per-story budget from --story-max-cost etc.
 }
 
 func main() {
@@ -44,6 +50,18 @@ func main() {
 		case "list":
 			runList()
 			return
+		case "run":
+			runRun()
+			return
+		case "prompt":
+			runPrompt()
+			return
+		case "replay":
+			runReplay()
+			return
+		case "serve":
+			runServe()
+			return
 		case "help":
 			printHelp()
 			return
@@ -132,6 +150,11 @@ func parseTUIFlags() *TUIOptions {
 	for i := 1; i < len(os.Args); i++ {
 		arg := os.Args[i]
 
+		if next, ok := parseBudgetFlag(os.Args, i, &opts.PRDBudget, &opts.StoryBudget); ok {
+			i = next
+			continue
+		}
+
 		switch {
 		case arg == "--help" || arg == "-h":
 			printHelp()
@@ -147,6 +170,16 @@ func parseTUIFlags() *TUIOptions {
 			opts.Force = true
 		case arg == "--no-retry":
 			opts.NoRetry = true
+		case arg == "--json-file":
+			if i+1 < len(os.Args) {
+				i++
+				opts.JSONFile = os.Args[i]
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+		case strings.HasPrefix(arg, "--json-file="):
+			opts.JSONFile = strings.TrimPrefix(arg, "--json-file=")
 		case arg == "--max-iterations" || arg == "-n":
 			// Next argument should be the number
 			if i+1 < len(os.Args) {
@@ -282,6 +315,240 @@ func runList() {
 	}
 }
 
+func runPrompt() {
+	// Parse arguments: chief prompt show [name]
+	if len(os.Args) < 3 || os.Args[2] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: chief prompt show [name]")
+		os.Exit(1)
+	}
+
+	opts := cmd.PromptOptions{}
+	if len(os.Args) > 3 && !strings.HasPrefix(os.Args[3], "-") {
+		opts.Name = os.Args[3]
+	}
+
+	if err := cmd.RunPromptShow(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runReplay() {
+	opts := cmd.ReplayOptions{}
+
+	// Parse arguments: chief replay [name] [--run N] [--story ID] [--plain]
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--plain":
+			opts.Plain = true
+		case arg == "--run" || arg == "--story":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			i++
+			if arg == "--story" {
+				opts.Story = args[i]
+			} else {
+				opts.Run = parseRunValue(args[i])
+			}
+		case strings.HasPrefix(arg, "--run="):
+			opts.Run = parseRunValue(strings.TrimPrefix(arg, "--run="))
+		case strings.HasPrefix(arg, "--story="):
+			opts.Story = strings.TrimPrefix(arg, "--story=")
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Error: unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Run 'chief --help' for usage.\n")
+			os.Exit(1)
+		default:
+			opts.Name = arg
+		}
+	}
+
+	if err := cmd.RunReplay(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseRunValue parses a --run value, exiting on invalid input.
+func parseRunValue(val string) int {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		fmt.Fprintf(os.Stderr, "Error: invalid value for --run: %s\n", val)
+		os.Exit(1)
+	}
+	return n
+}
+
+func runRun() {
+	opts := cmd.RunOptions{}
+
+	// Parse arguments: chief run [name|path] [--max-iterations N] [--no-retry] [--json] [budget flags]
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if next, ok := parseBudgetFlag(args, i, &opts.PRDBudget, &opts.StoryBudget); ok {
+			i = next
+			continue
+		}
+		switch {
+		case arg == "--no-retry":
+			opts.NoRetry = true
+		case arg == "--json":
+			opts.JSON = true
+		case arg == "--max-iterations" || arg == "-n":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(cmd.ExitError)
+			}
+			i++
+			opts.MaxIterations = parseIterationsValue(arg, args[i])
+		case strings.HasPrefix(arg, "--max-iterations="):
+			opts.MaxIterations = parseIterationsValue("--max-iterations", strings.TrimPrefix(arg, "--max-iterations="))
+		case strings.HasPrefix(arg, "-n="):
+			opts.MaxIterations = parseIterationsValue("-n", strings.TrimPrefix(arg, "-n="))
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Error: unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Run 'chief --help' for usage.\n")
+			os.Exit(cmd.ExitError)
+		default:
+			if strings.HasSuffix(arg, ".json") {
+				opts.PRDPath = arg
+			} else {
+				opts.Name = arg
+			}
+		}
+	}
+
+	result, err := cmd.RunHeadless(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(result.ExitCode())
+}
+
+func runServe() {
+	opts := cmd.ServeOptions{}
+
+	// Parse arguments: chief serve [name...] [--max-iterations N] [--no-retry] [--http ADDR] [budget flags]
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if next, ok := parseBudgetFlag(args, i, &opts.PRDBudget, &opts.StoryBudget); ok {
+			i = next
+			continue
+		}
+		switch {
+		case arg == "--no-retry":
+			opts.NoRetry = true
+		case arg == "--max-iterations" || arg == "-n":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.MaxIterations = parseIterationsValue(arg, args[i])
+		case strings.HasPrefix(arg, "--max-iterations="):
+			opts.MaxIterations = parseIterationsValue("--max-iterations", strings.TrimPrefix(arg, "--max-iterations="))
+		case strings.HasPrefix(arg, "-n="):
+			opts.MaxIterations = parseIterationsValue("-n", strings.TrimPrefix(arg, "-n="))
+		case arg == "--http":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", arg)
+				os.Exit(1)
+			}
+			i++
+			opts.HTTPAddr = args[i]
+		case strings.HasPrefix(arg, "--http="):
+			opts.HTTPAddr = strings.TrimPrefix(arg, "--http=")
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintf(os.Stderr, "Error: unknown flag: %s\n", arg)
+			fmt.Fprintf(os.Stderr, "Run 'chief --help' for usage.\n")
+			os.Exit(1)
+		default:
+			opts.Start = append(opts.Start, arg)
+		}
+	}
+
+	if err := cmd.RunServe(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseIterationsValue parses a --max-iterations value, exiting on invalid input.
+func parseIterationsValue(flag, val string) int {
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid value for %s: %s\n", flag, val)
+		os.Exit(1)
+	}
+	if n < 1 {
+		fmt.Fprintf(os.Stderr, "Error: %s must be at least 1\n", flag)
+		os.Exit(1)
+	}
+	return n
+}
+
+// parseBudgetFlag parses the budget flags shared by the TUI and the run command.
+// It returns the index of the last argument consumed and whether args[i] was a
+// budget flag. Invalid values exit the process.
+func parseBudgetFlag(args []string, i int, prdBudget, storyBudget *loop.Budget) (int, bool) {
+	name, val, hasVal := strings.Cut(args[i], "=")
+	if !strings.HasPrefix(name, "--") {
+		return i, false
+	}
+
+	budget := prdBudget
+	limit := strings.TrimPrefix(name, "--")
+	if strings.HasPrefix(limit, "story-") {
+		budget = storyBudget
+		limit = strings.TrimPrefix(limit, "story-")
+	}
+	switch limit {
+	case "max-cost", "max-tokens", "max-duration":
+	default:
+		return i, false
+	}
+
+	if !hasVal {
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", name)
+			os.Exit(1)
+		}
+		i++
+		val = args[i]
+	}
+
+	switch limit {
+	case "max-cost":
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil || f <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid value for %s: %s (expected USD amount, e.g. 5 or 2.50)\n", name, val)
+			os.Exit(1)
+		}
+		budget.MaxCostUSD = f
+	case "max-tokens":
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 1 {
+			fmt.Fprintf(os.Stderr, "Error: invalid value for %s: %s\n", name, val)
+			os.Exit(1)
+		}
+		budget.MaxTokens = n
+	case "max-duration":
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid value for %s: %s (expected duration, e.g. 45m or 2h)\n", name, val)
+			os.Exit(1)
+		}
+		budget.MaxDuration = d
+	}
+	return i, true
+}
+
 func runTUIWithOptions(opts *TUIOptions) {
 	prdPath := opts.PRDPath
 
@@ -344,10 +611,16 @@ func runTUIWithOptions(opts *TUIOptions) {
 		fmt.Printf("Warning: failed to check conversion status: %v\n", err)
 	} else if needsConvert {
 		fmt.Println("prd.md is newer than prd.json, running conversion...")
+		cwd, _ := os.Getwd()
+		cfg, err := config.Load(cwd)
+		if err != nil {
+			cfg = config.Default()
+		}
 		convertOpts := prd.ConvertOptions{
-			PRDDir: prdDir,
-			Merge:  opts.Merge,
-			Force:  opts.Force,
+			PRDDir:        prdDir,
+			Merge:         opts.Merge,
+			Force:         opts.Force,
+			ClaudeCommand: cfg.Agent.ClaudeCommand(),
 		}
 		if err := prd.Convert(convertOpts); err != nil {
 			fmt.Printf("Error converting PRD: %v\n", err)
@@ -359,6 +632,12 @@ func runTUIWithOptions(opts *TUIOptions) {
 	app, err := tui.NewAppWithOptions(prdPath, opts.MaxIterations)
 	if err != nil {
 		// Check if this is a missing PRD file error
+		var wrapperErr *loop.WrapperError
+		if errors.As(err, &wrapperErr) {
+			fmt.Printf("Error: %v\n", err)
+			fmt.Println("Check agent.wrapper in .chief/config.yaml")
+			os.Exit(1)
+		}
 		if os.IsNotExist(err) || strings.Contains(err.Error(), "no such file") {
 			fmt.Printf("PRD not found: %s\n", prdPath)
 			fmt.Println()
@@ -387,7 +666,29 @@ func runTUIWithOptions(opts *TUIOptions) {
 
 	// Disable retry if requested
 	if opts.NoRetry {
-		app.DisableRetry()
+		if err := app.DisableRetry(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --no-retry can't be used: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Pass it to chief serve instead.")
+			os.Exit(1)
+		}
+	}
+
+	// Apply budgets from CLI flags on top of the config
+	if err := app.SetBudget(opts.PRDBudget, opts.StoryBudget); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: budget flags can't be used: %v\n", err)
+		fmt.Fprintln(os.Stderr, "Pass them to chief serve instead.")
+		os.Exit(1)
+	}
+
+	// Mirror loop events to an NDJSON file if requested
+	if opts.JSONFile != "" {
+		jsonFile, err := os.OpenFile(opts.JSONFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to open %s: %v\n", opts.JSONFile, err)
+			os.Exit(1)
+		}
+		defer jsonFile.Close()
+		app.SetEventWriter(loop.NewJSONEventWriter(jsonFile))
 	}
 
 	p := tea.NewProgram(app, tea.WithAltScreen())
@@ -399,6 +700,9 @@ func runTUIWithOptions(opts *TUIOptions) {
 
 	// Check for post-exit actions
 	if finalApp, ok := model.(tui.App); ok {
+		if finalApp.IsAttached() && finalApp.PostExitAction == tui.PostExitNone {
+			fmt.Println("Detached from chief serve; its loops keep running.")
+		}
 		switch finalApp.PostExitAction {
 		case tui.PostExitInit:
 			// Run new command then restart TUI
@@ -443,6 +747,10 @@ Commands:
   edit [name] [options]     Edit an existing PRD interactively
   status [name]             Show progress for a PRD (default: main)
   list                      List all PRDs with progress
+  run [name] [options]      Run the loop headless (no TUI), for CI and scripts
+  prompt show [name]        Print the agent prompt for a PRD's next iteration
+  replay [name] [options]   Replay past iterations from a PRD's claude.log
+  serve [name...] [options] Run loops in a daemon the TUI attaches to
   update                    Update Chief to the latest version
   help                      Show this help message
 
@@ -450,6 +758,13 @@ Global Options:
   --max-iterations N, -n N  Set maximum iterations (default: dynamic)
   --no-retry                Disable auto-retry on Claude crashes
   --verbose                 Show raw Claude output in log
+  --json-file PATH          Append loop events as NDJSON to PATH
+  --max-cost USD            Stop when a run has spent USD (e.g. 5 or 2.50)
+  --max-tokens N            Stop when a run has used N tokens
+  --max-duration D          Stop when a run has taken D (e.g. 45m, 2h)
+  --story-max-cost USD      Stop when a single story has spent USD
+  --story-max-tokens N      Stop when a single story has used N tokens
+  --story-max-duration D    Stop when a single story has taken D
   --merge                   Auto-merge progress on conversion conflicts
   --force                   Auto-overwrite on conversion conflicts
   --help, -h                Show this help message
@@ -459,6 +774,34 @@ Edit Options:
   --merge                   Auto-merge progress on conversion conflicts
   --force                   Auto-overwrite on conversion conflicts
 
+Run Options:
+  --max-iterations N, -n N  Set maximum iterations (default: dynamic)
+  --no-retry                Disable auto-retry on Claude crashes
+  --json                    Print events as NDJSON (one JSON object per line)
+  --max-cost, --max-tokens, --max-duration, --story-max-*
+                            Budgets, as in Global Options
+
+Serve Options:
+  --max-iterations N, -n N  Set default maximum iterations (default: dynamic)
+  --no-retry                Disable auto-retry on Claude crashes
+  --http ADDR               Serve the HTTP API on a localhost address (e.g. 127.0.0.1:7420)
+  --max-cost, --max-tokens, --max-duration, --story-max-*
+                            Budgets, as in Global Options
+
+Replay Options:
+  --run N                   Replay run N (default: the latest)
+  --story ID                Only replay iterations that worked on story ID
+  --plain                   Print the transcript instead of opening a pager
+
+Run Exit Codes:
+  0                         All stories complete
+  1                         Error
+  2                         Max iterations reached
+  3                         Budget exceeded
+  4                         All remaining stories blocked
+  5                         No progress
+  130                       Interrupted
+
 Positional Arguments:
   <name>                    PRD name (loads .chief/prds/<name>/prd.json)
   <path/to/prd.json>        Direct path to a prd.json file
@@ -481,6 +824,16 @@ Examples:
   chief status              Show progress for default PRD
   chief status auth         Show progress for auth PRD
   chief list                List all PRDs with progress
+  chief run auth -n 30      Run auth PRD headless with 30 max iterations
+  chief prompt show auth    Print the rendered prompt for auth PRD
+  chief replay auth --story US-003
+                            Replay the iterations that worked on US-003
+  chief run auth --max-cost 10 --story-max-duration 1h
+                            Run headless, stopping at $10 or a 1h story
+  nohup chief serve auth > .chief/serve.log 2>&1 &
+                            Run auth in a daemon that outlives the terminal
+  chief serve --http 127.0.0.1:7420
+                            Run the daemon with the HTTP API
   chief --version           Show version number`)
 }
 
@@ -22,6 +22,12 @@ var convertPromptTemplate string
 //go:embed detect_setup_prompt.txt
 var detectSetupPromptTemplate string
 
+//go:embed verify_failed_prompt.txt
+var verifyFailedPromptTemplate string
+
+//go:embed resume_prompt.txt
+var resumePromptTemplate string
+
 // GetPrompt returns the agent prompt with the PRD path substituted.
 func GetPrompt(prdPath string) string {
 	return strings.ReplaceAll(promptTemplate, "{{PRD_PATH}}", prdPath)
@@ -50,3 +56,16 @@ func GetConvertPrompt(prdContent string) string {
 func GetDetectSetupPrompt() string {
 	return detectSetupPromptTemplate
 }
+
+// GetResumePrompt returns the prompt sent when resuming an interrupted agent session.
+func GetResumePrompt() string {
+	return resumePromptTemplate
+}
+
+// GetVerifyFailedPrompt returns the prompt section that reports a failed
+// verification command for a story to the next iteration.
+func GetVerifyFailedPrompt(storyID, command, output string) string {
+	result := strings.ReplaceAll(verifyFailedPromptTemplate, "{{STORY_ID}}", storyID)
+	result = strings.ReplaceAll(result, "{{COMMAND}}", command)
+	return strings.ReplaceAll(result, "{{OUTPUT}}", output)
+}
@@ -104,3 +104,23 @@ func TestGetEditPrompt(t *testing.T) {
 		t.Error("Expected prompt to contain the PRD directory path")
 	}
 }
+
+func TestGetResumePrompt(t *testing.T) {
+	prompt := GetResumePrompt()
+	if !strings.Contains(prompt, "Continue where you left off") {
+		t.Errorf("Expected resume prompt to ask the agent to continue, got %q", prompt)
+	}
+}
+
+func TestGetVerifyFailedPrompt(t *testing.T) {
+	prompt := GetVerifyFailedPrompt("US-004", "go test ./...", "FAIL: TestLogin")
+
+	for _, want := range []string{"US-004", "`go test ./...`", "FAIL: TestLogin"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected prompt to contain %q", want)
+		}
+	}
+	if strings.Contains(prompt, "{{") {
+		t.Error("Expected all placeholders to be substituted")
+	}
+}
@@ -52,7 +52,8 @@ func RunEdit(opts EditOptions) error {
 	fmt.Println("Launching Claude to help you edit your PRD...")
 	fmt.Println()
 
-	if err := runInteractiveClaude(opts.BaseDir, prompt); err != nil {
+	claudeCmd := claudeCommand(opts.BaseDir)
+	if err := runInteractiveClaude(claudeCmd, opts.BaseDir, prompt); err != nil {
 		return fmt.Errorf("Claude session failed: %w", err)
 	}
 
@@ -60,9 +61,10 @@ func RunEdit(opts EditOptions) error {
 
 	// Run conversion from prd.md to prd.json with progress protection
 	convertOpts := ConvertOptions{
-		PRDDir: prdDir,
-		Merge:  opts.Merge,
-		Force:  opts.Force,
+		PRDDir:        prdDir,
+		Merge:         opts.Merge,
+		Force:         opts.Force,
+		ClaudeCommand: claudeCmd,
 	}
 	if err := RunConvertWithOptions(convertOpts); err != nil {
 		return fmt.Errorf("conversion failed: %w", err)
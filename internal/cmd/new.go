@@ -1,5 +1,5 @@
 // Package cmd provides CLI command implementations for Chief.
-// This includes new, edit, status, and list commands that can be
+// This includes new, edit, status, list, and run commands that can be
 // run from the command line without launching the full TUI.
 package cmd
 
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 
 	"github.com/minicodemonkey/chief/embed"
+	"github.com/minicodemonkey/chief/internal/config"
 	"github.com/minicodemonkey/chief/internal/prd"
 )
 
@@ -59,7 +60,8 @@ func RunNew(opts NewOptions) error {
 	fmt.Println("Launching Claude to help you create your PRD...")
 	fmt.Println()
 
-	if err := runInteractiveClaude(opts.BaseDir, prompt); err != nil {
+	claudeCmd := claudeCommand(opts.BaseDir)
+	if err := runInteractiveClaude(claudeCmd, opts.BaseDir, prompt); err != nil {
 		return fmt.Errorf("Claude session failed: %w", err)
 	}
 
@@ -72,7 +74,7 @@ func RunNew(opts NewOptions) error {
 	fmt.Println("\nPRD created successfully!")
 
 	// Run conversion from prd.md to prd.json
-	if err := RunConvert(prdDir); err != nil {
+	if err := RunConvertWithOptions(ConvertOptions{PRDDir: prdDir, ClaudeCommand: claudeCmd}); err != nil {
 		return fmt.Errorf("conversion failed: %w", err)
 	}
 
@@ -80,10 +82,19 @@ func RunNew(opts NewOptions) error {
 	return nil
 }
 
+// claudeCommand returns the Claude binary configured for the project in baseDir.
+func claudeCommand(baseDir string) string {
+	cfg, err := config.Load(baseDir)
+	if err != nil {
+		return "claude"
+	}
+	return cfg.Agent.ClaudeCommand()
+}
+
 // runInteractiveClaude launches an interactive Claude session in the specified directory.
-func runInteractiveClaude(workDir, prompt string) error {
+func runInteractiveClaude(claudeCmd, workDir, prompt string) error {
 	// Pass prompt as argument (not -p which is print mode / non-interactive)
-	cmd := exec.Command("claude", prompt)
+	cmd := exec.Command(claudeCmd, prompt)
 	cmd.Dir = workDir
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -94,9 +105,10 @@ func runInteractiveClaude(workDir, prompt string) error {
 
 // ConvertOptions contains configuration for the conversion command.
 type ConvertOptions struct {
-	PRDDir string // PRD directory containing prd.md
-	Merge  bool   // Auto-merge without prompting on conversion conflicts
-	Force  bool   // Auto-overwrite without prompting on conversion conflicts
+	PRDDir        string // PRD directory containing prd.md
+	Merge         bool   // Auto-merge without prompting on conversion conflicts
+	Force         bool   // Auto-overwrite without prompting on conversion conflicts
+	ClaudeCommand string // Claude binary to run (default: "claude")
 }
 
 // RunConvert converts prd.md to prd.json using Claude.
@@ -108,9 +120,10 @@ func RunConvert(prdDir string) error {
 // The Merge and Force flags will be fully implemented in US-019.
 func RunConvertWithOptions(opts ConvertOptions) error {
 	return prd.Convert(prd.ConvertOptions{
-		PRDDir: opts.PRDDir,
-		Merge:  opts.Merge,
-		Force:  opts.Force,
+		PRDDir:        opts.PRDDir,
+		Merge:         opts.Merge,
+		Force:         opts.Force,
+		ClaudeCommand: opts.ClaudeCommand,
 	})
 }
 
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/minicodemonkey/chief/embed"
+	"github.com/minicodemonkey/chief/internal/git"
+	"github.com/minicodemonkey/chief/internal/loop"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// PromptOptions contains configuration for the prompt show command.
+type PromptOptions struct {
+	Name    string    // PRD name (default: "main")
+	BaseDir string    // Base directory for .chief/prds/ (default: current directory)
+	Out     io.Writer // Destination for the prompt (default: os.Stdout)
+}
+
+// RunPromptShow prints the prompt the agent would receive for the next
+// iteration of a PRD, rendering the project's prompt template if there is one.
+func RunPromptShow(opts PromptOptions) error {
+	// Set defaults
+	if opts.Name == "" {
+		opts.Name = "main"
+	}
+	if opts.BaseDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		opts.BaseDir = cwd
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+
+	if !isValidPRDName(opts.Name) {
+		return fmt.Errorf("invalid PRD name %q: must contain only letters, numbers, hyphens, and underscores", opts.Name)
+	}
+	prdPath := filepath.Join(opts.BaseDir, ".chief", "prds", opts.Name, "prd.json")
+	if _, err := prd.LoadPRD(prdPath); err != nil {
+		return fmt.Errorf("failed to load PRD %q: %w", opts.Name, err)
+	}
+
+	tmpl, err := loop.LoadPromptTemplate(opts.BaseDir, prdPath)
+	if err != nil {
+		return err
+	}
+	if tmpl == nil {
+		fmt.Fprint(opts.Out, embed.GetPrompt(prdPath))
+		return nil
+	}
+
+	// Use the PRD's worktree when it has one, like the loop does
+	workDir := opts.BaseDir
+	if wt := git.WorktreePathForPRD(opts.BaseDir, opts.Name); git.IsWorktree(wt) {
+		workDir = wt
+	}
+
+	prompt, err := tmpl.Render(loop.NewPromptData(prdPath, workDir, 1))
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(opts.Out, prompt)
+	return nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePromptTestPRD creates .chief/prds/<name>/prd.json with two stories.
+func writePromptTestPRD(t *testing.T, baseDir, name string) string {
+	t.Helper()
+
+	prdDir := filepath.Join(baseDir, ".chief", "prds", name)
+	if err := os.MkdirAll(prdDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	prdJSON := `{
+  "project": "Test Project",
+  "userStories": [
+    {"id": "US-001", "title": "Story 1", "passes": true, "priority": 1},
+    {"id": "US-002", "title": "Story 2", "passes": false, "priority": 2}
+  ]
+}`
+	prdPath := filepath.Join(prdDir, "prd.json")
+	if err := os.WriteFile(prdPath, []byte(prdJSON), 0644); err != nil {
+		t.Fatalf("Failed to create prd.json: %v", err)
+	}
+	return prdPath
+}
+
+func TestRunPromptShowBuiltIn(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := writePromptTestPRD(t, tmpDir, "test")
+
+	var out bytes.Buffer
+	if err := RunPromptShow(PromptOptions{Name: "test", BaseDir: tmpDir, Out: &out}); err != nil {
+		t.Fatalf("RunPromptShow() returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), prdPath) {
+		t.Errorf("Expected the built-in prompt to reference %s, got:\n%s", prdPath, out.String())
+	}
+}
+
+func TestRunPromptShowTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePromptTestPRD(t, tmpDir, "test")
+
+	tmpl := "{{.Project}}: {{.Story.ID}} {{.Story.Title}} (iteration {{.Iteration}}) in {{.WorkDir}}"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".chief", "prompt.md"), []byte(tmpl), 0644); err != nil {
+		t.Fatalf("Failed to write prompt.md: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := RunPromptShow(PromptOptions{Name: "test", BaseDir: tmpDir, Out: &out}); err != nil {
+		t.Fatalf("RunPromptShow() returned error: %v", err)
+	}
+	want := "Test Project: US-002 Story 2 (iteration 1) in " + tmpDir
+	if out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+}
+
+func TestRunPromptShowMissingPRD(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	err := RunPromptShow(PromptOptions{Name: "nonexistent", BaseDir: tmpDir, Out: &bytes.Buffer{}})
+	if err == nil {
+		t.Error("Expected error for missing PRD")
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+	"github.com/minicodemonkey/chief/internal/loop"
+	"github.com/minicodemonkey/chief/internal/prd"
+	"github.com/minicodemonkey/chief/internal/tui"
+)
+
+// replayWidth is the width transcripts are printed at when stdout isn't a
+// terminal.
+const replayWidth = 100
+
+// ReplayOptions contains configuration for the replay command.
+type ReplayOptions struct {
+	Name    string    // PRD name (default: "main")
+	BaseDir string    // Base directory for .chief/prds/ (default: current directory)
+	Run     int       // Run to replay (default: the latest)
+	Story   string    // Only replay iterations that worked on this story
+	Plain   bool      // Print the transcript instead of opening the pager
+	Out     io.Writer // Destination for plain output (default: os.Stdout)
+}
+
+// RunReplay renders past iterations of a PRD from its claude.log, with the
+// same tool cards and highlighting as the TUI log view. It opens a pager on
+// a terminal, and prints plain text otherwise.
+func RunReplay(opts ReplayOptions) error {
+	// Set defaults
+	if opts.Name == "" {
+		opts.Name = "main"
+	}
+	if opts.BaseDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		opts.BaseDir = cwd
+	}
+	isTerminal := opts.Out == nil && term.IsTerminal(os.Stdout.Fd())
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+
+	if !isValidPRDName(opts.Name) {
+		return fmt.Errorf("invalid PRD name %q: must contain only letters, numbers, hyphens, and underscores", opts.Name)
+	}
+	prdPath := filepath.Join(opts.BaseDir, ".chief", "prds", opts.Name, "prd.json")
+	if _, err := prd.LoadPRD(prdPath); err != nil {
+		return fmt.Errorf("failed to load PRD %q: %w", opts.Name, err)
+	}
+
+	// Each run has its own claude.log; runs recorded before that share the
+	// one in the PRD directory
+	run := opts.Run
+	if latest := loop.LatestRun(prdPath); run == 0 && loop.LogExists(loop.RunLogPath(prdPath, latest)) {
+		run = latest
+	}
+	logPath, storyLogPath := loop.LogPath(prdPath), loop.StoryLogPath(prdPath, opts.Story)
+	if run > 0 && loop.LogExists(loop.RunLogPath(prdPath, run)) {
+		logPath, storyLogPath = loop.RunLogPath(prdPath, run), loop.RunStoryLogPath(prdPath, run, opts.Story)
+	}
+
+	transcripts, err := loop.ReadTranscripts(logPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read claude.log: %w", err)
+	}
+	// Stories worked on in parallel log to their own claude.log
+	if opts.Story != "" {
+		if storyTranscripts, err := loop.ReadTranscripts(storyLogPath); err == nil {
+			transcripts = mergeStoryTranscripts(transcripts, storyTranscripts, opts.Story)
+		}
+	}
+	transcripts, err = selectTranscripts(transcripts, run, opts.Story)
+	if err != nil {
+		return err
+	}
+	if len(transcripts) == 0 {
+		if opts.Story != "" {
+			return fmt.Errorf("no iterations of %s recorded in claude.log", opts.Story)
+		}
+		return fmt.Errorf("nothing recorded in claude.log for PRD %q", opts.Name)
+	}
+
+	title := opts.Name
+	if t := transcripts[0]; t.Run > 0 {
+		title += fmt.Sprintf(" · run %d", t.Run)
+	}
+	if opts.Story != "" {
+		title += " · " + opts.Story
+	}
+
+	if isTerminal && !opts.Plain {
+		return tui.RunReplay(title, transcripts)
+	}
+
+	width := replayWidth
+	if isTerminal {
+		if w, _, err := term.GetSize(os.Stdout.Fd()); err == nil && w > 0 {
+			width = w
+		}
+	}
+	lines, _ := tui.RenderTranscripts(transcripts, width)
+	for _, line := range lines {
+		if !isTerminal {
+			// Syntax highlighting is colored regardless of the terminal
+			line = ansi.Strip(line)
+		}
+		fmt.Fprintln(opts.Out, strings.TrimRight(line, " "))
+	}
+	return nil
+}
+
+// selectTranscripts picks the iterations of one run, the latest by default,
+// optionally only those that worked on a story.
+func selectTranscripts(transcripts []loop.Transcript, run int, story string) ([]loop.Transcript, error) {
+	if len(transcripts) == 0 {
+		return nil, nil
+	}
+	if run == 0 {
+		run = transcripts[len(transcripts)-1].Run
+	} else {
+		found := false
+		for _, t := range transcripts {
+			if t.Run == run {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("run %d not found in claude.log", run)
+		}
+	}
+
+	var selected []loop.Transcript
+	for _, t := range transcripts {
+		if t.Run == run && (story == "" || t.HasStory(story)) {
+			selected = append(selected, t)
+		}
+	}
+	return selected, nil
+}
+
+// mergeStoryTranscripts fills in the parallel rounds of a story, which the
+// PRD's claude.log only marks, with the story's own log. Each round of the
+// story's log starts over at iteration 1.
+func mergeStoryTranscripts(transcripts, storyTranscripts []loop.Transcript, story string) []loop.Transcript {
+	var rounds [][]loop.Transcript
+	for _, t := range storyTranscripts {
+		if n := len(rounds); n == 0 || t.Iteration == 1 || rounds[n-1][0].Run != t.Run {
+			rounds = append(rounds, nil)
+		}
+		t.Stories = []string{story}
+		rounds[len(rounds)-1] = append(rounds[len(rounds)-1], t)
+	}
+
+	var merged []loop.Transcript
+	for _, t := range transcripts {
+		if !t.HasStory(story) || len(t.Stories) < 2 || len(rounds) == 0 || rounds[0][0].Run != t.Run {
+			merged = append(merged, t)
+			continue
+		}
+		// A parallel round: show the story's iterations under the round's number
+		for _, st := range rounds[0] {
+			st.Iteration = t.Iteration
+			merged = append(merged, st)
+		}
+		rounds = rounds[1:]
+	}
+	// Rounds the PRD's log has no record of, e.g. after it was cleared
+	for _, round := range rounds {
+		merged = append(merged, round...)
+	}
+	return merged
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+// writeReplayLog writes a claude.log with two runs next to the PRD.
+func writeReplayLog(t *testing.T, prdPath string) {
+	t.Helper()
+
+	log := strings.Join([]string{
+		`[run] 1`,
+		`[iteration] 1 US-001`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"First run"}]}}`,
+		`[run] 2`,
+		`[iteration] 1 US-001`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"auth.go"}}]}}`,
+		`[iteration] 2 US-002`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Working on story two"}]}}`,
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(filepath.Dir(prdPath), "claude.log"), []byte(log), 0644); err != nil {
+		t.Fatalf("Failed to write claude.log: %v", err)
+	}
+}
+
+func TestRunReplayLatestRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := writePromptTestPRD(t, tmpDir, "test")
+	writeReplayLog(t, prdPath)
+
+	var out bytes.Buffer
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Out: &out}); err != nil {
+		t.Fatalf("RunReplay() returned error: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"Run 2 · Iteration 1 · US-001", "Read auth.go", "Run 2 · Iteration 2 · US-002", "Working on story two"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "First run") {
+		t.Errorf("Expected only the latest run, got:\n%s", got)
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("Expected plain output without escape codes, got %q", got)
+	}
+}
+
+func TestRunReplayRunAndStory(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := writePromptTestPRD(t, tmpDir, "test")
+	writeReplayLog(t, prdPath)
+
+	var out bytes.Buffer
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Run: 1, Out: &out}); err != nil {
+		t.Fatalf("RunReplay() returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "First run") {
+		t.Errorf("Expected run 1, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Story: "US-002", Out: &out}); err != nil {
+		t.Fatalf("RunReplay() returned error: %v", err)
+	}
+	if strings.Contains(out.String(), "auth.go") || !strings.Contains(out.String(), "Working on story two") {
+		t.Errorf("Expected only US-002's iteration, got:\n%s", out.String())
+	}
+
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Run: 7, Out: &out}); err == nil {
+		t.Error("Expected an error for a run that doesn't exist")
+	}
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Story: "US-009", Out: &out}); err == nil {
+		t.Error("Expected an error for a story with no iterations")
+	}
+}
+
+func TestRunReplayParallelStory(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := writePromptTestPRD(t, tmpDir, "test")
+	writeReplayLog(t, prdPath)
+
+	// Stories worked on in parallel have their own log, and the PRD's log
+	// only marks the round
+	f, err := os.OpenFile(filepath.Join(filepath.Dir(prdPath), "claude.log"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("\n[iteration] 3 US-003 US-004\n")
+	f.Close()
+
+	storyDir := filepath.Join(filepath.Dir(prdPath), "stories", "US-003")
+	if err := os.MkdirAll(storyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	storyLog := "[run] 2\n[iteration] 1 US-003\n" +
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Parallel work"}]}}` + "\n"
+	if err := os.WriteFile(filepath.Join(storyDir, "claude.log"), []byte(storyLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Story: "US-003", Out: &out}); err != nil {
+		t.Fatalf("RunReplay() returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Parallel work") {
+		t.Errorf("Expected the story's own log, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "Run 2 · Iteration 3 · US-003") || strings.Count(out.String(), "Iteration") != 1 {
+		t.Errorf("Expected the story's log in place of round 3, got:\n%s", out.String())
+	}
+}
+
+func TestRunReplayNoLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	writePromptTestPRD(t, tmpDir, "test")
+
+	var out bytes.Buffer
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Out: &out}); err == nil {
+		t.Error("Expected an error when nothing was logged")
+	}
+}
+
+func TestRunReplayRunLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := writePromptTestPRD(t, tmpDir, "test")
+
+	// Each run has its own log, and older runs may be compressed
+	for run, text := range map[int]string{1: "Old run", 2: "New run"} {
+		dir := filepath.Join(filepath.Dir(prdPath), "runs", fmt.Sprint(run))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		log := fmt.Sprintf("[run] %d\n[iteration] 1 US-002\n", run) +
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"` + text + `"}]}}` + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "claude.log"), []byte(log), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := loop.PruneRuns(prdPath, loop.LogRetention{Compress: true}, 2, time.Now()); err != nil {
+		t.Fatalf("PruneRuns failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Out: &out}); err != nil {
+		t.Fatalf("RunReplay() returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "New run") || strings.Contains(out.String(), "Old run") {
+		t.Errorf("Expected the latest run, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := RunReplay(ReplayOptions{Name: "test", BaseDir: tmpDir, Run: 1, Out: &out}); err != nil {
+		t.Fatalf("RunReplay() returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Old run") {
+		t.Errorf("Expected the compressed run 1, got:\n%s", out.String())
+	}
+}
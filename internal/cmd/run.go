@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/loop"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// RunOptions contains configuration for the headless run command.
+type RunOptions struct {
+	Name          string      // PRD name (default: "main")
+	PRDPath       string      // Direct path to prd.json (overrides Name)
+	BaseDir       string      // Base directory for .chief/prds/ (default: current directory)
+	MaxIterations int         // Maximum iterations (0 = remaining stories + 5)
+	NoRetry       bool        // Disable auto-retry on agent crashes
+	JSON          bool        // Write events as NDJSON instead of human-readable lines
+	Out           io.Writer   // Destination for event output (default: os.Stdout)
+	Agent         loop.Agent  // Agent override (default: from config)
+	PRDBudget     loop.Budget // Per-run budget (overrides config)
+	StoryBudget   loop.Budget // Per-story budget (overrides config)
+}
+
+// RunResult describes how a headless run ended.
+type RunResult int
+
+const (
+	RunComplete       RunResult = iota // All stories passed
+	RunError                           // The loop failed
+	RunMaxIterations                   // Max iterations reached with stories remaining
+	RunInterrupted                     // Interrupted by SIGINT/SIGTERM
+	RunBudgetExceeded                  // A cost, token or time budget was exceeded
+	RunBlocked                         // Every remaining story is blocked
+	RunNoProgress                      // Several iterations in a row changed nothing
+)
+
+// Exit codes for the headless run command.
+const (
+	ExitComplete       = 0
+	ExitError          = 1
+	ExitMaxIterations  = 2
+	ExitBudgetExceeded = 3
+	ExitBlocked        = 4
+	ExitNoProgress     = 5
+	ExitInterrupted    = 130
+)
+
+// String returns the string representation of a RunResult.
+func (r RunResult) String() string {
+	switch r {
+	case RunComplete:
+		return "complete"
+	case RunError:
+		return "error"
+	case RunMaxIterations:
+		return "max-iterations"
+	case RunInterrupted:
+		return "interrupted"
+	case RunBudgetExceeded:
+		return "budget-exceeded"
+	case RunBlocked:
+		return "blocked"
+	case RunNoProgress:
+		return "no-progress"
+	default:
+		return "unknown"
+	}
+}
+
+// ExitCode returns the process exit code for a RunResult.
+func (r RunResult) ExitCode() int {
+	switch r {
+	case RunComplete:
+		return ExitComplete
+	case RunMaxIterations:
+		return ExitMaxIterations
+	case RunInterrupted:
+		return ExitInterrupted
+	case RunBudgetExceeded:
+		return ExitBudgetExceeded
+	case RunBlocked:
+		return ExitBlocked
+	case RunNoProgress:
+		return ExitNoProgress
+	default:
+		return ExitError
+	}
+}
+
+// RunHeadless runs the agent loop for a PRD without the TUI, printing one
+// line per loop event. It returns when the PRD completes, the loop stops, or
+// the process receives SIGINT/SIGTERM.
+func RunHeadless(opts RunOptions) (RunResult, error) {
+	// Set defaults
+	if opts.Name == "" {
+		opts.Name = "main"
+	}
+	if opts.BaseDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return RunError, fmt.Errorf("failed to get current directory: %w", err)
+		}
+		opts.BaseDir = cwd
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+
+	// Status messages go to stderr in JSON mode so stdout stays pure NDJSON
+	info := opts.Out
+	printer := &eventPrinter{out: opts.Out}
+	if opts.JSON {
+		info = os.Stderr
+		printer.json = loop.NewJSONEventWriter(opts.Out)
+	}
+
+	prdPath := opts.PRDPath
+	if prdPath == "" {
+		if !isValidPRDName(opts.Name) {
+			return RunError, fmt.Errorf("invalid PRD name %q: must contain only letters, numbers, hyphens, and underscores", opts.Name)
+		}
+		prdPath = filepath.Join(opts.BaseDir, ".chief", "prds", opts.Name, "prd.json")
+	}
+	prdName := filepath.Base(filepath.Dir(prdPath))
+	printer.prdName = prdName
+
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		return RunError, fmt.Errorf("failed to load PRD %q: %w", prdName, err)
+	}
+	if p.AllComplete() {
+		fmt.Fprintf(info, "%s: all stories already complete\n", prdName)
+		return RunComplete, nil
+	}
+
+	if needsConvert, err := prd.NeedsConversion(filepath.Dir(prdPath)); err == nil && needsConvert {
+		fmt.Fprintf(info, "Warning: prd.md is newer than prd.json; run 'chief edit %s' to convert it\n", prdName)
+	}
+
+	maxIter := opts.MaxIterations
+	if maxIter <= 0 {
+		maxIter = remainingStories(p) + 5
+	}
+
+	cfg, err := config.Load(opts.BaseDir)
+	if err != nil {
+		return RunError, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager := loop.NewManager(maxIter)
+	manager.SetBaseDir(opts.BaseDir)
+	manager.SetConfig(cfg)
+	if opts.Agent != nil {
+		manager.SetAgent(opts.Agent)
+	}
+	if opts.NoRetry {
+		manager.DisableRetry()
+	}
+	manager.SetBudget(opts.PRDBudget, opts.StoryBudget)
+	if err := manager.Register(prdName, prdPath); err != nil {
+		return RunError, err
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	fmt.Fprintf(info, "Running %s (%d stories remaining, max %d iterations)\n", prdName, remainingStories(p), maxIter)
+	if err := manager.Start(prdName); err != nil {
+		return RunError, err
+	}
+	if instance := manager.GetInstance(prdName); instance != nil && instance.Profile == loop.ProfileUnrestricted {
+		fmt.Fprintln(info, "Warning: running without permission checks (permissions.skipPermissions is set)")
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		manager.Wait()
+		close(finished)
+	}()
+
+	result := RunError
+	for {
+		select {
+		case event := <-manager.Events():
+			result = printer.print(event, result)
+		case <-sigCh:
+			fmt.Fprintln(info, "Interrupted, stopping...")
+			manager.StopAll()
+			return RunInterrupted, nil
+		case <-finished:
+			// Drain events that were forwarded before the loop exited
+			for {
+				select {
+				case event := <-manager.Events():
+					result = printer.print(event, result)
+				default:
+					return printer.outcome(finalRunResult(manager, prdName, result))
+				}
+			}
+		}
+	}
+}
+
+// finalRunResult reconciles the result derived from events with the final loop state.
+func finalRunResult(manager *loop.Manager, prdName string, result RunResult) (RunResult, error) {
+	state, _, err := manager.GetState(prdName)
+	switch state {
+	case loop.LoopStateComplete:
+		return RunComplete, nil
+	case loop.LoopStateError:
+		return RunError, err
+	}
+	return result, nil
+}
+
+// eventPrinter writes loop events either as human-readable lines or as NDJSON.
+type eventPrinter struct {
+	out         io.Writer
+	json        *loop.JSONEventWriter
+	prdName     string    // PRD the run was started for
+	chainResult RunResult // How the PRDs chained after it ended, if one failed
+}
+
+// print writes an event and returns the updated run result.
+func (p *eventPrinter) print(mEvent loop.ManagerEvent, result RunResult) RunResult {
+	event := mEvent.Event
+	chained := p.prdName != "" && mEvent.PRDName != p.prdName
+	if p.json != nil {
+		p.json.Write(mEvent)
+	} else if line := FormatEvent(event); line != "" {
+		ts := event.Time
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		if chained {
+			line = "[" + mEvent.PRDName + "] " + line
+		}
+		fmt.Fprintf(p.out, "%s %s\n", ts.Format("15:04:05"), line)
+	}
+
+	next := result
+	switch event.Type {
+	case loop.EventComplete:
+		next = RunComplete
+	case loop.EventMaxIterationsReached:
+		next = RunMaxIterations
+	case loop.EventBudgetExceeded:
+		next = RunBudgetExceeded
+	case loop.EventAllStoriesBlocked:
+		next = RunBlocked
+	case loop.EventNoProgress:
+		next = RunNoProgress
+	case loop.EventError:
+		next = RunError
+	}
+
+	// PRDs chained after this one only change the outcome when they fail
+	if chained {
+		if next != result && next != RunComplete {
+			p.chainResult = next
+		}
+		return result
+	}
+	return next
+}
+
+// outcome reports the failure of a chained PRD in place of the run's success.
+func (p *eventPrinter) outcome(result RunResult, err error) (RunResult, error) {
+	if result == RunComplete && p.chainResult != RunComplete {
+		return p.chainResult, nil
+	}
+	return result, err
+}
+
+// FormatEvent returns a single human-readable line describing a loop event.
+// It returns an empty string for events that aren't worth printing.
+func FormatEvent(event loop.Event) string {
+	switch event.Type {
+	case loop.EventIterationStart:
+		return fmt.Sprintf("── Iteration %d ──", event.Iteration)
+	case loop.EventAssistantText:
+		text := strings.Join(strings.Fields(event.Text), " ")
+		if text == "" {
+			return ""
+		}
+		return "  " + text
+	case loop.EventToolStart:
+		if detail := toolDetail(event.ToolInput); detail != "" {
+			return fmt.Sprintf("  → %s %s", event.Tool, detail)
+		}
+		return "  → " + event.Tool
+	case loop.EventToolResult:
+		return ""
+	case loop.EventStoryStarted:
+		return "Working on " + event.StoryID
+	case loop.EventStoryCompleted:
+		return "Completed " + event.StoryID
+	case loop.EventComplete:
+		return "All stories complete!"
+	case loop.EventMaxIterationsReached:
+		return fmt.Sprintf("Max iterations reached (%d)", event.Iteration)
+	case loop.EventError:
+		if event.Err != nil {
+			return "Error: " + event.Err.Error()
+		}
+		return "Error"
+	case loop.EventRetrying:
+		return event.Text
+	case loop.EventBudgetExceeded:
+		return event.Text
+	case loop.EventVerificationStarted, loop.EventVerificationPassed:
+		return event.Text
+	case loop.EventStoryMerged, loop.EventStoryFailed, loop.EventStoryBlocked, loop.EventAllStoriesBlocked, loop.EventNoProgress:
+		return event.Text
+	case loop.EventVerificationFailed:
+		summary, _, _ := strings.Cut(event.Text, "\n")
+		return summary
+	case loop.EventIterationResult:
+		if event.Usage == nil {
+			return ""
+		}
+		return fmt.Sprintf("Iteration %d finished: %s, %d turns", event.Iteration, event.Usage.Summary(), event.Usage.NumTurns)
+	default:
+		return ""
+	}
+}
+
+// toolDetail extracts the most useful argument from a tool input for display.
+func toolDetail(input map[string]interface{}) string {
+	for _, key := range []string{"file_path", "command", "pattern", "path", "url"} {
+		if v, ok := input[key].(string); ok && v != "" {
+			v = strings.Join(strings.Fields(v), " ")
+			if len(v) > 120 {
+				v = v[:117] + "..."
+			}
+			return v
+		}
+	}
+	return ""
+}
+
+// remainingStories counts the stories that haven't passed yet.
+func remainingStories(p *prd.PRD) int {
+	remaining := 0
+	for _, story := range p.UserStories {
+		if !story.Passes {
+			remaining++
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minicodemonkey/chief/internal/loop"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// scriptedAgent is a loop.Agent that replays canned output and optionally
+// marks all stories as passed, so headless runs can be tested without Claude.
+type scriptedAgent struct {
+	prdPath  string
+	output   []string
+	complete bool
+	fail     bool
+}
+
+func (a *scriptedAgent) Name() string { return "Scripted" }
+
+func (a *scriptedAgent) Start(ctx context.Context, req loop.AgentRequest) (loop.AgentProcess, error) {
+	if a.complete {
+		// Without a PRD path, complete whichever PRD the loop runs
+		prdPath := a.prdPath
+		if prdPath == "" {
+			prdPath = filepath.Join(req.PRDDir, "prd.json")
+		}
+		p, err := prd.LoadPRD(prdPath)
+		if err != nil {
+			return nil, err
+		}
+		for i := range p.UserStories {
+			p.UserStories[i].Passes = true
+		}
+		if err := p.Save(prdPath); err != nil {
+			return nil, err
+		}
+	}
+	return &scriptedProcess{
+		stdout: strings.NewReader(strings.Join(a.output, "\n") + "\n"),
+		fail:   a.fail,
+	}, nil
+}
+
+func (a *scriptedAgent) ParseLine(line string) []loop.Event { return loop.ParseLine(line) }
+
+type scriptedProcess struct {
+	stdout io.Reader
+	fail   bool
+}
+
+func (p *scriptedProcess) Stdout() io.Reader { return p.stdout }
+func (p *scriptedProcess) Stderr() io.Reader { return strings.NewReader("") }
+func (p *scriptedProcess) Kill() error       { return nil }
+func (p *scriptedProcess) Wait() error {
+	if p.fail {
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+// createRunTestPRD writes a PRD with one incomplete story and returns its path.
+func createRunTestPRD(t *testing.T, baseDir, name string) string {
+	t.Helper()
+
+	prdDir := filepath.Join(baseDir, ".chief", "prds", name)
+	if err := os.MkdirAll(prdDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	prdJSON := `{
+  "project": "Run Project",
+  "userStories": [
+    {"id": "US-001", "title": "Story 1", "passes": false, "priority": 1}
+  ]
+}`
+	prdPath := filepath.Join(prdDir, "prd.json")
+	if err := os.WriteFile(prdPath, []byte(prdJSON), 0644); err != nil {
+		t.Fatalf("Failed to create prd.json: %v", err)
+	}
+	return prdPath
+}
+
+func TestRunHeadlessComplete(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createRunTestPRD(t, tmpDir, "test")
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{
+		Name:    "test",
+		BaseDir: tmpDir,
+		Out:     &out,
+		Agent: &scriptedAgent{
+			prdPath:  prdPath,
+			complete: true,
+			output: []string{
+				`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"1","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunHeadless() returned error: %v", err)
+	}
+	if result != RunComplete {
+		t.Errorf("expected result complete, got %s", result)
+	}
+	if result.ExitCode() != ExitComplete {
+		t.Errorf("expected exit code %d, got %d", ExitComplete, result.ExitCode())
+	}
+
+	for _, want := range []string{"Iteration 1", "Working on US-001", "→ Bash go test ./...", "All stories complete!"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunHeadlessJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createRunTestPRD(t, tmpDir, "test")
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{
+		Name:    "test",
+		BaseDir: tmpDir,
+		JSON:    true,
+		Out:     &out,
+		Agent: &scriptedAgent{
+			prdPath:  prdPath,
+			complete: true,
+			output: []string{
+				`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunHeadless() returned error: %v", err)
+	}
+	if result != RunComplete {
+		t.Errorf("expected result complete, got %s", result)
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			t.Fatalf("output line is not JSON: %q", scanner.Text())
+		}
+		if obj["prd"] != "test" {
+			t.Errorf("expected prd name on every line, got %v", obj["prd"])
+		}
+		types = append(types, obj["type"].(string))
+	}
+
+	want := []string{"IterationStart", "StoryStarted", "Complete"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("expected event types %v, got %v", want, types)
+	}
+}
+
+func TestRunHeadlessMaxIterations(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createRunTestPRD(t, tmpDir, "test")
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{
+		Name:          "test",
+		BaseDir:       tmpDir,
+		MaxIterations: 2,
+		Out:           &out,
+		Agent:         &scriptedAgent{prdPath: prdPath},
+	})
+	if err != nil {
+		t.Fatalf("RunHeadless() returned error: %v", err)
+	}
+	if result != RunMaxIterations {
+		t.Errorf("expected result max-iterations, got %s", result)
+	}
+	if result.ExitCode() != ExitMaxIterations {
+		t.Errorf("expected exit code %d, got %d", ExitMaxIterations, result.ExitCode())
+	}
+}
+
+func TestRunHeadlessError(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createRunTestPRD(t, tmpDir, "test")
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{
+		Name:    "test",
+		BaseDir: tmpDir,
+		NoRetry: true,
+		Out:     &out,
+		Agent:   &scriptedAgent{prdPath: prdPath, fail: true},
+	})
+	if err == nil {
+		t.Error("expected error from failing agent")
+	}
+	if result.ExitCode() != ExitError {
+		t.Errorf("expected exit code %d, got %d", ExitError, result.ExitCode())
+	}
+	if !strings.Contains(out.String(), "Error:") {
+		t.Errorf("expected error line in output, got:\n%s", out.String())
+	}
+}
+
+func TestRunHeadlessBudgetExceeded(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createRunTestPRD(t, tmpDir, "test")
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{
+		Name:          "test",
+		BaseDir:       tmpDir,
+		MaxIterations: 5,
+		Out:           &out,
+		PRDBudget:     loop.Budget{MaxCostUSD: 0.5},
+		Agent: &scriptedAgent{
+			prdPath: prdPath,
+			output: []string{
+				`{"type":"result","subtype":"success","total_cost_usd":0.75,"num_turns":3,"usage":{"input_tokens":10,"output_tokens":20}}`,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunHeadless() returned error: %v", err)
+	}
+	if result != RunBudgetExceeded {
+		t.Errorf("expected result budget-exceeded, got %s", result)
+	}
+	if result.ExitCode() != ExitBudgetExceeded {
+		t.Errorf("expected exit code %d, got %d", ExitBudgetExceeded, result.ExitCode())
+	}
+	if !strings.Contains(out.String(), "PRD budget exceeded: cost $0.75 reached limit of $0.50") {
+		t.Errorf("expected budget line in output, got:\n%s", out.String())
+	}
+}
+
+func TestRunHeadlessBlocked(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createRunTestPRD(t, tmpDir, "test")
+	if err := os.WriteFile(filepath.Join(tmpDir, ".chief", "config.yaml"), []byte("stories:\n  maxAttempts: 2\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{
+		Name:          "test",
+		BaseDir:       tmpDir,
+		MaxIterations: 5,
+		Out:           &out,
+		Agent: &scriptedAgent{
+			prdPath: prdPath,
+			output: []string{
+				`{"type":"assistant","message":{"content":[{"type":"text","text":"The test database won't start."}]}}`,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunHeadless() returned error: %v", err)
+	}
+	if result != RunBlocked {
+		t.Errorf("expected result blocked, got %s", result)
+	}
+	if result.ExitCode() != ExitBlocked {
+		t.Errorf("expected exit code %d, got %d", ExitBlocked, result.ExitCode())
+	}
+	if !strings.Contains(out.String(), "Blocked US-001 after 2 attempts: The test database won't start.") {
+		t.Errorf("expected blocked line in output, got:\n%s", out.String())
+	}
+
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		t.Fatalf("Failed to load PRD: %v", err)
+	}
+	story := p.UserStories[0]
+	if !story.Blocked || story.Attempts != 2 || story.BlockedReason != "The test database won't start." {
+		t.Errorf("expected US-001 blocked after 2 attempts, got %+v", story)
+	}
+}
+
+func TestRunHeadlessAlreadyComplete(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdDir := filepath.Join(tmpDir, ".chief", "prds", "done")
+	if err := os.MkdirAll(prdDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	prdJSON := `{"project": "Done", "userStories": [{"id": "US-001", "title": "Story", "passes": true}]}`
+	if err := os.WriteFile(filepath.Join(prdDir, "prd.json"), []byte(prdJSON), 0644); err != nil {
+		t.Fatalf("Failed to create prd.json: %v", err)
+	}
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{Name: "done", BaseDir: tmpDir, Out: &out})
+	if err != nil {
+		t.Fatalf("RunHeadless() returned error: %v", err)
+	}
+	if result != RunComplete {
+		t.Errorf("expected result complete, got %s", result)
+	}
+}
+
+func TestRunHeadlessMissingPRD(t *testing.T) {
+	result, err := RunHeadless(RunOptions{Name: "missing", BaseDir: t.TempDir(), Out: io.Discard})
+	if err == nil {
+		t.Error("expected error for missing PRD")
+	}
+	if result.ExitCode() != ExitError {
+		t.Errorf("expected exit code %d, got %d", ExitError, result.ExitCode())
+	}
+}
+
+func TestRunHeadlessChained(t *testing.T) {
+	tmpDir := t.TempDir()
+	createRunTestPRD(t, tmpDir, "backend")
+	frontend := createRunTestPRD(t, tmpDir, "frontend")
+	p, _ := prd.LoadPRD(frontend)
+	p.After = []string{"backend"}
+	if err := p.Save(frontend); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	result, err := RunHeadless(RunOptions{
+		Name:    "backend",
+		BaseDir: tmpDir,
+		Out:     &out,
+		Agent:   &scriptedAgent{complete: true},
+	})
+	if err != nil {
+		t.Fatalf("RunHeadless() returned error: %v", err)
+	}
+	if result != RunComplete {
+		t.Errorf("expected result complete, got %s", result)
+	}
+	if !strings.Contains(out.String(), "[frontend] All stories complete!") {
+		t.Errorf("expected the chained PRD's events in the output, got:\n%s", out.String())
+	}
+	if p, _ := prd.LoadPRD(frontend); !p.AllComplete() {
+		t.Error("expected the chained PRD to run after backend completed")
+	}
+}
+
+func TestEventPrinterChainedFailure(t *testing.T) {
+	printer := &eventPrinter{out: io.Discard, prdName: "backend"}
+	result := printer.print(loop.ManagerEvent{PRDName: "backend", Event: loop.Event{Type: loop.EventComplete}}, RunError)
+	result = printer.print(loop.ManagerEvent{PRDName: "frontend", Event: loop.Event{Type: loop.EventError}}, result)
+	if result != RunComplete {
+		t.Errorf("expected the chained PRD not to change backend's result, got %s", result)
+	}
+	if got, _ := printer.outcome(result, nil); got != RunError {
+		t.Errorf("expected the chained PRD's failure as the outcome, got %s", got)
+	}
+}
+
+func TestRunResultExitCodes(t *testing.T) {
+	codes := map[int]bool{}
+	for _, r := range []RunResult{RunComplete, RunError, RunMaxIterations, RunInterrupted, RunBudgetExceeded, RunBlocked, RunNoProgress} {
+		if codes[r.ExitCode()] {
+			t.Errorf("exit code %d for %s is not distinct", r.ExitCode(), r)
+		}
+		codes[r.ExitCode()] = true
+	}
+}
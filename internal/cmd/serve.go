@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/daemon"
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+// ServeOptions contains configuration for the serve command.
+type ServeOptions struct {
+	BaseDir       string          // Project directory (default: current directory)
+	Start         []string        // PRDs to start as soon as the daemon is up
+	MaxIterations int             // Default max iterations (0 = remaining stories + 5)
+	NoRetry       bool            // Disable auto-retry on agent crashes
+	PRDBudget     loop.Budget     // Per-run budget (overrides config)
+	StoryBudget   loop.Budget     // Per-story budget (overrides config)
+	Out           io.Writer       // Destination for event output (default: os.Stdout)
+	Agent         loop.Agent      // Agent override (default: from config)
+	HTTPAddr      string          // Localhost address for the HTTP API (empty = no HTTP API)
+	Stop          <-chan struct{} // Stops the daemon when closed, like SIGINT/SIGTERM
+}
+
+// RunServe runs the daemon: a loop manager that outlives any terminal,
+// controlled over a Unix socket in .chief/. The TUI attaches to it when it
+// is running. Loops are stopped when the daemon receives SIGINT or SIGTERM;
+// SIGHUP is ignored so closing the terminal leaves it running. With an
+// HTTPAddr it also serves the HTTP API, writing its token to .chief/serve.token.
+func RunServe(opts ServeOptions) error {
+	// Set defaults
+	if opts.BaseDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		opts.BaseDir = cwd
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+
+	cfg, err := config.Load(opts.BaseDir)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	manager := loop.NewManager(opts.MaxIterations)
+	manager.SetBaseDir(opts.BaseDir)
+	manager.SetConfig(cfg)
+	if opts.Agent != nil {
+		manager.SetAgent(opts.Agent)
+	}
+	if opts.NoRetry {
+		manager.DisableRetry()
+	}
+	manager.SetBudget(opts.PRDBudget, opts.StoryBudget)
+
+	// Register every PRD so clients can list them
+	prdsDir := filepath.Join(opts.BaseDir, ".chief", "prds")
+	entries, _ := os.ReadDir(prdsDir)
+	for _, entry := range entries {
+		prdPath := filepath.Join(prdsDir, entry.Name(), "prd.json")
+		if _, err := os.Stat(prdPath); entry.IsDir() && err == nil {
+			manager.Register(entry.Name(), prdPath)
+		}
+	}
+
+	server := daemon.NewServer(manager, opts.BaseDir)
+	server.SetEventHandler(func(event loop.ManagerEvent) {
+		if line := FormatEvent(event.Event); line != "" {
+			ts := event.Event.Time
+			if ts.IsZero() {
+				ts = time.Now()
+			}
+			fmt.Fprintf(opts.Out, "%s [%s] %s\n", ts.Format("15:04:05"), event.PRDName, line)
+		}
+	})
+	if err := server.Listen(); err != nil {
+		return err
+	}
+
+	var httpServer *http.Server
+	var httpAddr string
+	if opts.HTTPAddr != "" {
+		listener, err := daemon.ListenHTTP(opts.HTTPAddr)
+		if err != nil {
+			server.Close()
+			return err
+		}
+		token, err := daemon.NewToken()
+		if err != nil {
+			listener.Close()
+			server.Close()
+			return fmt.Errorf("failed to generate HTTP token: %w", err)
+		}
+		tokenPath := daemon.TokenPath(opts.BaseDir)
+		if err := os.WriteFile(tokenPath, []byte(token+"\n"), 0600); err != nil {
+			listener.Close()
+			server.Close()
+			return fmt.Errorf("failed to write HTTP token: %w", err)
+		}
+		defer os.Remove(tokenPath)
+
+		httpAddr = listener.Addr().String()
+		httpServer = &http.Server{Handler: server.HTTPHandler(token)}
+		go httpServer.Serve(listener)
+		defer httpServer.Close()
+	}
+
+	signal.Ignore(syscall.SIGHUP)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve()
+	}()
+	fmt.Fprintf(opts.Out, "Serving %d PRDs on %s\n", len(manager.GetAllInstances()), server.Socket())
+	if httpServer != nil {
+		fmt.Fprintf(opts.Out, "HTTP API on http://%s (token in %s)\n", httpAddr, daemon.TokenPath(opts.BaseDir))
+	}
+
+	for _, name := range opts.Start {
+		if !isValidPRDName(name) {
+			fmt.Fprintf(opts.Out, "Error: invalid PRD name %q\n", name)
+			continue
+		}
+		if err := manager.Start(name); err != nil {
+			fmt.Fprintf(opts.Out, "Error: failed to start %s: %v\n", name, err)
+		}
+	}
+
+	select {
+	case <-sigCh:
+	case <-opts.Stop:
+	case err := <-served:
+		manager.StopAll()
+		server.Close()
+		return err
+	}
+
+	// Stop the loops before the server, which keeps draining their events
+	fmt.Fprintln(opts.Out, "Stopping loops...")
+	manager.StopAll()
+	return server.Close()
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/daemon"
+	"github.com/minicodemonkey/chief/internal/loop"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// lockedBuffer is a buffer the daemon's event handler can write to while
+// the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// dialDaemon connects to the daemon of a project once it is listening.
+func dialDaemon(t *testing.T, baseDir string) *daemon.Client {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c, err := daemon.Dial(daemon.SocketPath(baseDir))
+		if err == nil {
+			return c
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Daemon not reachable: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunServe(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createRunTestPRD(t, tmpDir, "auth")
+	createRunTestPRD(t, tmpDir, "api")
+
+	var out lockedBuffer
+	stop := make(chan struct{})
+	served := make(chan error, 1)
+	go func() {
+		served <- RunServe(ServeOptions{
+			BaseDir: tmpDir,
+			Out:     &out,
+			Agent:   &scriptedAgent{complete: true},
+			Stop:    stop,
+		})
+	}()
+
+	c := dialDaemon(t, tmpDir)
+	defer c.Close()
+	if len(c.GetAllInstances()) != 2 {
+		t.Fatalf("Expected both PRDs to be registered, got %d", len(c.GetAllInstances()))
+	}
+	if err := c.Start("auth"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case event := <-c.Events():
+			done = event.Event.Type == loop.EventComplete
+		case <-timeout:
+			t.Fatal("Timed out waiting for auth to complete")
+		}
+	}
+	if p, _ := prd.LoadPRD(prdPath); !p.AllComplete() {
+		t.Error("Expected the daemon to run auth to completion")
+	}
+
+	close(stop)
+	if err := <-served; err != nil {
+		t.Fatalf("RunServe returned error: %v", err)
+	}
+	if _, err := os.Stat(daemon.SocketPath(tmpDir)); !os.IsNotExist(err) {
+		t.Error("Expected the socket to be removed on exit")
+	}
+	if !strings.Contains(out.String(), "[auth] All stories complete!") {
+		t.Errorf("Expected the daemon to print auth's events, got:\n%s", out.String())
+	}
+}
+
+func TestRunServeAlreadyRunning(t *testing.T) {
+	tmpDir := t.TempDir()
+	stop := make(chan struct{})
+	defer close(stop)
+	go RunServe(ServeOptions{BaseDir: tmpDir, Out: &lockedBuffer{}, Stop: stop})
+
+	dialDaemon(t, tmpDir).Close()
+	if err := RunServe(ServeOptions{BaseDir: tmpDir, Out: &lockedBuffer{}}); err != daemon.ErrRunning {
+		t.Errorf("Expected ErrRunning, got %v", err)
+	}
+}
+
+func TestRunServeHTTP(t *testing.T) {
+	tmpDir := t.TempDir()
+	createRunTestPRD(t, tmpDir, "auth")
+
+	var out lockedBuffer
+	stop := make(chan struct{})
+	served := make(chan error, 1)
+	go func() {
+		served <- RunServe(ServeOptions{
+			BaseDir:  tmpDir,
+			Out:      &out,
+			Agent:    &scriptedAgent{complete: true},
+			HTTPAddr: "127.0.0.1:0",
+			Stop:     stop,
+		})
+	}()
+	dialDaemon(t, tmpDir).Close()
+
+	// The address is only known from the output, as the port is picked on listen
+	var addr string
+	deadline := time.Now().Add(5 * time.Second)
+	for addr == "" {
+		if _, rest, ok := strings.Cut(out.String(), "HTTP API on http://"); ok {
+			addr, _, _ = strings.Cut(rest, " ")
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Expected the HTTP address in the output, got:\n%s", out.String())
+		} else {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	tokenPath := daemon.TokenPath(tmpDir)
+	info, err := os.Stat(tokenPath)
+	if err != nil {
+		t.Fatalf("Expected the token to be written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the token file to be private, got %v", info.Mode().Perm())
+	}
+	token, _ := os.ReadFile(tokenPath)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr+"/api/prds/auth/start", nil)
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Start request failed: %v", err)
+	}
+	var r daemon.Response
+	json.NewDecoder(resp.Body).Decode(&r)
+	resp.Body.Close()
+	if !r.OK {
+		t.Fatalf("Expected the token to start auth, got %d: %s", resp.StatusCode, r.Error)
+	}
+
+	close(stop)
+	if err := <-served; err != nil {
+		t.Fatalf("RunServe returned error: %v", err)
+	}
+	if _, err := os.Stat(tokenPath); !os.IsNotExist(err) {
+		t.Error("Expected the token file to be removed on exit")
+	}
+}
+
+func TestRunServeHTTPRequiresLocalhost(t *testing.T) {
+	tmpDir := t.TempDir()
+	err := RunServe(ServeOptions{BaseDir: tmpDir, Out: &lockedBuffer{}, HTTPAddr: "0.0.0.0:0"})
+	if err == nil || !strings.Contains(err.Error(), "only listens on localhost") {
+		t.Errorf("Expected a non-loopback address to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(daemon.SocketPath(tmpDir)); !os.IsNotExist(err) {
+		t.Error("Expected the socket to be removed after the error")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/minicodemonkey/chief/internal/loop"
 	"github.com/minicodemonkey/chief/internal/prd"
 )
 
@@ -41,12 +43,16 @@ func RunStatus(opts StatusOptions) error {
 	// Count completed stories
 	total := len(p.UserStories)
 	completed := 0
+	blocked := 0
 	var incomplete []prd.UserStory
 	for _, story := range p.UserStories {
 		if story.Passes {
 			completed++
 		} else {
 			incomplete = append(incomplete, story)
+			if story.Blocked {
+				blocked++
+			}
 		}
 	}
 
@@ -60,13 +66,26 @@ func RunStatus(opts StatusOptions) error {
 	}
 
 	fmt.Printf("%d/%d stories complete\n", completed, total)
+	if blocked > 0 {
+		fmt.Printf("%d blocked after too many attempts (remove \"blocked\" and \"attempts\" from prd.json to retry)\n", blocked)
+	}
+
+	// Print accumulated cost, if any runs have reported usage
+	if usage, err := loop.LoadUsage(prdPath); err == nil && !usage.Total.IsZero() {
+		fmt.Printf("Cost: %s over %d iterations\n", usage.Total.Summary(), usage.Total.Iterations)
+	}
 
 	// Print incomplete stories
 	if len(incomplete) > 0 {
 		fmt.Println("\nIncomplete stories:")
-		for _, story := range incomplete {
+		for i := range incomplete {
+			story := &incomplete[i]
 			status := ""
-			if story.InProgress {
+			if story.Blocked {
+				status = fmt.Sprintf(" (blocked after %d attempts: %s)", story.Attempts, story.BlockedReason)
+			} else if blockedBy := p.BlockedBy(story); len(blockedBy) > 0 {
+				status = " (blocked by " + strings.Join(blockedBy, ", ") + ")"
+			} else if story.InProgress {
 				status = " (in progress)"
 			}
 			fmt.Printf("  %s: %s%s\n", story.ID, story.Title, status)
@@ -11,8 +11,18 @@ const configFile = ".chief/config.yaml"
 
 // Config holds project-level settings for Chief.
 type Config struct {
-	Worktree   WorktreeConfig   `yaml:"worktree"`
-	OnComplete OnCompleteConfig `yaml:"onComplete"`
+	Worktree    WorktreeConfig    `yaml:"worktree"`
+	OnComplete  OnCompleteConfig  `yaml:"onComplete"`
+	Agent       AgentConfig       `yaml:"agent,omitempty"`
+	Budget      BudgetConfig      `yaml:"budget,omitempty"`
+	Timeouts    TimeoutConfig     `yaml:"timeouts,omitempty"`
+	Verify      VerifyConfig      `yaml:"verify,omitempty"`
+	Parallel    ParallelConfig    `yaml:"parallel,omitempty"`
+	Loops       LoopsConfig       `yaml:"loops,omitempty"`
+	Chains      ChainsConfig      `yaml:"chains,omitempty"`
+	Permissions PermissionsConfig `yaml:"permissions,omitempty"`
+	Stories     StoriesConfig     `yaml:"stories,omitempty"`
+	Logs        LogsConfig        `yaml:"logs,omitempty"`
 }
 
 // WorktreeConfig holds worktree-related settings.
@@ -26,6 +36,126 @@ type OnCompleteConfig struct {
 	CreatePR bool `yaml:"createPR"`
 }
 
+// AgentConfig selects the coding agent that runs each loop iteration.
+type AgentConfig struct {
+	Provider      string   `yaml:"provider,omitempty"`      // "claude" (default) or "command"
+	Command       string   `yaml:"command,omitempty"`       // Binary to run (default: "claude")
+	Args          []string `yaml:"args,omitempty"`          // Arguments for the command provider ({{PROMPT}} and {{WORK_DIR}} are substituted)
+	Output        string   `yaml:"output,omitempty"`        // Output format for the command provider: "stream-json" (default) or "text"
+	ResumeOnRetry bool     `yaml:"resumeOnRetry,omitempty"` // Continue a crashed Claude session with --resume instead of starting over
+	Model         string   `yaml:"model,omitempty"`         // Default model for every iteration (PRDs and stories can override it)
+	ExtraArgs     []string `yaml:"extraArgs,omitempty"`     // Extra arguments for every iteration (e.g. --max-turns 50)
+	Wrapper       []string `yaml:"wrapper,omitempty"`       // Command the agent is launched through, e.g. a sandbox ({{WORK_DIR}} and {{PRD_DIR}} are substituted)
+}
+
+// Agent provider names.
+const (
+	AgentProviderClaude  = "claude"
+	AgentProviderCommand = "command"
+)
+
+// ClaudeCommand returns the binary used for Claude Code sessions (PRD creation,
+// editing and conversion). A custom command is only honored for the claude
+// provider, since other agents don't understand Claude's flags.
+func (a AgentConfig) ClaudeCommand() string {
+	if a.Command != "" && (a.Provider == "" || a.Provider == AgentProviderClaude) {
+		return a.Command
+	}
+	return "claude"
+}
+
+// BudgetConfig holds spending limits that stop a loop once exceeded.
+type BudgetConfig struct {
+	PRD   BudgetLimits `yaml:"prd,omitempty"`   // Limits for a whole run of a PRD
+	Story BudgetLimits `yaml:"story,omitempty"` // Limits for each story within a run
+}
+
+// BudgetLimits caps cost, tokens and wall-clock time. Zero values mean no limit.
+type BudgetLimits struct {
+	MaxCostUSD  float64 `yaml:"maxCostUsd,omitempty"`  // Maximum spend in USD
+	MaxTokens   int     `yaml:"maxTokens,omitempty"`   // Maximum input, output and cache tokens
+	MaxDuration string  `yaml:"maxDuration,omitempty"` // Maximum wall-clock time (e.g. "45m", "2h")
+}
+
+// TimeoutConfig holds stall detection settings for agent iterations.
+// Durations use Go syntax (e.g. "30m", "2h"); "0" disables a timeout.
+type TimeoutConfig struct {
+	Inactivity string `yaml:"inactivity,omitempty"` // Kill the agent after this long without output (default: 30m)
+	Iteration  string `yaml:"iteration,omitempty"`  // Kill the agent when one iteration runs this long (default: 2h)
+}
+
+// VerifyConfig holds the commands Chief runs to check a story before accepting
+// it as passing. Commands run with "sh -c" in the PRD's working directory.
+type VerifyConfig struct {
+	Commands []string            `yaml:"commands,omitempty"` // Run for every story
+	Stories  map[string][]string `yaml:"stories,omitempty"`  // Additional commands by story ID
+}
+
+// CommandsFor returns the verification commands for a story.
+func (v VerifyConfig) CommandsFor(storyID string) []string {
+	cmds := append([]string(nil), v.Commands...)
+	return append(cmds, v.Stories[storyID]...)
+}
+
+// ParallelConfig holds settings for working on several stories of one PRD at once.
+type ParallelConfig struct {
+	MaxStories int `yaml:"maxStories,omitempty"` // Independent stories to run at once, each in its own worktree (default: 1)
+}
+
+// LoopsConfig holds settings for running several PRDs at once.
+type LoopsConfig struct {
+	MaxConcurrent int    `yaml:"maxConcurrent,omitempty"` // PRDs that may run at once; starting more queues them (0 = no limit)
+	QueueOrder    string `yaml:"queueOrder,omitempty"`    // Order queued PRDs start in: "fifo" (default) or "priority"
+}
+
+// Queue orders.
+const (
+	QueueOrderFIFO     = "fifo"
+	QueueOrderPriority = "priority"
+)
+
+// ChainsConfig holds the PRD chains, keyed by the name of the PRD that runs
+// after the others.
+type ChainsConfig map[string]ChainConfig
+
+// ChainConfig makes a PRD start automatically once the PRDs it runs after
+// have completed. It overrides the PRD's own "after" in prd.json.
+type ChainConfig struct {
+	After           []string `yaml:"after,omitempty"`           // PRDs that must complete first
+	BranchFromAfter bool     `yaml:"branchFromAfter,omitempty"` // Base the worktree branch on the predecessor's branch instead of the default branch
+}
+
+// StoriesConfig holds settings for how the loop works through stories.
+type StoriesConfig struct {
+	MaxAttempts       int `yaml:"maxAttempts,omitempty"`       // Iterations a story may take before it's blocked and skipped (0 = no limit)
+	MaxIdleIterations int `yaml:"maxIdleIterations,omitempty"` // Iterations in a row that may change nothing before the loop stops (default: 3, -1 = no limit)
+}
+
+// LogsConfig holds retention settings for the logs and event journals Chief
+// keeps for each run of a PRD. Runs are only removed by age or size when a
+// limit is set.
+type LogsConfig struct {
+	MaxAge    string `yaml:"maxAge,omitempty"`    // Delete runs that ended longer ago than this (e.g. "720h")
+	MaxSizeMB int    `yaml:"maxSizeMb,omitempty"` // Delete the oldest runs once a PRD's runs take up more than this many megabytes
+	Compress  bool   `yaml:"compress,omitempty"`  // Gzip the logs of finished runs
+}
+
+// PermissionsConfig selects the permission profile the agent runs under.
+// Without a profile, loops run under Chief's built-in default profile.
+type PermissionsConfig struct {
+	Profile         string                       `yaml:"profile,omitempty"`         // Name of the profile in Profiles to run loops under
+	SkipPermissions bool                         `yaml:"skipPermissions,omitempty"` // Opt in to running without permission checks (--dangerously-skip-permissions)
+	Profiles        map[string]PermissionProfile `yaml:"profiles,omitempty"`        // Named profiles
+}
+
+// PermissionProfile restricts what the agent may do during an iteration.
+type PermissionProfile struct {
+	AllowedTools    []string `yaml:"allowedTools,omitempty"`    // Tools the agent may use (e.g. Read, Grep, Edit)
+	DisallowedTools []string `yaml:"disallowedTools,omitempty"` // Tools the agent may never use, even if allowed elsewhere
+	Bash            []string `yaml:"bash,omitempty"`            // Allowed Bash command patterns (e.g. "npm test", "git commit:*")
+	WritablePaths   []string `yaml:"writablePaths,omitempty"`   // Paths the agent may edit and write (e.g. "src/**")
+}
+
 // Default returns a Config with zero-value defaults.
 func Default() *Config {
 	return &Config{}
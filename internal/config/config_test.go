@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -82,3 +83,89 @@ func TestExists(t *testing.T) {
 		t.Error("expected Exists to return true for existing config")
 	}
 }
+
+func TestAgentClaudeCommand(t *testing.T) {
+	tests := []struct {
+		agent AgentConfig
+		want  string
+	}{
+		{AgentConfig{}, "claude"},
+		{AgentConfig{Command: "/opt/bin/claude-pinned"}, "/opt/bin/claude-pinned"},
+		{AgentConfig{Provider: "claude", Command: "claude-wrapper"}, "claude-wrapper"},
+		{AgentConfig{Provider: "command", Command: "other-agent"}, "claude"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.agent.ClaudeCommand(); got != tt.want {
+			t.Errorf("ClaudeCommand() for %+v = %q, want %q", tt.agent, got, tt.want)
+		}
+	}
+}
+
+func TestSaveOmitsEmptyAgent(t *testing.T) {
+	dir := t.TempDir()
+	if err := Save(dir, Default()); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ".chief", "config.yaml"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if strings.Contains(string(data), "agent") {
+		t.Errorf("expected empty agent section to be omitted, got:\n%s", data)
+	}
+	if strings.Contains(string(data), "budget") {
+		t.Errorf("expected empty budget section to be omitted, got:\n%s", data)
+	}
+}
+
+func TestLoadBudget(t *testing.T) {
+	dir := t.TempDir()
+	chiefDir := filepath.Join(dir, ".chief")
+	if err := os.MkdirAll(chiefDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	yaml := `budget:
+  prd:
+    maxCostUsd: 12.5
+    maxDuration: 2h
+  story:
+    maxTokens: 500000
+`
+	if err := os.WriteFile(filepath.Join(chiefDir, "config.yaml"), []byte(yaml), 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	cfg, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Budget.PRD.MaxCostUSD != 12.5 {
+		t.Errorf("expected PRD maxCostUsd 12.5, got %v", cfg.Budget.PRD.MaxCostUSD)
+	}
+	if cfg.Budget.PRD.MaxDuration != "2h" {
+		t.Errorf("expected PRD maxDuration 2h, got %q", cfg.Budget.PRD.MaxDuration)
+	}
+	if cfg.Budget.Story.MaxTokens != 500000 {
+		t.Errorf("expected story maxTokens 500000, got %d", cfg.Budget.Story.MaxTokens)
+	}
+}
+
+func TestVerifyCommandsFor(t *testing.T) {
+	v := VerifyConfig{
+		Commands: []string{"go test ./..."},
+		Stories:  map[string][]string{"US-002": {"npm run e2e"}},
+	}
+
+	if got := v.CommandsFor("US-001"); len(got) != 1 || got[0] != "go test ./..." {
+		t.Errorf("CommandsFor(US-001) = %v, want global commands only", got)
+	}
+	got := v.CommandsFor("US-002")
+	if len(got) != 2 || got[1] != "npm run e2e" {
+		t.Errorf("CommandsFor(US-002) = %v, want global then story commands", got)
+	}
+	if len(v.Commands) != 1 {
+		t.Error("CommandsFor must not modify the global command list")
+	}
+}
@@ -0,0 +1,241 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+// Client controls a daemon over its socket. It mirrors the loop manager's
+// methods so the TUI can use either: state is answered from the snapshot
+// that comes with every event, and commands are sent to the daemon.
+type Client struct {
+	socket    string
+	conn      net.Conn // Subscription connection
+	events    chan loop.ManagerEvent
+	done      chan struct{}
+	instances map[string]Instance
+	maxIter   int
+	mu        sync.Mutex
+	once      sync.Once
+}
+
+// Dial connects to the daemon listening on socket and subscribes to its events.
+func Dial(socket string) (*Client, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(conn).Encode(Request{Method: MethodSubscribe}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	var resp Response
+	if err := dec.Decode(&resp); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	if !resp.OK {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe: %s", resp.Error)
+	}
+
+	c := &Client{
+		socket: socket,
+		conn:   conn,
+		events: make(chan loop.ManagerEvent, 100),
+		done:   make(chan struct{}),
+	}
+	c.update(resp.Instances, resp.MaxIterations)
+	go c.receive(dec)
+	return c, nil
+}
+
+// receive reads events until the subscription ends, then closes the events
+// channel.
+func (c *Client) receive(dec *json.Decoder) {
+	defer close(c.events)
+	for {
+		var msg Message
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		c.mu.Lock()
+		c.instances = indexInstances(msg.Instances)
+		c.mu.Unlock()
+		select {
+		case c.events <- msg.Event:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Close detaches from the daemon. Its loops keep running.
+func (c *Client) Close() error {
+	var err error
+	c.once.Do(func() {
+		close(c.done)
+		err = c.conn.Close()
+	})
+	return err
+}
+
+// Socket returns the path of the daemon's socket.
+func (c *Client) Socket() string {
+	return c.socket
+}
+
+// Call sends a request to the daemon and returns its response. Failed calls
+// return the daemon's error.
+func (c *Client) Call(req Request) (Response, error) {
+	conn, err := net.Dial("unix", c.socket)
+	if err != nil {
+		return Response{}, fmt.Errorf("daemon not reachable: %w", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("invalid response from daemon: %w", err)
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+	c.update(resp.Instances, resp.MaxIterations)
+	return resp, nil
+}
+
+// call sends a request, keeping only the error.
+func (c *Client) call(req Request) error {
+	_, err := c.Call(req)
+	return err
+}
+
+// update replaces the snapshot of the daemon's state.
+func (c *Client) update(instances []Instance, maxIter int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.instances = indexInstances(instances)
+	c.maxIter = maxIter
+}
+
+// indexInstances maps instances by PRD name.
+func indexInstances(instances []Instance) map[string]Instance {
+	m := make(map[string]Instance, len(instances))
+	for _, i := range instances {
+		m[i.Name] = i
+	}
+	return m
+}
+
+// Events returns the daemon's loop events. The channel is closed when the
+// client is detached or the daemon exits.
+func (c *Client) Events() <-chan loop.ManagerEvent {
+	return c.events
+}
+
+// Register registers a PRD with the daemon.
+func (c *Client) Register(name, prdPath string) error {
+	return c.call(Request{Method: MethodRegister, Name: name, PRDPath: prdPath})
+}
+
+// RegisterWithWorktree registers a PRD with its worktree.
+func (c *Client) RegisterWithWorktree(name, prdPath, worktreeDir, branch string) error {
+	return c.call(Request{Method: MethodRegister, Name: name, PRDPath: prdPath, WorktreeDir: worktreeDir, Branch: branch})
+}
+
+// UpdateWorktreeInfo sets the worktree directory and branch of a PRD.
+func (c *Client) UpdateWorktreeInfo(name, worktreeDir, branch string) error {
+	return c.call(Request{Method: MethodSetWorktree, Name: name, WorktreeDir: worktreeDir, Branch: branch})
+}
+
+// ClearWorktreeInfo clears the worktree directory and optionally the branch of a PRD.
+func (c *Client) ClearWorktreeInfo(name string, clearBranch bool) error {
+	return c.call(Request{Method: MethodClearWorktree, Name: name, ClearBranch: clearBranch})
+}
+
+// Start starts or queues a PRD's loop in the daemon.
+func (c *Client) Start(name string) error {
+	return c.call(Request{Method: MethodStart, Name: name})
+}
+
+// Pause pauses a PRD's loop after its current iteration.
+func (c *Client) Pause(name string) error {
+	return c.call(Request{Method: MethodPause, Name: name})
+}
+
+// Stop stops a PRD's loop immediately.
+func (c *Client) Stop(name string) error {
+	return c.call(Request{Method: MethodStop, Name: name})
+}
+
+// SetMaxIterations changes the daemon's default max iterations for new loops.
+func (c *Client) SetMaxIterations(maxIter int) {
+	_ = c.call(Request{Method: MethodSetMaxIterations, MaxIterations: maxIter})
+}
+
+// MaxIterations returns the daemon's default max iterations (0 = remaining
+// stories + 5).
+func (c *Client) MaxIterations() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxIter
+}
+
+// SetMaxIterationsForInstance changes the max iterations of a PRD's loop.
+func (c *Client) SetMaxIterationsForInstance(name string, maxIter int) error {
+	return c.call(Request{Method: MethodSetMaxIterations, Name: name, MaxIterations: maxIter})
+}
+
+// GetState returns the state of a PRD's loop.
+func (c *Client) GetState(name string) (loop.LoopState, int, error) {
+	inst := c.GetInstance(name)
+	if inst == nil {
+		return loop.LoopStateReady, 0, fmt.Errorf("PRD %s not found", name)
+	}
+	return inst.State, inst.Iteration, inst.Error
+}
+
+// GetInstance returns a copy of a PRD's loop instance, or nil.
+func (c *Client) GetInstance(name string) *loop.LoopInstance {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	inst, ok := c.instances[name]
+	if !ok {
+		return nil
+	}
+	return inst.LoopInstance()
+}
+
+// GetAllInstances returns a copy of every registered PRD's loop instance.
+func (c *Client) GetAllInstances() []*loop.LoopInstance {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result := make([]*loop.LoopInstance, 0, len(c.instances))
+	for _, inst := range c.instances {
+		result = append(result, inst.LoopInstance())
+	}
+	return result
+}
+
+// IsAnyRunning returns true if any of the daemon's loops is running.
+func (c *Client) IsAnyRunning() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, inst := range c.instances {
+		if loop.ParseLoopState(inst.State) == loop.LoopStateRunning {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+func TestClientControlsDaemon(t *testing.T) {
+	s, m := startTestServer(t, &testAgent{}, "auth")
+
+	c, err := Dial(s.Socket())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+
+	if inst := c.GetInstance("auth"); inst == nil || inst.State != loop.LoopStateReady {
+		t.Fatalf("Expected auth to be ready, got %+v", inst)
+	}
+	if c.GetInstance("missing") != nil {
+		t.Error("Expected no instance for an unregistered PRD")
+	}
+
+	if err := c.Start("auth"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForState(t, m, "auth", loop.LoopStateRunning)
+	if !c.IsAnyRunning() {
+		t.Error("Expected the client to see the running loop")
+	}
+	if err := c.Start("auth"); err == nil {
+		t.Error("Expected an error starting a running PRD")
+	}
+
+	if err := c.Stop("auth"); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if state, _, _ := c.GetState("auth"); state != loop.LoopStateStopped {
+		t.Errorf("Expected auth to be stopped, got %s", state)
+	}
+}
+
+func TestClientReceivesEvents(t *testing.T) {
+	s, _ := startTestServer(t, &testAgent{complete: true}, "auth")
+
+	c, err := Dial(s.Socket())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.Start("auth"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-c.Events():
+			if event.PRDName != "auth" {
+				t.Fatalf("Expected events of auth, got %q", event.PRDName)
+			}
+			if event.Event.Type == loop.EventComplete {
+				if state, _, _ := c.GetState("auth"); state != loop.LoopStateRunning && state != loop.LoopStateComplete {
+					t.Errorf("Expected the state to come with the event, got %s", state)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for the complete event")
+		}
+	}
+}
+
+func TestClientDetachKeepsLoopsRunning(t *testing.T) {
+	s, m := startTestServer(t, &testAgent{}, "auth")
+
+	c, err := Dial(s.Socket())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	if err := c.Start("auth"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForState(t, m, "auth", loop.LoopStateRunning)
+
+	c.Close()
+	select {
+	case _, ok := <-c.Events():
+		for ok {
+			_, ok = <-c.Events()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the events channel to close on detach")
+	}
+	if state, _, _ := m.GetState("auth"); state != loop.LoopStateRunning {
+		t.Errorf("Expected auth to keep running after detaching, got %s", state)
+	}
+}
@@ -0,0 +1,202 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// TokenPath returns the path of the file holding the token of the daemon's
+// HTTP API, readable only by its user.
+func TokenPath(baseDir string) string {
+	return filepath.Join(baseDir, ".chief", "serve.token")
+}
+
+// NewToken returns a random token for the HTTP API.
+func NewToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ListenHTTP listens for the HTTP API on addr, which must be a loopback
+// address so the API can't be reached from the network.
+func ListenHTTP(addr string) (net.Listener, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP address %q: %w", addr, err)
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("invalid HTTP address %q: the HTTP API only listens on localhost", addr)
+		}
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Story is the status of a story in a PRD's prd.json.
+type Story struct {
+	ID            string `json:"id"`
+	Title         string `json:"title"`
+	Priority      int    `json:"priority"`
+	Status        string `json:"status"` // "complete", "inProgress", "blocked" or "pending"
+	Attempts      int    `json:"attempts,omitempty"`
+	BlockedReason string `json:"blockedReason,omitempty"`
+}
+
+// PRDStatus is a PRD's loop state with the status of its stories.
+type PRDStatus struct {
+	Instance
+	Project string  `json:"project"`
+	Stories []Story `json:"stories"`
+}
+
+// storyStatus describes where a story stands.
+func storyStatus(s prd.UserStory) string {
+	switch {
+	case s.Passes:
+		return "complete"
+	case s.Blocked:
+		return "blocked"
+	case s.InProgress:
+		return "inProgress"
+	default:
+		return "pending"
+	}
+}
+
+// HTTPHandler returns the HTTP API: JSON endpoints for the state of the
+// PRDs, an SSE stream of loop events, and endpoints to start, pause and stop
+// loops. Every endpoint requires the token as a bearer token, so that web
+// pages can't read the API through DNS rebinding.
+func (s *Server) HTTPHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/prds", s.handleListPRDs)
+	mux.HandleFunc("GET /api/prds/{name}", s.handleGetPRD)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	for _, method := range []string{MethodStart, MethodPause, MethodStop} {
+		mux.Handle("POST /api/prds/{name}/"+method, s.handleControl(method))
+	}
+	return requireToken(token, mux)
+}
+
+// requireToken rejects requests without the bearer token.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, Response{Error: "missing or invalid token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleListPRDs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, instancesOf(s.manager))
+}
+
+func (s *Server) handleGetPRD(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	inst := s.manager.GetInstance(name)
+	if inst == nil {
+		writeJSON(w, http.StatusNotFound, Response{Error: fmt.Sprintf("PRD %s not found", name)})
+		return
+	}
+	p, err := prd.LoadPRD(inst.PRDPath)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, Response{Error: fmt.Sprintf("failed to load PRD %s: %v", name, err)})
+		return
+	}
+
+	status := PRDStatus{Instance: instanceOf(inst), Project: p.Project, Stories: []Story{}}
+	for _, story := range p.UserStories {
+		status.Stories = append(status.Stories, Story{
+			ID:            story.ID,
+			Title:         story.Title,
+			Priority:      story.Priority,
+			Status:        storyStatus(story),
+			Attempts:      story.Attempts,
+			BlockedReason: story.BlockedReason,
+		})
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleControl runs a start, pause or stop request for the PRD in the path.
+func (s *Server) handleControl(method string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if s.manager.GetInstance(name) == nil {
+			if _, err := os.Stat(filepath.Join(s.baseDir, ".chief", "prds", name, "prd.json")); method != MethodStart || err != nil {
+				writeJSON(w, http.StatusNotFound, Response{Error: fmt.Sprintf("PRD %s not found", name)})
+				return
+			}
+		}
+		resp := s.call(Request{Method: method, Name: name})
+		if !resp.OK {
+			writeJSON(w, http.StatusConflict, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// handleEvents streams loop events as server-sent events until the client
+// disconnects. Each event is named after its type, and its data is a
+// Message: the event and the state of every PRD after it.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, Response{Error: "streaming not supported"})
+		return
+	}
+	sub := s.newSubscriber()
+	if sub == nil {
+		writeJSON(w, http.StatusServiceUnavailable, Response{Error: "server is shutting down"})
+		return
+	}
+	defer s.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case msg, ok := <-sub.ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event.Event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+const testToken = "secret"
+
+// startTestHTTP serves the HTTP API of a test server.
+func startTestHTTP(t *testing.T, agent loop.Agent, names ...string) (*httptest.Server, *loop.Manager) {
+	t.Helper()
+	s, m := startTestServer(t, agent, names...)
+	ts := httptest.NewServer(s.HTTPHandler(testToken))
+	t.Cleanup(ts.Close)
+	return ts, m
+}
+
+// get sends a read request with the given token.
+func get(t *testing.T, url, token string) *http.Response {
+	t.Helper()
+	return request(t, http.MethodGet, url, token)
+}
+
+// post sends a control request with the given token.
+func post(t *testing.T, url, token string) *http.Response {
+	t.Helper()
+	return request(t, http.MethodPost, url, token)
+}
+
+// request sends a request with the given token.
+func request(t *testing.T, method, url, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestHTTPListPRDs(t *testing.T) {
+	ts, _ := startTestHTTP(t, &testAgent{}, "auth", "api")
+
+	resp := get(t, ts.URL+"/api/prds", testToken)
+	var instances []Instance
+	if err := json.NewDecoder(resp.Body).Decode(&instances); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(instances) != 2 || instances[0].Name != "api" || instances[1].Name != "auth" {
+		t.Fatalf("Expected api and auth, got %+v", instances)
+	}
+	if instances[0].State != "Ready" {
+		t.Errorf("Expected state Ready, got %s", instances[0].State)
+	}
+}
+
+func TestHTTPGetPRD(t *testing.T) {
+	ts, _ := startTestHTTP(t, &testAgent{}, "auth")
+
+	resp := get(t, ts.URL+"/api/prds/auth", testToken)
+	var status PRDStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.Name != "auth" || status.Project != "auth" {
+		t.Errorf("Expected auth's status, got %+v", status)
+	}
+	if len(status.Stories) != 1 || status.Stories[0].ID != "US-001" || status.Stories[0].Status != "pending" {
+		t.Errorf("Expected US-001 to be pending, got %+v", status.Stories)
+	}
+
+	resp = get(t, ts.URL+"/api/prds/missing", testToken)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing PRD, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPControlRequiresToken(t *testing.T) {
+	ts, m := startTestHTTP(t, &testAgent{}, "auth")
+
+	for _, token := range []string{"", "wrong"} {
+		if resp := post(t, ts.URL+"/api/prds/auth/start", token); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with token %q, got %d", token, resp.StatusCode)
+		}
+	}
+	if state, _, _ := m.GetState("auth"); state != loop.LoopStateReady {
+		t.Errorf("Expected auth not to start without the token, got %s", state)
+	}
+}
+
+func TestHTTPReadRequiresToken(t *testing.T) {
+	ts, _ := startTestHTTP(t, &testAgent{}, "auth")
+
+	for _, path := range []string{"/api/prds", "/api/prds/auth", "/api/events"} {
+		for _, token := range []string{"", "wrong"} {
+			if resp := get(t, ts.URL+path, token); resp.StatusCode != http.StatusUnauthorized {
+				t.Errorf("Expected 401 for %s with token %q, got %d", path, token, resp.StatusCode)
+			}
+		}
+	}
+}
+
+func TestHTTPStartStop(t *testing.T) {
+	ts, m := startTestHTTP(t, &testAgent{}, "auth")
+
+	if resp := post(t, ts.URL+"/api/prds/auth/start", testToken); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected start to succeed, got %d", resp.StatusCode)
+	}
+	waitForState(t, m, "auth", loop.LoopStateRunning)
+
+	// Starting it again conflicts with the running loop
+	if resp := post(t, ts.URL+"/api/prds/auth/start", testToken); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 when starting a running PRD, got %d", resp.StatusCode)
+	}
+
+	if resp := post(t, ts.URL+"/api/prds/auth/stop", testToken); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected stop to succeed, got %d", resp.StatusCode)
+	}
+	waitForState(t, m, "auth", loop.LoopStateStopped)
+
+	if resp := post(t, ts.URL+"/api/prds/missing/pause", testToken); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing PRD, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPEvents(t *testing.T) {
+	ts, _ := startTestHTTP(t, &testAgent{complete: true}, "auth")
+
+	resp := get(t, ts.URL+"/api/events", testToken)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", ct)
+	}
+	if resp := post(t, ts.URL+"/api/prds/auth/start", testToken); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected start to succeed, got %d", resp.StatusCode)
+	}
+
+	// Closing the body on timeout ends the scan
+	timer := time.AfterFunc(5*time.Second, func() { resp.Body.Close() })
+	defer timer.Stop()
+
+	name := ""
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if n, ok := strings.CutPrefix(line, "event: "); ok {
+			name = n
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || name != loop.EventComplete.String() {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if msg.Event.PRDName != "auth" || msg.Event.Event.Type != loop.EventComplete {
+			t.Errorf("Expected auth's completion, got %+v", msg.Event)
+		}
+		if len(msg.Instances) != 1 {
+			t.Errorf("Expected the event to carry auth's state, got %+v", msg.Instances)
+		}
+		return
+	}
+	t.Fatal("Event stream ended before auth's completion event")
+}
@@ -0,0 +1,139 @@
+// Package daemon runs loops in a long-lived process that owns the loop
+// manager, and lets TUIs and scripts control it over a Unix domain socket.
+//
+// The protocol is newline-delimited JSON: a client writes a Request per line
+// and reads a Response per line. After a subscribe request, the server
+// instead writes a Message per line for every loop event until the client
+// disconnects.
+package daemon
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+// Methods of the socket API.
+const (
+	MethodList             = "list"             // List registered PRDs
+	MethodRegister         = "register"         // Register a PRD, optionally with its worktree
+	MethodStart            = "start"            // Start or queue a PRD's loop
+	MethodPause            = "pause"            // Pause a loop after its current iteration
+	MethodStop             = "stop"             // Stop a loop immediately
+	MethodSubscribe        = "subscribe"        // Stream loop events
+	MethodSetMaxIterations = "setMaxIterations" // Change max iterations of a loop, or the default without a name
+	MethodSetWorktree      = "setWorktree"      // Set the worktree and branch of a PRD
+	MethodClearWorktree    = "clearWorktree"    // Forget the worktree, and optionally the branch, of a PRD
+)
+
+// SocketPath returns the path of the socket the daemon of a project listens on.
+func SocketPath(baseDir string) string {
+	return filepath.Join(baseDir, ".chief", "chief.sock")
+}
+
+// ErrRunning is returned when another daemon is already serving the project.
+var ErrRunning = errors.New("chief serve is already running for this project")
+
+// Request is a call to the socket API.
+type Request struct {
+	Method        string `json:"method"`
+	Name          string `json:"name,omitempty"`          // PRD name
+	PRDPath       string `json:"prdPath,omitempty"`       // For register (default: .chief/prds/<name>/prd.json)
+	WorktreeDir   string `json:"worktreeDir,omitempty"`   // For register and setWorktree
+	Branch        string `json:"branch,omitempty"`        // For register and setWorktree
+	ClearBranch   bool   `json:"clearBranch,omitempty"`   // For clearWorktree
+	MaxIterations int    `json:"maxIterations,omitempty"` // For setMaxIterations
+}
+
+// Response is the result of a call. Successful calls return the state of
+// every registered PRD.
+type Response struct {
+	OK            bool       `json:"ok"`
+	Error         string     `json:"error,omitempty"`
+	Instances     []Instance `json:"instances,omitempty"`
+	MaxIterations int        `json:"maxIterations,omitempty"` // Default for new loops (0 = remaining stories + 5)
+}
+
+// Message is a loop event sent to subscribers, with the state of every
+// registered PRD after it.
+type Message struct {
+	Event     loop.ManagerEvent `json:"event"`
+	Instances []Instance        `json:"instances"`
+}
+
+// Instance is the state of a PRD's loop.
+type Instance struct {
+	Name           string                `json:"name"`
+	PRDPath        string                `json:"prdPath"`
+	WorktreeDir    string                `json:"worktreeDir,omitempty"`
+	Branch         string                `json:"branch,omitempty"`
+	State          string                `json:"state"`
+	Iteration      int                   `json:"iteration,omitempty"`
+	StartTime      time.Time             `json:"startTime,omitzero"`
+	Error          string                `json:"error,omitempty"`
+	Usage          loop.Usage            `json:"usage"`
+	StoryUsage     map[string]loop.Usage `json:"storyUsage,omitempty"`
+	IterationUsage map[int]loop.Usage    `json:"iterationUsage,omitempty"`
+	Profile        string                `json:"profile,omitempty"`
+	QueuePosition  int                   `json:"queuePosition,omitempty"`
+}
+
+// instanceOf converts a manager's loop instance.
+func instanceOf(i *loop.LoopInstance) Instance {
+	out := Instance{
+		Name:           i.Name,
+		PRDPath:        i.PRDPath,
+		WorktreeDir:    i.WorktreeDir,
+		Branch:         i.Branch,
+		State:          i.State.String(),
+		Iteration:      i.Iteration,
+		StartTime:      i.StartTime,
+		Usage:          i.Usage,
+		StoryUsage:     i.StoryUsage,
+		IterationUsage: i.IterationUsage,
+		Profile:        i.Profile,
+		QueuePosition:  i.QueuePosition,
+	}
+	if i.Error != nil {
+		out.Error = i.Error.Error()
+	}
+	return out
+}
+
+// LoopInstance converts the instance back to the manager's representation.
+// The error, if any, is restored as a plain error carrying the message.
+func (i Instance) LoopInstance() *loop.LoopInstance {
+	out := &loop.LoopInstance{
+		Name:           i.Name,
+		PRDPath:        i.PRDPath,
+		WorktreeDir:    i.WorktreeDir,
+		Branch:         i.Branch,
+		State:          loop.ParseLoopState(i.State),
+		Iteration:      i.Iteration,
+		StartTime:      i.StartTime,
+		Usage:          i.Usage,
+		StoryUsage:     i.StoryUsage,
+		IterationUsage: i.IterationUsage,
+		Profile:        i.Profile,
+		QueuePosition:  i.QueuePosition,
+	}
+	if i.Error != "" {
+		out.Error = errors.New(i.Error)
+	}
+	return out
+}
+
+// instancesOf returns the state of every PRD registered with a manager,
+// sorted by name.
+func instancesOf(m *loop.Manager) []Instance {
+	all := m.GetAllInstances()
+	out := make([]Instance, 0, len(all))
+	for _, i := range all {
+		out = append(out, instanceOf(i))
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Name < out[b].Name })
+	return out
+}
@@ -0,0 +1,318 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+// subscriberBuffer is how many messages a subscriber may fall behind by
+// before it is disconnected, so a stuck client never holds up the loops.
+const subscriberBuffer = 1024
+
+// subscriber is a connection streaming loop events.
+type subscriber struct {
+	ch chan Message
+}
+
+// Server serves the socket API for a loop manager. It is the only reader of
+// the manager's events.
+type Server struct {
+	manager  *loop.Manager
+	baseDir  string
+	socket   string
+	listener net.Listener
+	onEvent  func(loop.ManagerEvent) // Called for every event, e.g. to print it
+	subs     map[*subscriber]struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+	once     sync.Once
+}
+
+// NewServer creates a server for the manager of the project in baseDir.
+func NewServer(manager *loop.Manager, baseDir string) *Server {
+	return &Server{
+		manager: manager,
+		baseDir: baseDir,
+		socket:  SocketPath(baseDir),
+		subs:    make(map[*subscriber]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// SetEventHandler sets a function called with every loop event.
+func (s *Server) SetEventHandler(fn func(loop.ManagerEvent)) {
+	s.onEvent = fn
+}
+
+// Socket returns the path of the socket the server listens on.
+func (s *Server) Socket() string {
+	return s.socket
+}
+
+// Listen creates the socket. A socket left behind by a daemon that is no
+// longer running is replaced; ErrRunning is returned if one still is.
+func (s *Server) Listen() error {
+	if err := os.MkdirAll(filepath.Dir(s.socket), 0755); err != nil {
+		return fmt.Errorf("failed to create .chief directory: %w", err)
+	}
+	if _, err := os.Stat(s.socket); err == nil {
+		if conn, err := net.Dial("unix", s.socket); err == nil {
+			conn.Close()
+			return ErrRunning
+		}
+		if err := os.Remove(s.socket); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", s.socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socket, err)
+	}
+	// Anyone who can connect can run agents in the project
+	if err := os.Chmod(s.socket, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
+// Serve accepts connections until Close is called. Listen must be called first.
+func (s *Server) Serve() error {
+	go s.forwardEvents()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting connections, disconnects subscribers and removes
+// the socket. It does not stop the loops.
+func (s *Server) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		if s.listener != nil {
+			err = s.listener.Close()
+		}
+		s.mu.Lock()
+		for sub := range s.subs {
+			close(sub.ch)
+			delete(s.subs, sub)
+		}
+		s.mu.Unlock()
+	})
+	return err
+}
+
+// forwardEvents passes the manager's events to the event handler and the
+// subscribers.
+func (s *Server) forwardEvents() {
+	for {
+		select {
+		case event := <-s.manager.Events():
+			if s.onEvent != nil {
+				s.onEvent(event)
+			}
+			s.broadcast(event)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// broadcast sends an event to every subscriber, dropping those that have
+// fallen too far behind.
+func (s *Server) broadcast(event loop.ManagerEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.subs) == 0 {
+		return
+	}
+
+	msg := Message{Event: event, Instances: instancesOf(s.manager)}
+	for sub := range s.subs {
+		select {
+		case sub.ch <- msg:
+		default:
+			close(sub.ch)
+			delete(s.subs, sub)
+		}
+	}
+}
+
+// unsubscribe removes a subscriber if it is still subscribed.
+func (s *Server) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[sub]; ok {
+		close(sub.ch)
+		delete(s.subs, sub)
+	}
+}
+
+// handle answers the requests on a connection until the client disconnects
+// or subscribes.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			enc.Encode(Response{Error: "invalid request: " + err.Error()})
+			continue
+		}
+		if req.Method == MethodSubscribe {
+			s.subscribe(conn, reader)
+			return
+		}
+		if err := enc.Encode(s.call(req)); err != nil {
+			return
+		}
+	}
+}
+
+// newSubscriber adds a subscriber to the loop events. It returns nil once
+// the server is closed.
+func (s *Server) newSubscriber() *subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	sub := &subscriber{ch: make(chan Message, subscriberBuffer)}
+	s.subs[sub] = struct{}{}
+	return sub
+}
+
+// subscribe streams events to a connection until either side closes it.
+func (s *Server) subscribe(conn net.Conn, reader io.Reader) {
+	sub := s.newSubscriber()
+	if sub == nil {
+		return
+	}
+	enc := json.NewEncoder(conn)
+	if err := enc.Encode(s.response()); err != nil {
+		s.unsubscribe(sub)
+		return
+	}
+
+	// The client closing the connection ends the subscription
+	go func() {
+		io.Copy(io.Discard, reader)
+		s.unsubscribe(sub)
+	}()
+
+	for msg := range sub.ch {
+		if err := enc.Encode(msg); err != nil {
+			s.unsubscribe(sub)
+		}
+	}
+}
+
+// response returns a successful response with the state of every PRD.
+func (s *Server) response() Response {
+	return Response{
+		OK:            true,
+		Instances:     instancesOf(s.manager),
+		MaxIterations: s.manager.MaxIterations(),
+	}
+}
+
+// call runs a request against the manager.
+func (s *Server) call(req Request) Response {
+	var err error
+	switch req.Method {
+	case MethodList:
+	case MethodRegister:
+		err = s.register(req)
+	case MethodStart:
+		// PRDs created since the daemon started are registered on demand
+		if s.manager.GetInstance(req.Name) == nil {
+			err = s.register(req)
+		}
+		if err == nil {
+			err = s.manager.Start(req.Name)
+		}
+	case MethodPause:
+		err = s.manager.Pause(req.Name)
+	case MethodStop:
+		err = s.manager.Stop(req.Name)
+	case MethodSetMaxIterations:
+		switch {
+		case req.MaxIterations < 1:
+			err = errors.New("max iterations must be at least 1")
+		case req.Name == "":
+			s.manager.SetMaxIterations(req.MaxIterations)
+		default:
+			err = s.manager.SetMaxIterationsForInstance(req.Name, req.MaxIterations)
+		}
+	case MethodSetWorktree:
+		err = s.manager.UpdateWorktreeInfo(req.Name, req.WorktreeDir, req.Branch)
+	case MethodClearWorktree:
+		err = s.manager.ClearWorktreeInfo(req.Name, req.ClearBranch)
+	default:
+		err = fmt.Errorf("unknown method %q", req.Method)
+	}
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	return s.response()
+}
+
+// register registers a PRD, by default the one of that name in .chief/prds.
+func (s *Server) register(req Request) error {
+	if !isValidPRDName(req.Name) {
+		return fmt.Errorf("invalid PRD name %q", req.Name)
+	}
+	prdPath := req.PRDPath
+	if prdPath == "" {
+		prdPath = filepath.Join(s.baseDir, ".chief", "prds", req.Name, "prd.json")
+	}
+	if _, err := os.Stat(prdPath); err != nil {
+		return fmt.Errorf("PRD %s not found", req.Name)
+	}
+	if req.WorktreeDir != "" || req.Branch != "" {
+		return s.manager.RegisterWithWorktree(req.Name, prdPath, req.WorktreeDir, req.Branch)
+	}
+	return s.manager.Register(req.Name, prdPath)
+}
+
+// isValidPRDName checks if the name contains only the characters the PRD
+// commands allow, which also rules out "." and "..".
+func isValidPRDName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
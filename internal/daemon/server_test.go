@@ -0,0 +1,292 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/loop"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// testAgent either completes every story of the PRD it runs, or hangs until
+// the loop is stopped.
+type testAgent struct {
+	complete bool
+}
+
+func (a *testAgent) Name() string { return "Test" }
+
+func (a *testAgent) Start(ctx context.Context, req loop.AgentRequest) (loop.AgentProcess, error) {
+	if a.complete {
+		prdPath := filepath.Join(req.PRDDir, "prd.json")
+		p, err := prd.LoadPRD(prdPath)
+		if err != nil {
+			return nil, err
+		}
+		for i := range p.UserStories {
+			p.UserStories[i].Passes = true
+		}
+		if err := p.Save(prdPath); err != nil {
+			return nil, err
+		}
+	}
+	r, w := io.Pipe()
+	proc := &testProcess{stdout: r, w: w, killed: make(chan struct{})}
+	if a.complete {
+		proc.Kill()
+	}
+	return proc, nil
+}
+
+func (a *testAgent) ParseLine(line string) []loop.Event { return loop.ParseLine(line) }
+
+type testProcess struct {
+	stdout io.Reader
+	w      *io.PipeWriter
+	killed chan struct{}
+	once   sync.Once
+}
+
+func (p *testProcess) Stdout() io.Reader { return p.stdout }
+func (p *testProcess) Stderr() io.Reader { return strings.NewReader("") }
+func (p *testProcess) Wait() error {
+	<-p.killed
+	return nil
+}
+func (p *testProcess) Kill() error {
+	p.once.Do(func() {
+		p.w.Close()
+		close(p.killed)
+	})
+	return nil
+}
+
+// createTestPRD writes a PRD with one incomplete story under dir/.chief/prds/name.
+func createTestPRD(t *testing.T, dir, name string) string {
+	t.Helper()
+	prdPath := filepath.Join(dir, ".chief", "prds", name, "prd.json")
+	if err := os.MkdirAll(filepath.Dir(prdPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := &prd.PRD{
+		Project:     name,
+		UserStories: []prd.UserStory{{ID: "US-001", Title: "Story", Priority: 1}},
+	}
+	if err := p.Save(prdPath); err != nil {
+		t.Fatal(err)
+	}
+	return prdPath
+}
+
+// startTestServer serves a manager for a new project with the given PRDs.
+func startTestServer(t *testing.T, agent loop.Agent, names ...string) (*Server, *loop.Manager) {
+	t.Helper()
+	dir := t.TempDir()
+	m := loop.NewManager(10)
+	m.SetBaseDir(dir)
+	m.SetConfig(config.Default())
+	m.SetAgent(agent)
+	for _, name := range names {
+		m.Register(name, createTestPRD(t, dir, name))
+	}
+
+	s := NewServer(m, dir)
+	if err := s.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go s.Serve()
+	t.Cleanup(func() {
+		s.Close()
+		m.StopAll()
+	})
+	return s, m
+}
+
+// send writes a raw request line to the server and reads the response.
+func send(t *testing.T, socket, line string) Response {
+	t.Helper()
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, line+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return resp
+}
+
+// waitForState polls the manager until a PRD reaches a state.
+func waitForState(t *testing.T, m *loop.Manager, name string, state loop.LoopState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if s, _, _ := m.GetState(name); s == state {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s, _, _ := m.GetState(name)
+	t.Fatalf("Expected %s to be %s, got %s", name, state, s)
+}
+
+func TestServerList(t *testing.T) {
+	s, _ := startTestServer(t, &testAgent{}, "auth", "api")
+
+	resp := send(t, s.Socket(), `{"method":"list"}`)
+	if !resp.OK {
+		t.Fatalf("Expected ok, got error %q", resp.Error)
+	}
+	if len(resp.Instances) != 2 || resp.Instances[0].Name != "api" || resp.Instances[1].Name != "auth" {
+		t.Fatalf("Expected api and auth, got %+v", resp.Instances)
+	}
+	if resp.Instances[0].State != "Ready" {
+		t.Errorf("Expected state Ready, got %s", resp.Instances[0].State)
+	}
+	if resp.MaxIterations != 10 {
+		t.Errorf("Expected max iterations 10, got %d", resp.MaxIterations)
+	}
+}
+
+func TestServerStartPauseStop(t *testing.T) {
+	s, m := startTestServer(t, &testAgent{}, "auth", "api")
+
+	if resp := send(t, s.Socket(), `{"method":"start","name":"auth"}`); !resp.OK {
+		t.Fatalf("Expected start to succeed, got %q", resp.Error)
+	}
+	waitForState(t, m, "auth", loop.LoopStateRunning)
+
+	if resp := send(t, s.Socket(), `{"method":"setMaxIterations","name":"auth","maxIterations":3}`); !resp.OK {
+		t.Errorf("Expected setMaxIterations to succeed, got %q", resp.Error)
+	}
+	if resp := send(t, s.Socket(), `{"method":"stop","name":"auth"}`); !resp.OK {
+		t.Errorf("Expected stop to succeed, got %q", resp.Error)
+	}
+	waitForState(t, m, "auth", loop.LoopStateStopped)
+
+	send(t, s.Socket(), `{"method":"start","name":"api"}`)
+	waitForState(t, m, "api", loop.LoopStateRunning)
+	if resp := send(t, s.Socket(), `{"method":"pause","name":"api"}`); !resp.OK {
+		t.Errorf("Expected pause to succeed, got %q", resp.Error)
+	}
+}
+
+func TestServerStartRegistersNewPRD(t *testing.T) {
+	s, m := startTestServer(t, &testAgent{})
+	createTestPRD(t, filepath.Dir(filepath.Dir(s.Socket())), "billing")
+
+	if resp := send(t, s.Socket(), `{"method":"start","name":"billing"}`); !resp.OK {
+		t.Fatalf("Expected start to succeed, got %q", resp.Error)
+	}
+	waitForState(t, m, "billing", loop.LoopStateRunning)
+
+	for _, name := range []string{"../billing", "..", ".", "bill ing"} {
+		resp := send(t, s.Socket(), fmt.Sprintf(`{"method":"start","name":%q}`, name))
+		if resp.OK || !strings.Contains(resp.Error, "invalid PRD name") {
+			t.Errorf("Expected an invalid name error for %q, got %+v", name, resp)
+		}
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	s, _ := startTestServer(t, &testAgent{}, "auth")
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`{"method":"start","name":"missing"}`, "PRD missing not found"},
+		{`{"method":"pause","name":"auth"}`, "PRD auth is not running"},
+		{`{"method":"setMaxIterations","maxIterations":0}`, "max iterations must be at least 1"},
+		{`{"method":"bogus"}`, `unknown method "bogus"`},
+		{`not json`, "invalid request"},
+	}
+	for _, tt := range tests {
+		resp := send(t, s.Socket(), tt.line)
+		if resp.OK || !strings.Contains(resp.Error, tt.want) {
+			t.Errorf("%s: expected error %q, got %+v", tt.line, tt.want, resp)
+		}
+	}
+}
+
+func TestServerListenAlreadyRunning(t *testing.T) {
+	s, m := startTestServer(t, &testAgent{})
+
+	other := NewServer(m, filepath.Dir(filepath.Dir(s.Socket())))
+	if err := other.Listen(); !errors.Is(err, ErrRunning) {
+		t.Errorf("Expected ErrRunning, got %v", err)
+	}
+}
+
+func TestServerListenReplacesStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".chief"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A socket file nobody listens on, as left by a daemon that was killed
+	if err := os.WriteFile(SocketPath(dir), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(loop.NewManager(10), dir)
+	if err := s.Listen(); err != nil {
+		t.Fatalf("Expected the stale socket to be replaced, got %v", err)
+	}
+	s.Close()
+	if _, err := os.Stat(SocketPath(dir)); !os.IsNotExist(err) {
+		t.Error("Expected Close to remove the socket")
+	}
+}
+
+func TestServerSubscribe(t *testing.T) {
+	s, _ := startTestServer(t, &testAgent{complete: true}, "auth")
+
+	conn, err := net.Dial("unix", s.Socket())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, `{"method":"subscribe"}`+"\n")
+	reader := bufio.NewReader(conn)
+
+	var resp Response
+	line, _ := reader.ReadBytes('\n')
+	if err := json.Unmarshal(line, &resp); err != nil || !resp.OK {
+		t.Fatalf("Expected an ok response, got %s (%v)", line, err)
+	}
+
+	send(t, s.Socket(), `{"method":"start","name":"auth"}`)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("Expected a complete event: %v", err)
+		}
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			t.Fatalf("Invalid message %s: %v", line, err)
+		}
+		if msg.Event.PRDName != "auth" || len(msg.Instances) != 1 {
+			t.Fatalf("Expected an event of auth with its state, got %s", line)
+		}
+		if msg.Event.Event.Type == loop.EventComplete {
+			break
+		}
+	}
+}
@@ -49,6 +49,40 @@ func IsGitRepo(dir string) bool {
 	return cmd.Run() == nil
 }
 
+// HeadCommit returns the commit hash HEAD points to.
+func HeadCommit(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// WorkingTreeChanges returns the uncommitted changes in dir: the short status
+// of every changed or untracked file followed by the diff against HEAD. Paths
+// in exclude (relative to dir) are left out.
+func WorkingTreeChanges(dir string, exclude ...string) (string, error) {
+	pathspec := []string{"--", "."}
+	for _, path := range exclude {
+		pathspec = append(pathspec, ":(exclude)"+path)
+	}
+
+	cmd := exec.Command("git", append([]string{"status", "--porcelain", "--untracked-files=all"}, pathspec...)...)
+	cmd.Dir = dir
+	status, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	// A repository without commits has nothing to diff against
+	cmd = exec.Command("git", append([]string{"diff", "HEAD", "--binary"}, pathspec...)...)
+	cmd.Dir = dir
+	diff, _ := cmd.Output()
+	return string(status) + string(diff), nil
+}
+
 // CommitCount returns the number of commits on branch that are not on the default branch.
 // Returns 0 if the count cannot be determined.
 func CommitCount(repoDir, branch string) int {
@@ -3,6 +3,7 @@ package git
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -150,3 +151,41 @@ func TestIsProtectedBranch(t *testing.T) {
 		})
 	}
 }
+
+func TestHeadCommitAndWorkingTreeChanges(t *testing.T) {
+	dir := initTestRepo(t)
+
+	head, err := HeadCommit(dir)
+	if err != nil || len(head) != 40 {
+		t.Fatalf("HeadCommit() = %q, %v, want a commit hash", head, err)
+	}
+
+	changes, err := WorkingTreeChanges(dir)
+	if err != nil {
+		t.Fatalf("WorkingTreeChanges() error = %v", err)
+	}
+	if changes != "" {
+		t.Errorf("expected no changes in a clean tree, got %q", changes)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# Changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".chief"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".chief", "claude.log"), []byte("log\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err = WorkingTreeChanges(dir, ".chief")
+	if err != nil {
+		t.Fatalf("WorkingTreeChanges() error = %v", err)
+	}
+	if !strings.Contains(changes, "README.md") || !strings.Contains(changes, "# Changed") {
+		t.Errorf("expected the README change, got %q", changes)
+	}
+	if strings.Contains(changes, "claude.log") {
+		t.Errorf("expected .chief to be excluded, got %q", changes)
+	}
+}
@@ -49,6 +49,12 @@ func GetDefaultBranch(repoDir string) (string, error) {
 // If the worktree path already exists and is a valid worktree on the expected branch, it is reused.
 // If the worktree path exists but is stale (wrong branch or invalid), it is removed and recreated.
 func CreateWorktree(repoDir, worktreePath, branch string) error {
+	return CreateWorktreeFrom(repoDir, worktreePath, branch, "")
+}
+
+// CreateWorktreeFrom is like CreateWorktree, but creates the branch from base
+// instead of the default branch. An empty base means the default branch.
+func CreateWorktreeFrom(repoDir, worktreePath, branch, base string) error {
 	absWorktreePath, err := filepath.Abs(worktreePath)
 	if err != nil {
 		return fmt.Errorf("failed to resolve worktree path: %w", err)
@@ -68,18 +74,21 @@ func CreateWorktree(repoDir, worktreePath, branch string) error {
 		}
 	}
 
-	defaultBranch, err := GetDefaultBranch(repoDir)
-	if err != nil {
-		return fmt.Errorf("failed to detect default branch: %w", err)
+	if base == "" {
+		defaultBranch, err := GetDefaultBranch(repoDir)
+		if err != nil {
+			return fmt.Errorf("failed to detect default branch: %w", err)
+		}
+		base = defaultBranch
 	}
 
-	// Create the branch from the default branch if it doesn't exist
+	// Create the branch from the base branch if it doesn't exist
 	exists, err := BranchExists(repoDir, branch)
 	if err != nil {
 		return fmt.Errorf("failed to check branch existence: %w", err)
 	}
 	if !exists {
-		cmd := exec.Command("git", "branch", branch, defaultBranch)
+		cmd := exec.Command("git", "branch", branch, base)
 		cmd.Dir = repoDir
 		if out, err := cmd.CombinedOutput(); err != nil {
 			return fmt.Errorf("failed to create branch %s: %s", branch, strings.TrimSpace(string(out)))
@@ -106,6 +115,17 @@ func RemoveWorktree(repoDir, worktreePath string) error {
 	return nil
 }
 
+// ForceRemoveWorktree removes a git worktree at the given path, discarding
+// any uncommitted or untracked files in it.
+func ForceRemoveWorktree(repoDir, worktreePath string) error {
+	cmd := exec.Command("git", "worktree", "remove", "--force", worktreePath)
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to remove worktree: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 // ListWorktrees parses `git worktree list --porcelain` and returns all worktrees.
 func ListWorktrees(repoDir string) ([]Worktree, error) {
 	cmd := exec.Command("git", "worktree", "list", "--porcelain")
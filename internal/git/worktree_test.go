@@ -168,6 +168,46 @@ func TestCreateWorktree(t *testing.T) {
 	})
 }
 
+func TestCreateWorktreeFrom(t *testing.T) {
+	dir := initTestRepo(t)
+
+	// Add a commit on a feature branch that the new branch should start from
+	for _, args := range [][]string{
+		{"git", "checkout", "-b", "chief/feature"},
+		{"git", "commit", "--allow-empty", "-m", "feature commit"},
+		{"git", "checkout", "main"},
+	} {
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("command %v failed: %s", args, string(out))
+		}
+	}
+
+	wtPath := filepath.Join(dir, "worktrees", "story")
+	if err := CreateWorktreeFrom(dir, wtPath, "chief/feature-US-001", "chief/feature"); err != nil {
+		t.Fatalf("CreateWorktreeFrom() error = %v", err)
+	}
+
+	branch, err := GetCurrentBranch(wtPath)
+	if err != nil {
+		t.Fatalf("GetCurrentBranch() error = %v", err)
+	}
+	if branch != "chief/feature-US-001" {
+		t.Errorf("branch = %q, want %q", branch, "chief/feature-US-001")
+	}
+
+	cmd := exec.Command("git", "log", "-1", "--format=%s")
+	cmd.Dir = wtPath
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "feature commit" {
+		t.Errorf("HEAD commit = %q, want %q", got, "feature commit")
+	}
+}
+
 func TestRemoveWorktree(t *testing.T) {
 	t.Run("removes existing worktree", func(t *testing.T) {
 		dir := initTestRepo(t)
@@ -189,6 +229,25 @@ func TestRemoveWorktree(t *testing.T) {
 	})
 }
 
+func TestForceRemoveWorktree(t *testing.T) {
+	dir := initTestRepo(t)
+	wtPath := filepath.Join(dir, "worktrees", "test-prd")
+
+	if err := CreateWorktree(dir, wtPath, "chief/test-prd"); err != nil {
+		t.Fatalf("CreateWorktree() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(wtPath, "untracked.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to create untracked file: %v", err)
+	}
+
+	if err := ForceRemoveWorktree(dir, wtPath); err != nil {
+		t.Fatalf("ForceRemoveWorktree() error = %v", err)
+	}
+	if _, err := os.Stat(wtPath); !os.IsNotExist(err) {
+		t.Error("worktree directory still exists after removal")
+	}
+}
+
 func TestListWorktrees(t *testing.T) {
 	t.Run("lists worktrees including main", func(t *testing.T) {
 		dir := initTestRepo(t)
@@ -0,0 +1,226 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/minicodemonkey/chief/embed"
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+// Agent launches a coding agent for a single loop iteration and knows how to
+// turn the agent's output into Events.
+type Agent interface {
+	// Name returns a human-readable name used in status and error messages.
+	Name() string
+	// Start launches the agent for one iteration.
+	Start(ctx context.Context, req AgentRequest) (AgentProcess, error)
+	// ParseLine parses a single line of the agent's stdout into events, in
+	// order. It returns nil for lines that don't produce an event.
+	ParseLine(line string) []Event
+}
+
+// AgentRequest describes a single agent invocation.
+type AgentRequest struct {
+	Prompt        string      // Prompt for this iteration
+	WorkDir       string      // Directory the agent runs in
+	PRDDir        string      // Directory holding prd.json and progress.md
+	ResumeSession string      // Session to continue instead of starting fresh (agents that can't resume ignore it)
+	Model         string      // Model to use (empty for the agent's default)
+	ExtraArgs     []string    // Additional command line arguments
+	Permissions   Permissions // Permission profile to run under (agents without permission controls ignore it)
+	Wrapper       []string    // Command template the agent is launched through (empty = run directly)
+}
+
+// AgentProcess is a running agent invocation. Stdout and Stderr must be read
+// to completion before calling Wait.
+type AgentProcess interface {
+	Stdout() io.Reader
+	Stderr() io.Reader
+	// Wait waits for the agent to exit and returns its exit error, if any.
+	Wait() error
+	// Kill terminates the agent immediately.
+	Kill() error
+}
+
+// NewAgent creates the agent described by the project config.
+func NewAgent(cfg config.AgentConfig) (Agent, error) {
+	switch cfg.Provider {
+	case "", config.AgentProviderClaude:
+		return &ClaudeAgent{Command: cfg.Command}, nil
+	case config.AgentProviderCommand:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("agent provider %q requires agent.command to be set", cfg.Provider)
+		}
+		switch cfg.Output {
+		case "", OutputStreamJSON, OutputText:
+		default:
+			return nil, fmt.Errorf("unknown agent output format %q (expected %q or %q)", cfg.Output, OutputStreamJSON, OutputText)
+		}
+		return &CommandAgent{Command: cfg.Command, Args: cfg.Args, Output: cfg.Output}, nil
+	default:
+		return nil, fmt.Errorf("unknown agent provider %q", cfg.Provider)
+	}
+}
+
+// ClaudeAgent runs Claude Code in print mode with stream-json output.
+type ClaudeAgent struct {
+	Command string // Binary to run (default: "claude")
+}
+
+// Name returns the agent name.
+func (a *ClaudeAgent) Name() string {
+	return "Claude"
+}
+
+// Start launches Claude with the iteration prompt.
+func (a *ClaudeAgent) Start(ctx context.Context, req AgentRequest) (AgentProcess, error) {
+	command := a.Command
+	if command == "" {
+		command = "claude"
+	}
+	return startProcess(ctx, req, command, a.args(req), nil)
+}
+
+// args returns the command line arguments for a request. A resumed session
+// gets a short prompt to carry on, since it already has the instructions.
+func (a *ClaudeAgent) args(req AgentRequest) []string {
+	args := req.Permissions.claudeArgs(req.PRDDir)
+	prompt := req.Prompt
+	if req.ResumeSession != "" {
+		args = append(args, "--resume", req.ResumeSession)
+		prompt = embed.GetResumePrompt()
+	}
+	if req.Model != "" {
+		args = append(args, "--model", req.Model)
+	}
+	args = append(args,
+		"-p", prompt,
+		"--output-format", "stream-json",
+		"--verbose",
+	)
+	return append(args, req.ExtraArgs...)
+}
+
+// ParseLine parses Claude's stream-json output.
+func (a *ClaudeAgent) ParseLine(line string) []Event {
+	return ParseLine(line)
+}
+
+// Output formats understood by CommandAgent.
+const (
+	OutputStreamJSON = "stream-json"
+	OutputText       = "text"
+)
+
+// CommandAgent runs an arbitrary command. The placeholders {{PROMPT}},
+// {{WORK_DIR}} and {{MODEL}} are substituted in Args; when no argument
+// contains {{PROMPT}}, the prompt is written to the command's stdin instead.
+// Extra arguments from the request are added after Args.
+type CommandAgent struct {
+	Command string
+	Args    []string
+	Output  string // "stream-json" (default) or "text"
+}
+
+// Name returns the agent name.
+func (a *CommandAgent) Name() string {
+	return a.Command
+}
+
+// Start launches the command with the iteration prompt.
+func (a *CommandAgent) Start(ctx context.Context, req AgentRequest) (AgentProcess, error) {
+	args := make([]string, len(a.Args))
+	promptInArgs := false
+	for i, arg := range a.Args {
+		if strings.Contains(arg, "{{PROMPT}}") {
+			promptInArgs = true
+		}
+		arg = strings.ReplaceAll(arg, "{{PROMPT}}", req.Prompt)
+		arg = strings.ReplaceAll(arg, "{{MODEL}}", req.Model)
+		args[i] = strings.ReplaceAll(arg, "{{WORK_DIR}}", req.WorkDir)
+	}
+	args = append(args, req.ExtraArgs...)
+
+	var stdin io.Reader
+	if !promptInArgs {
+		stdin = strings.NewReader(req.Prompt)
+	}
+	return startProcess(ctx, req, a.Command, args, stdin)
+}
+
+// ParseLine parses a line of output according to the configured format.
+// Plain text output is surfaced as assistant text.
+func (a *CommandAgent) ParseLine(line string) []Event {
+	if a.Output == OutputText {
+		if strings.TrimSpace(line) == "" {
+			return nil
+		}
+		return []Event{{Type: EventAssistantText, Text: line}}
+	}
+	return ParseLine(line)
+}
+
+// killGrace is how long a killed agent's output may still drain before its
+// pipes are closed, in case a process that escaped the kill holds them open.
+const killGrace = 5 * time.Second
+
+// execProcess is an AgentProcess backed by an exec.Cmd.
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+}
+
+// startProcess starts a command in the request's work dir with piped stdout
+// and stderr, launching it through the request's wrapper if there is one.
+// The command runs in its own process group, so killing it also kills the
+// processes it started, which would otherwise keep the pipes open.
+func startProcess(ctx context.Context, req AgentRequest, command string, args []string, stdin io.Reader) (AgentProcess, error) {
+	command, args = wrapCommand(req.Wrapper, req.WorkDir, req.PRDDir, command, args)
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = req.WorkDir
+	cmd.Stdin = stdin
+	setProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	p := &execProcess{cmd: cmd, stdout: stdout, stderr: stderr}
+	cmd.Cancel = p.Kill
+	if err := cmd.Start(); err != nil {
+		if len(req.Wrapper) > 0 {
+			return nil, &WrapperError{Wrapper: command, Err: err}
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *execProcess) Stdout() io.Reader { return p.stdout }
+func (p *execProcess) Stderr() io.Reader { return p.stderr }
+func (p *execProcess) Wait() error       { return p.cmd.Wait() }
+
+func (p *execProcess) Kill() error {
+	if p.cmd.Process == nil {
+		return nil
+	}
+	err := killProcessGroup(p.cmd.Process)
+	time.AfterFunc(killGrace, func() {
+		p.stdout.Close()
+		p.stderr.Close()
+	})
+	return err
+}
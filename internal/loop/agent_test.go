@@ -0,0 +1,523 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// fakeAgent is an in-process Agent for testing the loop without spawning Claude.
+type fakeAgent struct {
+	mu       sync.Mutex
+	runs     []fakeRun
+	requests []AgentRequest
+}
+
+// fakeRun describes the output and outcome of one fake agent invocation.
+type fakeRun struct {
+	stdout  []string
+	stderr  []string
+	waitErr error
+	onStart func() // Called when the run starts (e.g. to update prd.json)
+	hang    bool   // Write stdout, then block until killed
+}
+
+func (a *fakeAgent) Name() string { return "Fake" }
+
+func (a *fakeAgent) Start(ctx context.Context, req AgentRequest) (AgentProcess, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	idx := len(a.requests)
+	a.requests = append(a.requests, req)
+	if idx >= len(a.runs) {
+		return nil, errors.New("no more fake runs")
+	}
+	run := a.runs[idx]
+	if run.onStart != nil {
+		run.onStart()
+	}
+	if run.hang {
+		return newHangingProcess(run.stdout), nil
+	}
+	return &fakeProcess{
+		stdout:  strings.NewReader(strings.Join(run.stdout, "\n") + "\n"),
+		stderr:  strings.NewReader(strings.Join(run.stderr, "\n")),
+		waitErr: run.waitErr,
+	}, nil
+}
+
+func (a *fakeAgent) ParseLine(line string) []Event { return ParseLine(line) }
+
+func (a *fakeAgent) Requests() []AgentRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AgentRequest(nil), a.requests...)
+}
+
+type fakeProcess struct {
+	stdout  io.Reader
+	stderr  io.Reader
+	waitErr error
+}
+
+func (p *fakeProcess) Stdout() io.Reader { return p.stdout }
+func (p *fakeProcess) Stderr() io.Reader { return p.stderr }
+func (p *fakeProcess) Wait() error       { return p.waitErr }
+func (p *fakeProcess) Kill() error       { return nil }
+
+// markAllPassed returns an onStart hook that marks every story in prdPath as passed.
+func markAllPassed(t *testing.T, prdPath string) func() {
+	return func() {
+		p, err := prd.LoadPRD(prdPath)
+		if err != nil {
+			t.Errorf("Failed to load PRD: %v", err)
+			return
+		}
+		for i := range p.UserStories {
+			p.UserStories[i].Passes = true
+		}
+		if err := p.Save(prdPath); err != nil {
+			t.Errorf("Failed to save PRD: %v", err)
+		}
+	}
+}
+
+// collectEvents runs the loop to completion and returns all emitted events.
+func collectEvents(t *testing.T, l *Loop) ([]Event, error) {
+	t.Helper()
+
+	var events []Event
+	done := make(chan struct{})
+	go func() {
+		for event := range l.Events() {
+			events = append(events, event)
+		}
+		close(done)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := l.Run(ctx)
+	<-done
+	return events, err
+}
+
+func TestLoop_RunWithFakeAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{
+		stdout: []string{
+			`{"type":"system","subtype":"init"}`,
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+		},
+		onStart: markAllPassed(t, prdPath),
+	}}}
+
+	l := NewLoopWithWorkDir(prdPath, "/work/dir", "test prompt", 3)
+	l.SetAgent(agent)
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	reqs := agent.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("Expected 1 agent run, got %d", len(reqs))
+	}
+	if reqs[0].Prompt != "test prompt" || reqs[0].WorkDir != "/work/dir" {
+		t.Errorf("Unexpected agent request: %+v", reqs[0])
+	}
+
+	var sawStory, sawComplete bool
+	for _, e := range events {
+		if e.Type == EventStoryStarted && e.StoryID == "US-001" {
+			sawStory = true
+			if e.Iteration != 1 {
+				t.Errorf("Expected iteration 1 on story event, got %d", e.Iteration)
+			}
+		}
+		if e.Type == EventComplete {
+			sawComplete = true
+		}
+	}
+	if !sawStory {
+		t.Error("Expected StoryStarted event from fake agent output")
+	}
+	if !sawComplete {
+		t.Error("Expected Complete event after prd.json was marked complete")
+	}
+}
+
+func TestLoop_RunEmitsEveryContentBlock(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{
+		stdout: []string{
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"Let me fix both files."},{"type":"tool_use","id":"t1","name":"Edit","input":{}},{"type":"tool_use","id":"t2","name":"Edit","input":{}}]}}`,
+		},
+		onStart: markAllPassed(t, prdPath),
+	}}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 3)
+	l.SetAgent(agent)
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var types []string
+	for _, e := range events {
+		switch e.Type {
+		case EventAssistantText, EventToolStart:
+			types = append(types, e.Type.String()+":"+e.ToolUseID)
+			if e.Iteration != 1 {
+				t.Errorf("Expected iteration 1 on %s event, got %d", e.Type, e.Iteration)
+			}
+		}
+	}
+	want := "AssistantText:,ToolStart:t1,ToolStart:t2"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("Expected events %s, got %s", want, got)
+	}
+}
+
+func TestLoop_RunRetriesFailedAgent(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{waitErr: errors.New("exit status 1"), stderr: []string{"boom"}},
+		{onStart: markAllPassed(t, prdPath)},
+	}}
+
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(agent)
+	l.SetRetryConfig(RetryConfig{MaxRetries: 2, Enabled: true})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if got := len(agent.Requests()); got != 2 {
+		t.Errorf("Expected 2 agent runs, got %d", got)
+	}
+
+	var retries int
+	for _, e := range events {
+		if e.Type == EventRetrying {
+			retries++
+		}
+	}
+	if retries != 1 {
+		t.Errorf("Expected 1 Retrying event, got %d", retries)
+	}
+}
+
+func TestLoop_RunResumesCrashedSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		resume     bool
+		wantResume string
+	}{
+		{name: "resume enabled", resume: true, wantResume: "sess-1"},
+		{name: "resume disabled", resume: false, wantResume: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			prdPath := createTestPRD(t, tmpDir, false)
+
+			agent := &fakeAgent{runs: []fakeRun{
+				{
+					stdout:  []string{`{"type":"system","subtype":"init","session_id":"sess-1"}`},
+					waitErr: errors.New("exit status 1"),
+				},
+				{onStart: markAllPassed(t, prdPath)},
+			}}
+
+			l := NewLoop(prdPath, "test prompt", 3)
+			l.SetAgent(agent)
+			l.SetRetryConfig(RetryConfig{MaxRetries: 1, Enabled: true, ResumeSession: tt.resume})
+
+			events, err := collectEvents(t, l)
+			if err != nil {
+				t.Fatalf("Run returned error: %v", err)
+			}
+
+			requests := agent.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("Expected 2 agent runs, got %d", len(requests))
+			}
+			if requests[0].ResumeSession != "" {
+				t.Errorf("Expected first run to start fresh, got session %q", requests[0].ResumeSession)
+			}
+			if requests[1].ResumeSession != tt.wantResume {
+				t.Errorf("Expected retry to resume %q, got %q", tt.wantResume, requests[1].ResumeSession)
+			}
+
+			for _, e := range events {
+				if e.Type == EventRetrying && e.SessionID != tt.wantResume {
+					t.Errorf("Expected Retrying event session %q, got %q", tt.wantResume, e.SessionID)
+				}
+			}
+
+			logData, err := os.ReadFile(RunLogPath(prdPath, 1))
+			if err != nil {
+				t.Fatalf("Failed to read log: %v", err)
+			}
+			if !strings.Contains(string(logData), "[session] sess-1") {
+				t.Errorf("Expected session ID in claude.log, got:\n%s", logData)
+			}
+		})
+	}
+}
+
+func TestClaudeAgent_ArgsResume(t *testing.T) {
+	agent := &ClaudeAgent{}
+
+	args := strings.Join(agent.args(AgentRequest{Prompt: "do the work"}), " ")
+	if strings.Contains(args, "--resume") || !strings.Contains(args, "-p do the work") {
+		t.Errorf("Unexpected args for a fresh session: %s", args)
+	}
+
+	args = strings.Join(agent.args(AgentRequest{Prompt: "do the work", ResumeSession: "sess-1"}), " ")
+	if !strings.Contains(args, "--resume sess-1") {
+		t.Errorf("Expected --resume sess-1, got: %s", args)
+	}
+	if strings.Contains(args, "do the work") {
+		t.Errorf("Expected resumed session to get the resume prompt, got: %s", args)
+	}
+}
+
+func TestClaudeAgent_ArgsModel(t *testing.T) {
+	agent := &ClaudeAgent{}
+
+	args := strings.Join(agent.args(AgentRequest{Prompt: "p"}), " ")
+	if strings.Contains(args, "--model") {
+		t.Errorf("Expected no --model without a model, got: %s", args)
+	}
+
+	args = strings.Join(agent.args(AgentRequest{Prompt: "p", Model: "opus", ExtraArgs: []string{"--max-turns", "40"}}), " ")
+	if !strings.Contains(args, "--model opus") {
+		t.Errorf("Expected --model opus, got: %s", args)
+	}
+	if !strings.HasSuffix(args, "--max-turns 40") {
+		t.Errorf("Expected extra args at the end, got: %s", args)
+	}
+}
+
+func TestLoop_RunPassesModelAndAgentArgs(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := filepath.Join(tmpDir, "prd.json")
+	p := &prd.PRD{
+		Project:   "Test",
+		AgentArgs: []string{"--max-turns", "50"},
+		UserStories: []prd.UserStory{
+			{ID: "US-001", Priority: 1},
+			{ID: "US-002", Priority: 2, Model: "opus", AgentArgs: []string{"--verbose"}},
+		},
+	}
+	if err := p.Save(prdPath); err != nil {
+		t.Fatalf("Failed to save PRD: %v", err)
+	}
+
+	markPassed := func(id string) func() {
+		return func() {
+			p, err := prd.LoadPRD(prdPath)
+			if err != nil {
+				t.Errorf("Failed to load PRD: %v", err)
+				return
+			}
+			for i := range p.UserStories {
+				if p.UserStories[i].ID == id {
+					p.UserStories[i].Passes = true
+				}
+			}
+			if err := p.Save(prdPath); err != nil {
+				t.Errorf("Failed to save PRD: %v", err)
+			}
+		}
+	}
+	agent := &fakeAgent{runs: []fakeRun{
+		{onStart: markPassed("US-001")},
+		{onStart: markPassed("US-002")},
+	}}
+
+	l := NewLoop(prdPath, "test prompt", 5)
+	l.SetAgent(agent)
+	l.SetAgentOptions("sonnet", []string{"--add-dir", "/shared"})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	requests := agent.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 agent runs, got %d", len(requests))
+	}
+	if requests[0].Model != "sonnet" || strings.Join(requests[0].ExtraArgs, " ") != "--add-dir /shared --max-turns 50" {
+		t.Errorf("Unexpected first request: model %q, args %v", requests[0].Model, requests[0].ExtraArgs)
+	}
+	if requests[1].Model != "opus" || strings.Join(requests[1].ExtraArgs, " ") != "--add-dir /shared --max-turns 50 --verbose" {
+		t.Errorf("Unexpected second request: model %q, args %v", requests[1].Model, requests[1].ExtraArgs)
+	}
+
+	var models []string
+	for _, e := range events {
+		if e.Type == EventIterationStart {
+			models = append(models, e.Model)
+		}
+	}
+	if strings.Join(models, ",") != "sonnet,opus" {
+		t.Errorf("Expected IterationStart events with sonnet and opus, got %v", models)
+	}
+}
+
+func TestLoop_RunAgentFailureWithoutRetry(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{waitErr: errors.New("exit status 1")}}}
+
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(agent)
+	l.DisableRetry()
+
+	events, err := collectEvents(t, l)
+	if err == nil {
+		t.Fatal("Expected Run to return an error")
+	}
+	if !strings.Contains(err.Error(), "Fake exited with error") {
+		t.Errorf("Expected error to name the agent, got %v", err)
+	}
+	if len(events) == 0 || events[len(events)-1].Type != EventError {
+		t.Errorf("Expected last event to be Error, got %v", events)
+	}
+}
+
+func TestNewAgent(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.AgentConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "default", cfg: config.AgentConfig{}, want: "Claude"},
+		{name: "claude with path", cfg: config.AgentConfig{Provider: "claude", Command: "/opt/claude"}, want: "Claude"},
+		{name: "command", cfg: config.AgentConfig{Provider: "command", Command: "my-agent"}, want: "my-agent"},
+		{name: "command without binary", cfg: config.AgentConfig{Provider: "command"}, wantErr: true},
+		{name: "bad output", cfg: config.AgentConfig{Provider: "command", Command: "x", Output: "xml"}, wantErr: true},
+		{name: "unknown provider", cfg: config.AgentConfig{Provider: "nope"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent, err := NewAgent(tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if agent.Name() != tt.want {
+				t.Errorf("Expected agent %q, got %q", tt.want, agent.Name())
+			}
+		})
+	}
+}
+
+func TestCommandAgent_Start(t *testing.T) {
+	workDir := t.TempDir()
+	agent := &CommandAgent{
+		Command: "sh",
+		Args:    []string{"-c", `printf '%s|%s\n' "$0" "$PWD"`, "{{PROMPT}}"},
+		Output:  OutputText,
+	}
+
+	proc, err := agent.Start(context.Background(), AgentRequest{Prompt: "hello", WorkDir: workDir})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	out, _ := io.ReadAll(proc.Stdout())
+	io.ReadAll(proc.Stderr())
+	if err := proc.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	if got := strings.TrimSpace(string(out)); !strings.HasPrefix(got, "hello|") {
+		t.Errorf("Expected prompt substituted into args, got %q", got)
+	}
+}
+
+func TestCommandAgent_StartModelAndExtraArgs(t *testing.T) {
+	agent := &CommandAgent{
+		Command: "sh",
+		Args:    []string{"-c", `echo "$@"`, "sh", "--model={{MODEL}}"},
+		Output:  OutputText,
+	}
+
+	proc, err := agent.Start(context.Background(), AgentRequest{Prompt: "p", WorkDir: t.TempDir(), Model: "fast", ExtraArgs: []string{"--quiet"}})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	out, _ := io.ReadAll(proc.Stdout())
+	io.ReadAll(proc.Stderr())
+	if err := proc.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	if got := strings.TrimSpace(string(out)); got != "--model=fast --quiet" {
+		t.Errorf("Expected model substituted and extra args appended, got %q", got)
+	}
+}
+
+func TestCommandAgent_StartPromptOnStdin(t *testing.T) {
+	agent := &CommandAgent{Command: "cat", Output: OutputText}
+
+	proc, err := agent.Start(context.Background(), AgentRequest{Prompt: "from stdin", WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	out, _ := io.ReadAll(proc.Stdout())
+	io.ReadAll(proc.Stderr())
+	proc.Wait()
+
+	if string(out) != "from stdin" {
+		t.Errorf("Expected prompt on stdin, got %q", string(out))
+	}
+}
+
+func TestCommandAgent_ParseLineText(t *testing.T) {
+	agent := &CommandAgent{Command: "x", Output: OutputText}
+
+	if e := agent.ParseLine("   "); e != nil {
+		t.Errorf("Expected nil for blank line, got %v", e)
+	}
+	if e := agent.ParseLine("doing things"); len(e) != 1 || e[0].Type != EventAssistantText {
+		t.Errorf("Expected AssistantText event, got %v", e)
+	}
+
+	jsonAgent := &CommandAgent{Command: "x"}
+	if e := jsonAgent.ParseLine(`{"type":"system","subtype":"init"}`); len(e) != 1 || e[0].Type != EventIterationStart {
+		t.Errorf("Expected stream-json parsing by default, got %v", e)
+	}
+}
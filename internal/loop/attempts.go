@@ -0,0 +1,99 @@
+package loop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// maxBlockedReasonLen limits the reason recorded for a blocked story.
+const maxBlockedReasonLen = 300
+
+// nextStoryIDs returns the ID of the story the next iteration works on.
+func (l *Loop) nextStoryIDs() []string {
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		return nil
+	}
+	if story := p.NextStory(); story != nil {
+		return []string{story.ID}
+	}
+	return nil
+}
+
+// recordAttempts counts an iteration against each story that was worked on.
+// Stories that still haven't passed once they reach the attempt cap are
+// blocked, with the last thing the agent said as the reason, so the loop
+// moves on to the next eligible story.
+func (l *Loop) recordAttempts(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	maxAttempts := l.maxAttempts
+	iter := l.iteration
+	reason := l.lastText
+	l.mu.Unlock()
+
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		// The completion check reports unreadable PRDs
+		return nil
+	}
+
+	var blocked []*prd.UserStory
+	for i := range p.UserStories {
+		story := &p.UserStories[i]
+		for _, id := range ids {
+			if story.ID != id {
+				continue
+			}
+			story.Attempts++
+			if !story.Passes && maxAttempts > 0 && story.Attempts >= maxAttempts {
+				story.Blocked = true
+				story.BlockedReason = blockedReason(reason, story.Attempts)
+				story.InProgress = false
+				blocked = append(blocked, story)
+			}
+		}
+	}
+	if err := p.Save(l.prdPath); err != nil {
+		return fmt.Errorf("failed to record story attempts: %w", err)
+	}
+
+	for _, story := range blocked {
+		l.logLine(fmt.Sprintf("[blocked] %s after %d attempts: %s", story.ID, story.Attempts, story.BlockedReason))
+		l.emit(Event{
+			Type:      EventStoryBlocked,
+			Iteration: iter,
+			StoryID:   story.ID,
+			Text:      fmt.Sprintf("Blocked %s after %d attempts: %s", story.ID, story.Attempts, story.BlockedReason),
+		})
+	}
+	return nil
+}
+
+// blockedReason condenses the agent's last message into a reason for
+// blocking a story: its last paragraph, shortened to fit on a line or two.
+func blockedReason(text string, attempts int) string {
+	text = strings.TrimSpace(text)
+	if i := strings.LastIndex(text, "\n\n"); i >= 0 {
+		text = strings.TrimSpace(text[i:])
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return fmt.Sprintf("not passing after %d attempts", attempts)
+	}
+	if r := []rune(text); len(r) > maxBlockedReasonLen {
+		text = strings.TrimSpace(string(r[:maxBlockedReasonLen])) + "…"
+	}
+	return text
+}
+
+// allBlocked reports whether every remaining story is blocked, or waits on a
+// blocked story, so there's nothing left for the loop to work on.
+func allBlocked(p *prd.PRD) bool {
+	return !p.AllComplete() && p.NextStory() == nil
+}
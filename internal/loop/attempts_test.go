@@ -0,0 +1,101 @@
+package loop
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+func TestLoop_RunBlocksStoryAfterMaxAttempts(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := filepath.Join(tmpDir, "prd.json")
+	data, _ := json.MarshalIndent(&prd.PRD{
+		Project: "Test Project",
+		UserStories: []prd.UserStory{
+			{ID: "US-001", Title: "Hard", Priority: 1},
+			{ID: "US-002", Title: "Easy", Priority: 2},
+		},
+	}, "", "  ")
+	if err := os.WriteFile(prdPath, data, 0644); err != nil {
+		t.Fatalf("Failed to create test PRD: %v", err)
+	}
+
+	stuck := fakeRun{stdout: []string{
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"Working on it.\n\nThe migration keeps failing."}]}}`,
+	}}
+	agent := &fakeAgent{runs: []fakeRun{stuck, stuck, {onStart: markAllPassed(t, prdPath)}}}
+
+	l := NewLoop(prdPath, "test prompt", 5)
+	l.SetAgent(agent)
+	l.SetMaxStoryAttempts(2)
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	var blocked []Event
+	for _, e := range events {
+		if e.Type == EventStoryBlocked {
+			blocked = append(blocked, e)
+		}
+	}
+	if len(blocked) != 1 || blocked[0].StoryID != "US-001" {
+		t.Fatalf("Expected US-001 to be blocked once, got %v", blocked)
+	}
+	if !strings.Contains(blocked[0].Text, "The migration keeps failing.") {
+		t.Errorf("Expected the agent's last message as the reason, got %q", blocked[0].Text)
+	}
+	if n := len(agent.Requests()); n != 3 {
+		t.Errorf("Expected the loop to move on after 2 attempts, got %d runs", n)
+	}
+
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		t.Fatalf("Failed to load PRD: %v", err)
+	}
+	if story := p.UserStories[0]; !story.Blocked || story.Attempts != 2 || story.BlockedReason != "The migration keeps failing." {
+		t.Errorf("Expected US-001 blocked after 2 attempts, got %+v", story)
+	}
+	if story := p.UserStories[1]; story.Attempts != 1 {
+		t.Errorf("Expected 1 attempt for US-002, got %d", story.Attempts)
+	}
+}
+
+func TestLoop_RunStopsWhenAllStoriesBlocked(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{}, {}, {}}}
+	l := NewLoop(prdPath, "test prompt", 5)
+	l.SetAgent(agent)
+	l.SetMaxStoryAttempts(1)
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := len(agent.Requests()); n != 1 {
+		t.Errorf("Expected 1 run before the story was blocked, got %d", n)
+	}
+	if last := events[len(events)-1]; last.Type != EventAllStoriesBlocked {
+		t.Errorf("Expected the loop to end with AllStoriesBlocked, got %v", last.Type)
+	}
+}
+
+func TestBlockedReason(t *testing.T) {
+	if got := blockedReason("", 3); got != "not passing after 3 attempts" {
+		t.Errorf("Expected a fallback reason, got %q", got)
+	}
+	if got := blockedReason("first\n\nsecond  line\nwraps", 3); got != "second line wraps" {
+		t.Errorf("Expected the last paragraph on one line, got %q", got)
+	}
+	long := strings.Repeat("é", maxBlockedReasonLen+10)
+	if got := blockedReason(long, 3); len([]rune(got)) != maxBlockedReasonLen+1 {
+		t.Errorf("Expected the reason to be shortened, got %d runes", len([]rune(got)))
+	}
+}
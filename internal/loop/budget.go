@@ -0,0 +1,146 @@
+package loop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+// Budget limits how much a loop may spend before it stops. Zero fields mean
+// no limit.
+type Budget struct {
+	MaxCostUSD  float64
+	MaxTokens   int
+	MaxDuration time.Duration
+}
+
+// IsZero returns true if the budget sets no limits.
+func (b Budget) IsZero() bool {
+	return b == Budget{}
+}
+
+// Merge returns b with every limit that is set in override replaced.
+func (b Budget) Merge(override Budget) Budget {
+	if override.MaxCostUSD > 0 {
+		b.MaxCostUSD = override.MaxCostUSD
+	}
+	if override.MaxTokens > 0 {
+		b.MaxTokens = override.MaxTokens
+	}
+	if override.MaxDuration > 0 {
+		b.MaxDuration = override.MaxDuration
+	}
+	return b
+}
+
+// Exceeded checks usage and elapsed time against the budget. It returns a
+// description of the first limit that was reached, or an empty string.
+func (b Budget) Exceeded(u Usage, elapsed time.Duration) string {
+	if b.MaxCostUSD > 0 && u.CostUSD >= b.MaxCostUSD {
+		return fmt.Sprintf("cost $%.2f reached limit of $%.2f", u.CostUSD, b.MaxCostUSD)
+	}
+	if b.MaxTokens > 0 && u.TotalTokens() >= b.MaxTokens {
+		return fmt.Sprintf("%s tokens reached limit of %s", FormatTokens(u.TotalTokens()), FormatTokens(b.MaxTokens))
+	}
+	if b.MaxDuration > 0 && elapsed >= b.MaxDuration {
+		return fmt.Sprintf("running time %s reached limit of %s", elapsed.Round(time.Second), b.MaxDuration)
+	}
+	return ""
+}
+
+// BudgetFromConfig converts config limits into a Budget.
+func BudgetFromConfig(limits config.BudgetLimits) (Budget, error) {
+	b := Budget{
+		MaxCostUSD: limits.MaxCostUSD,
+		MaxTokens:  limits.MaxTokens,
+	}
+	if limits.MaxDuration != "" {
+		d, err := time.ParseDuration(limits.MaxDuration)
+		if err != nil {
+			return Budget{}, fmt.Errorf("invalid maxDuration %q: %w", limits.MaxDuration, err)
+		}
+		b.MaxDuration = d
+	}
+	if b.MaxCostUSD < 0 || b.MaxTokens < 0 || b.MaxDuration < 0 {
+		return Budget{}, fmt.Errorf("budget limits must not be negative")
+	}
+	return b, nil
+}
+
+// budgetTracker accumulates usage and running time, overall and per story.
+// Usage starts from the PRD's usage.json, running time from zero each run.
+type budgetTracker struct {
+	usage        Usage
+	elapsed      time.Duration
+	storyUsage   map[string]Usage
+	storyElapsed map[string]time.Duration
+}
+
+// addUsage records usage reported for an iteration of a story.
+func (t *budgetTracker) addUsage(storyID string, u Usage) {
+	t.usage.Add(u)
+	if storyID == "" {
+		return
+	}
+	if t.storyUsage == nil {
+		t.storyUsage = make(map[string]Usage)
+	}
+	su := t.storyUsage[storyID]
+	su.Add(u)
+	t.storyUsage[storyID] = su
+}
+
+// addElapsed records wall-clock time spent on an iteration of a story.
+func (t *budgetTracker) addElapsed(storyID string, d time.Duration) {
+	t.elapsed += d
+	if storyID == "" {
+		return
+	}
+	if t.storyElapsed == nil {
+		t.storyElapsed = make(map[string]time.Duration)
+	}
+	t.storyElapsed[storyID] += d
+}
+
+// check returns a budget-exceeded event if the run or the given story is over
+// budget, or nil.
+func (t *budgetTracker) check(prdBudget, storyBudget Budget, storyID string) *Event {
+	if reason := prdBudget.Exceeded(t.usage, t.elapsed); reason != "" {
+		u := t.usage
+		return &Event{
+			Type:  EventBudgetExceeded,
+			Text:  "PRD budget exceeded: " + reason,
+			Usage: &u,
+		}
+	}
+	if storyID == "" {
+		return nil
+	}
+	if reason := storyBudget.Exceeded(t.storyUsage[storyID], t.storyElapsed[storyID]); reason != "" {
+		u := t.storyUsage[storyID]
+		return &Event{
+			Type:    EventBudgetExceeded,
+			StoryID: storyID,
+			Text:    fmt.Sprintf("Story %s budget exceeded: %s", storyID, reason),
+			Usage:   &u,
+		}
+	}
+	return nil
+}
+
+// seedBudget counts the usage recorded in usage.json by earlier runs against
+// the budgets, so that restarting a PRD doesn't reset what it has spent.
+func (l *Loop) seedBudget() {
+	totals, err := LoadUsage(l.prdPath)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.spent.usage = totals.Total
+	l.spent.storyUsage = make(map[string]Usage, len(totals.Stories))
+	for id, u := range totals.Stories {
+		l.spent.storyUsage[id] = u
+	}
+}
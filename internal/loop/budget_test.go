@@ -0,0 +1,178 @@
+package loop
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+func TestBudgetExceeded(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  Budget
+		usage   Usage
+		elapsed time.Duration
+		want    string
+	}{
+		{"no limits", Budget{}, Usage{CostUSD: 100, InputTokens: 1_000_000}, time.Hour, ""},
+		{"under cost", Budget{MaxCostUSD: 5}, Usage{CostUSD: 4.99}, 0, ""},
+		{"cost reached", Budget{MaxCostUSD: 5}, Usage{CostUSD: 5.10}, 0, "cost $5.10 reached limit of $5.00"},
+		{"tokens reached", Budget{MaxTokens: 1000}, Usage{InputTokens: 600, OutputTokens: 400}, 0, "1.0k tokens reached limit of 1.0k"},
+		{"duration reached", Budget{MaxDuration: time.Minute}, Usage{}, 90 * time.Second, "running time 1m30s reached limit of 1m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.Exceeded(tt.usage, tt.elapsed); got != tt.want {
+				t.Errorf("Exceeded() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetMerge(t *testing.T) {
+	base := Budget{MaxCostUSD: 10, MaxTokens: 5000}
+	got := base.Merge(Budget{MaxCostUSD: 2, MaxDuration: time.Hour})
+
+	want := Budget{MaxCostUSD: 2, MaxTokens: 5000, MaxDuration: time.Hour}
+	if got != want {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBudgetFromConfig(t *testing.T) {
+	b, err := BudgetFromConfig(config.BudgetLimits{MaxCostUSD: 3, MaxTokens: 100, MaxDuration: "90m"})
+	if err != nil {
+		t.Fatalf("BudgetFromConfig failed: %v", err)
+	}
+	if b.MaxCostUSD != 3 || b.MaxTokens != 100 || b.MaxDuration != 90*time.Minute {
+		t.Errorf("unexpected budget: %+v", b)
+	}
+
+	if _, err := BudgetFromConfig(config.BudgetLimits{MaxDuration: "soon"}); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+	if _, err := BudgetFromConfig(config.BudgetLimits{MaxCostUSD: -1}); err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
+
+const costlyResult = `{"type":"result","subtype":"success","total_cost_usd":0.6,"num_turns":2,"usage":{"input_tokens":100,"output_tokens":50}}`
+
+func TestLoop_RunStopsOnPRDBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	run := fakeRun{stdout: []string{
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+		costlyResult,
+	}}
+	agent := &fakeAgent{runs: []fakeRun{run, run, run, run}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 10)
+	l.SetAgent(agent)
+	l.SetBudget(Budget{MaxCostUSD: 1}, Budget{})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if n := len(agent.Requests()); n != 2 {
+		t.Errorf("Expected loop to stop after 2 iterations, got %d", n)
+	}
+	last := events[len(events)-1]
+	if last.Type != EventBudgetExceeded {
+		t.Fatalf("Expected last event to be BudgetExceeded, got %s", last.Type)
+	}
+	if last.StoryID != "" || !strings.HasPrefix(last.Text, "PRD budget exceeded") {
+		t.Errorf("Unexpected budget event: %+v", last)
+	}
+	if last.Usage == nil || last.Usage.CostUSD != 1.2 {
+		t.Errorf("Expected usage $1.20 on budget event, got %+v", last.Usage)
+	}
+}
+
+func TestLoop_RunStopsOnStoryBudget(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{stdout: []string{
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+			costlyResult,
+		}},
+		{stdout: []string{costlyResult}},
+	}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 10)
+	l.SetAgent(agent)
+	l.SetBudget(Budget{}, Budget{MaxTokens: 100})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if n := len(agent.Requests()); n != 1 {
+		t.Errorf("Expected loop to stop after 1 iteration, got %d", n)
+	}
+	last := events[len(events)-1]
+	if last.Type != EventBudgetExceeded || last.StoryID != "US-001" {
+		t.Errorf("Expected story budget event for US-001, got %+v", last)
+	}
+}
+
+func TestLoop_BudgetCountsEarlierRuns(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	// An earlier run already spent half the budget
+	earlier := Usage{CostUSD: 0.5, InputTokens: 100}
+	if err := SaveUsage(prdPath, &UsageTotals{Total: earlier, Stories: map[string]Usage{"US-001": earlier}}); err != nil {
+		t.Fatalf("Failed to save usage: %v", err)
+	}
+
+	run := fakeRun{stdout: []string{
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+		costlyResult,
+	}}
+	agent := &fakeAgent{runs: []fakeRun{run, run, run}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 10)
+	l.SetAgent(agent)
+	l.SetBudget(Budget{MaxCostUSD: 1}, Budget{})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if n := len(agent.Requests()); n != 1 {
+		t.Errorf("Expected loop to stop after 1 iteration, got %d", n)
+	}
+	last := events[len(events)-1]
+	if last.Type != EventBudgetExceeded || last.Usage == nil || last.Usage.CostUSD != 1.1 {
+		t.Errorf("Expected the budget to be exceeded at $1.10, got %+v", last)
+	}
+
+	// Once the budget is spent, restarting doesn't run another iteration
+	if err := SaveUsage(prdPath, &UsageTotals{Total: Usage{CostUSD: 1.1}}); err != nil {
+		t.Fatalf("Failed to save usage: %v", err)
+	}
+	agent = &fakeAgent{runs: []fakeRun{run}}
+	l = NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 10)
+	l.SetAgent(agent)
+	l.SetBudget(Budget{MaxCostUSD: 1}, Budget{})
+
+	events, err = collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if n := len(agent.Requests()); n != 0 {
+		t.Errorf("Expected no iterations once the budget is spent, got %d", n)
+	}
+	if last := events[len(events)-1]; last.Type != EventBudgetExceeded {
+		t.Errorf("Expected BudgetExceeded, got %s", last.Type)
+	}
+}
@@ -0,0 +1,260 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/git"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// Chain describes the PRDs a PRD runs after.
+type Chain struct {
+	After           []string // PRDs that must complete before the PRD starts
+	BranchFromAfter bool     // Base the worktree branch on the predecessor's branch
+}
+
+// ChainFor returns how a PRD is chained to others: the project config's
+// chain for it if there is one, otherwise the "after" in its prd.json.
+func ChainFor(cfg *config.Config, name string, p *prd.PRD) Chain {
+	if cfg != nil {
+		if c, ok := cfg.Chains[name]; ok {
+			return Chain{After: c.After, BranchFromAfter: c.BranchFromAfter}
+		}
+	}
+	if p == nil {
+		return Chain{}
+	}
+	return Chain{After: p.After, BranchFromAfter: p.BranchFromAfter}
+}
+
+// Follows reports whether the chain waits for the named PRD.
+func (c Chain) Follows(name string) bool {
+	for _, after := range c.After {
+		if after == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateChains checks the chains of the PRDs in prdsDir, from the project
+// config and their prd.json: every PRD a chain runs after must exist, and no
+// chain may lead back to its own PRD. Either would keep PRDs from ever
+// starting.
+func ValidateChains(cfg *config.Config, prdsDir string) error {
+	entries, _ := os.ReadDir(prdsDir)
+	chains := make(map[string]Chain)
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		prdPath := filepath.Join(prdsDir, name, "prd.json")
+		if _, err := os.Stat(prdPath); !entry.IsDir() || err != nil {
+			continue
+		}
+		p, _ := prd.LoadPRD(prdPath)
+		chains[name] = ChainFor(cfg, name, p)
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		for _, after := range chains[name].After {
+			if _, ok := chains[after]; !ok {
+				return fmt.Errorf("%s runs after unknown PRD %q", name, after)
+			}
+		}
+	}
+
+	// Depth-first search for cycles, keeping the current path for the message
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(names))
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return fmt.Errorf("chain cycle: %s", strings.Join(cycle, " → "))
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, after := range chains[name].After {
+			if err := visit(after); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prdsDirOf returns the prds directory a PRD's prd.json is in.
+func prdsDirOf(prdPath string) string {
+	return filepath.Dir(filepath.Dir(prdPath))
+}
+
+// siblingPRDPath returns the path of another PRD's prd.json in the same
+// prds directory.
+func siblingPRDPath(prdPath, name string) string {
+	return filepath.Join(prdsDirOf(prdPath), name, "prd.json")
+}
+
+// startSuccessors starts the PRDs chained after a PRD that just completed,
+// once all of the PRDs they run after are complete. Successors run where the
+// completed PRD ran: in their own worktree if it had one, otherwise in the
+// same directory. Successors with branchFromAfter always get a worktree,
+// branched from the completed PRD's branch.
+func (m *Manager) startSuccessors(completed *LoopInstance) {
+	prdsDir := prdsDirOf(completed.PRDPath)
+	entries, err := os.ReadDir(prdsDir)
+	if err != nil {
+		return
+	}
+	cfg := m.Config()
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == completed.Name {
+			continue
+		}
+		prdPath := filepath.Join(prdsDir, name, "prd.json")
+		p, err := prd.LoadPRD(prdPath)
+		if err != nil || p.AllComplete() {
+			continue
+		}
+		chain := ChainFor(cfg, name, p)
+		if !chain.Follows(completed.Name) || !predecessorsComplete(prdPath, chain) {
+			continue
+		}
+		if err := m.startSuccessor(name, prdPath, completed, chain); err != nil {
+			m.setError(name, fmt.Errorf("failed to start after %s: %w", completed.Name, err))
+		}
+	}
+}
+
+// predecessorsComplete reports whether every PRD in a chain has completed.
+func predecessorsComplete(prdPath string, chain Chain) bool {
+	for _, name := range chain.After {
+		p, err := prd.LoadPRD(siblingPRDPath(prdPath, name))
+		if err != nil || !p.AllComplete() {
+			return false
+		}
+	}
+	return true
+}
+
+// startSuccessor registers a chained PRD if needed, sets up its worktree and
+// starts it.
+func (m *Manager) startSuccessor(name, prdPath string, completed *LoopInstance, chain Chain) error {
+	if m.GetInstance(name) == nil {
+		if err := m.Register(name, prdPath); err != nil {
+			return err
+		}
+	}
+	instance := m.GetInstance(name)
+	if instance.State == LoopStateRunning || instance.State == LoopStateQueued {
+		return nil
+	}
+
+	completed.mu.Lock()
+	predWorktree, predBranch := completed.WorktreeDir, completed.Branch
+	completed.mu.Unlock()
+
+	if instance.WorktreeDir == "" && (predWorktree != "" || chain.BranchFromAfter) {
+		m.mu.RLock()
+		baseDir := m.baseDir
+		var setup string
+		if m.config != nil {
+			setup = m.config.Worktree.Setup
+		}
+		m.mu.RUnlock()
+
+		base := ""
+		if chain.BranchFromAfter {
+			base = predBranch
+			// A predecessor that ran in place worked on the checked-out branch
+			if base == "" {
+				branch, err := git.GetCurrentBranch(baseDir)
+				if err != nil || branch == "" || branch == "HEAD" {
+					return fmt.Errorf("branchFromAfter needs the branch %s ran on", completed.Name)
+				}
+				base = branch
+			}
+		}
+		worktree := git.WorktreePathForPRD(baseDir, name)
+		branch := fmt.Sprintf("chief/%s", name)
+		if err := createWorktree(baseDir, worktree, branch, base, setup); err != nil {
+			return err
+		}
+		if err := m.UpdateWorktreeInfo(name, worktree, branch); err != nil {
+			return err
+		}
+	}
+
+	return m.Start(name)
+}
+
+// createWorktree adds a worktree on a new branch and runs the setup command
+// in it when it was newly created.
+func createWorktree(repoDir, worktree, branch, base, setup string) error {
+	_, statErr := os.Stat(worktree)
+	fresh := os.IsNotExist(statErr)
+	if err := git.CreateWorktreeFrom(repoDir, worktree, branch, base); err != nil {
+		return err
+	}
+	if fresh && setup != "" {
+		cmd := exec.Command("sh", "-c", setup)
+		cmd.Dir = worktree
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("worktree setup failed: %s\n%s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// setError puts a PRD's loop in the error state without running it and
+// reports the error as an event of the PRD.
+func (m *Manager) setError(name string, err error) {
+	m.mu.RLock()
+	instance, exists := m.instances[name]
+	m.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	instance.mu.Lock()
+	instance.State = LoopStateError
+	instance.Error = err
+	instance.mu.Unlock()
+
+	m.events <- ManagerEvent{
+		PRDName: name,
+		Event:   Event{Type: EventError, Err: err, Time: time.Now()},
+	}
+}
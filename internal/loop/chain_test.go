@@ -0,0 +1,237 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/git"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// createChainedPRD writes a PRD with one story under dir/.chief/prds/name.
+func createChainedPRD(t *testing.T, dir, name string, after ...string) string {
+	t.Helper()
+	prdPath := filepath.Join(dir, ".chief", "prds", name, "prd.json")
+	if err := os.MkdirAll(filepath.Dir(prdPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := &prd.PRD{
+		Project:     name,
+		UserStories: []prd.UserStory{{ID: "US-001", Title: "Story", Priority: 1}},
+		After:       after,
+	}
+	if err := p.Save(prdPath); err != nil {
+		t.Fatal(err)
+	}
+	return prdPath
+}
+
+// newChainTestManager creates a manager for dir that drains its events.
+func newChainTestManager(t *testing.T, dir string, agent Agent) *Manager {
+	t.Helper()
+	m := NewManager(10)
+	m.SetBaseDir(dir)
+	m.SetConfig(config.Default())
+	m.SetAgent(agent)
+	go func() {
+		for range m.Events() {
+		}
+	}()
+	t.Cleanup(m.StopAll)
+	return m
+}
+
+func TestChainFor(t *testing.T) {
+	p := &prd.PRD{After: []string{"backend"}}
+	if chain := ChainFor(nil, "frontend", p); !chain.Follows("backend") || chain.BranchFromAfter {
+		t.Errorf("Expected the chain from prd.json, got %+v", chain)
+	}
+
+	cfg := config.Default()
+	cfg.Chains = config.ChainsConfig{"frontend": {After: []string{"api"}, BranchFromAfter: true}}
+	chain := ChainFor(cfg, "frontend", p)
+	if chain.Follows("backend") || !chain.Follows("api") || !chain.BranchFromAfter {
+		t.Errorf("Expected the config to override prd.json, got %+v", chain)
+	}
+}
+
+func TestManagerStartsChainedPRD(t *testing.T) {
+	dir := t.TempDir()
+	backend := createChainedPRD(t, dir, "backend")
+	createChainedPRD(t, dir, "frontend", "backend")
+	createChainedPRD(t, dir, "docs", "backend", "frontend")
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{onStart: markAllPassed(t, backend)},
+		{hang: true},
+	}}
+	m := newChainTestManager(t, dir, agent)
+	m.Register("backend", backend)
+
+	if err := m.Start("backend"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForState(t, m, "frontend", LoopStateRunning)
+	if state, _, _ := m.GetState("backend"); state != LoopStateComplete {
+		t.Errorf("Expected backend to be complete, got %s", state)
+	}
+
+	// docs also waits for frontend, which hasn't completed
+	if inst := m.GetInstance("docs"); inst != nil && inst.State != LoopStateReady {
+		t.Errorf("Expected docs to wait for frontend, got %s", inst.State)
+	}
+}
+
+// initChainRepo creates a git repository on main with a backend PRD and a
+// frontend PRD that branches from it.
+func initChainRepo(t *testing.T) (dir, backend string) {
+	t.Helper()
+	dir = t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+	runGit(t, dir, "checkout", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".chief/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", ".gitignore")
+	runGit(t, dir, "commit", "-m", "initial commit")
+
+	backend = createChainedPRD(t, dir, "backend")
+	frontend := createChainedPRD(t, dir, "frontend", "backend")
+	p, _ := prd.LoadPRD(frontend)
+	p.BranchFromAfter = true
+	if err := p.Save(frontend); err != nil {
+		t.Fatal(err)
+	}
+	return dir, backend
+}
+
+func TestManagerChainedPRDBranchFromAfter(t *testing.T) {
+	dir, backend := initChainRepo(t)
+
+	backendWorktree := git.WorktreePathForPRD(dir, "backend")
+	if err := git.CreateWorktree(dir, backendWorktree, "chief/backend"); err != nil {
+		t.Fatalf("CreateWorktree failed: %v", err)
+	}
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{onStart: func() {
+			// The backend's work is committed on its branch
+			os.WriteFile(filepath.Join(backendWorktree, "api.go"), []byte("package api\n"), 0644)
+			runGit(t, backendWorktree, "add", "api.go")
+			runGit(t, backendWorktree, "commit", "-m", "feat: api")
+			markAllPassed(t, backend)()
+		}},
+		{hang: true},
+	}}
+	m := newChainTestManager(t, dir, agent)
+	m.RegisterWithWorktree("backend", backend, backendWorktree, "chief/backend")
+
+	if err := m.Start("backend"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForState(t, m, "frontend", LoopStateRunning)
+
+	inst := m.GetInstance("frontend")
+	if inst.Branch != "chief/frontend" || inst.WorktreeDir != git.WorktreePathForPRD(dir, "frontend") {
+		t.Errorf("Expected frontend in its own worktree on chief/frontend, got %q on %q", inst.WorktreeDir, inst.Branch)
+	}
+	if _, err := os.Stat(filepath.Join(inst.WorktreeDir, "api.go")); err != nil {
+		t.Errorf("Expected the frontend branch to start from the backend branch: %v", err)
+	}
+}
+
+func TestManagerChainedPRDBranchFromInPlacePredecessor(t *testing.T) {
+	dir, backend := initChainRepo(t)
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{onStart: func() {
+			// The backend runs in place and commits on main
+			os.WriteFile(filepath.Join(dir, "api.go"), []byte("package api\n"), 0644)
+			runGit(t, dir, "add", "api.go")
+			runGit(t, dir, "commit", "-m", "feat: api")
+			markAllPassed(t, backend)()
+		}},
+		{hang: true},
+	}}
+	m := newChainTestManager(t, dir, agent)
+	m.Register("backend", backend)
+
+	if err := m.Start("backend"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForState(t, m, "frontend", LoopStateRunning)
+
+	inst := m.GetInstance("frontend")
+	if inst.Branch != "chief/frontend" || inst.WorktreeDir != git.WorktreePathForPRD(dir, "frontend") {
+		t.Errorf("Expected frontend in its own worktree on chief/frontend, got %q on %q", inst.WorktreeDir, inst.Branch)
+	}
+	if _, err := os.Stat(filepath.Join(inst.WorktreeDir, "api.go")); err != nil {
+		t.Errorf("Expected the frontend branch to start from main: %v", err)
+	}
+}
+
+func TestManagerChainedPRDBranchFromAfterWithoutGit(t *testing.T) {
+	dir := t.TempDir()
+	backend := createChainedPRD(t, dir, "backend")
+	frontend := createChainedPRD(t, dir, "frontend", "backend")
+	p, _ := prd.LoadPRD(frontend)
+	p.BranchFromAfter = true
+	if err := p.Save(frontend); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := &fakeAgent{runs: []fakeRun{{onStart: markAllPassed(t, backend)}}}
+	m := newChainTestManager(t, dir, agent)
+	m.Register("backend", backend)
+
+	if err := m.Start("backend"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForState(t, m, "frontend", LoopStateError)
+	if inst := m.GetInstance("frontend"); inst.WorktreeDir != "" {
+		t.Errorf("Expected frontend not to run in place, got worktree %q", inst.WorktreeDir)
+	}
+}
+
+func TestValidateChains(t *testing.T) {
+	dir := t.TempDir()
+	prdsDir := filepath.Join(dir, ".chief", "prds")
+	createChainedPRD(t, dir, "backend")
+	createChainedPRD(t, dir, "frontend", "backend")
+	if err := ValidateChains(config.Default(), prdsDir); err != nil {
+		t.Fatalf("Expected a valid chain, got %v", err)
+	}
+
+	createChainedPRD(t, dir, "docs", "fronted")
+	if err := ValidateChains(config.Default(), prdsDir); err == nil || err.Error() != `docs runs after unknown PRD "fronted"` {
+		t.Errorf("Expected an unknown predecessor error, got %v", err)
+	}
+
+	// The config overrides the typo, but chains backend after docs
+	cfg := config.Default()
+	cfg.Chains = config.ChainsConfig{
+		"docs":    {After: []string{"frontend"}},
+		"backend": {After: []string{"docs"}},
+	}
+	err := ValidateChains(cfg, prdsDir)
+	if err == nil || err.Error() != "chain cycle: backend → docs → frontend → backend" {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+}
+
+func TestManagerStartRejectsInvalidChains(t *testing.T) {
+	dir := t.TempDir()
+	backend := createChainedPRD(t, dir, "backend", "backend")
+
+	m := newChainTestManager(t, dir, &fakeAgent{})
+	m.Register("backend", backend)
+	err := m.Start("backend")
+	if err == nil || !strings.Contains(err.Error(), "chain cycle: backend → backend") {
+		t.Errorf("Expected Start to report the cycle, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package loop
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FailureKind is the cause of a failed agent iteration.
+type FailureKind string
+
+// Failure kinds, classified from the agent's stderr and error results.
+const (
+	FailureRateLimit     FailureKind = "rate-limit"     // Rate limited or usage cap reached
+	FailureAuth          FailureKind = "auth"           // Not logged in, bad API key or no credit
+	FailureNetwork       FailureKind = "network"        // The agent couldn't reach its API
+	FailureCrash         FailureKind = "crash"          // Any other non-zero exit
+	FailureMissingBinary FailureKind = "missing-binary" // The agent command doesn't exist
+)
+
+// Label returns a human-readable name for the failure kind.
+func (k FailureKind) Label() string {
+	switch k {
+	case FailureRateLimit:
+		return "Rate limit"
+	case FailureAuth:
+		return "Authentication"
+	case FailureNetwork:
+		return "Network"
+	case FailureMissingBinary:
+		return "Missing binary"
+	default:
+		return "Agent crash"
+	}
+}
+
+// Retry reasons reported on EventRetrying, in addition to crashed and stalled.
+const (
+	RetryReasonRateLimited = "rate-limited" // The agent hit a rate limit or usage cap
+	RetryReasonNetwork     = "network"      // The agent couldn't reach its API
+)
+
+// rateLimitMargin is added to a reported reset time before retrying.
+const rateLimitMargin = 30 * time.Second
+
+// Backoff for rate limits that don't say when they reset. Limits take
+// minutes to lift, so the usual short backoff would use up every retry.
+const (
+	rateLimitBaseDelay = time.Minute
+	rateLimitMaxDelay  = 10 * time.Minute
+)
+
+// AgentError is returned when an agent iteration fails, classified by cause.
+type AgentError struct {
+	Agent   string      // Agent name
+	Kind    FailureKind // What went wrong
+	Detail  string      // Output line that identified the failure, if any
+	ResetAt time.Time   // When a rate limit resets (zero if not reported)
+	Err     error       // Exit or start error
+}
+
+func (e *AgentError) Error() string {
+	var msg string
+	switch e.Kind {
+	case FailureRateLimit:
+		msg = e.Agent + " hit a rate limit"
+		if !e.ResetAt.IsZero() {
+			msg += " (resets " + e.ResetAt.Format("Jan 2 15:04") + ")"
+		}
+	case FailureAuth:
+		msg = e.Agent + " authentication failed"
+	case FailureNetwork:
+		msg = e.Agent + " network error"
+	case FailureMissingBinary:
+		msg = fmt.Sprintf("%s not found: %v", e.Agent, e.Err)
+	default:
+		msg = fmt.Sprintf("%s exited with error: %v", e.Agent, e.Err)
+	}
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Fatal() {
+		msg += ". " + e.Hint()
+	}
+	return msg
+}
+
+func (e *AgentError) Unwrap() error {
+	return e.Err
+}
+
+// Fatal reports whether retrying can't help until the user fixes something.
+func (e *AgentError) Fatal() bool {
+	return e.Kind == FailureAuth || e.Kind == FailureMissingBinary
+}
+
+// Hint returns what the user can do about the failure.
+func (e *AgentError) Hint() string {
+	switch e.Kind {
+	case FailureRateLimit:
+		return "Chief waits for the limit to reset before retrying."
+	case FailureAuth:
+		return "Run 'claude' and log in with /login, or check ANTHROPIC_API_KEY and your account's credit."
+	case FailureNetwork:
+		return "Check your internet connection and proxy settings."
+	case FailureMissingBinary:
+		if e.Agent == "Claude" {
+			return "Install Claude Code (npm install -g @anthropic-ai/claude-code) or set agent.command in .chief/config.yaml."
+		}
+		return "Check agent.command in .chief/config.yaml."
+	default:
+		return ""
+	}
+}
+
+var (
+	rateLimitPattern = regexp.MustCompile(`usage limit|rate.?limit|too many requests|overloaded|api error: 429\b|"status":\s*429\b`)
+	authPattern      = regexp.MustCompile(`invalid api key|"type":\s*"authentication_error"|authentication (?:failed|error|required)|unauthorized|api error: 401\b|"status":\s*401\b|/login|not logged in|oauth token|credit balance`)
+	networkPattern   = regexp.MustCompile(`econnrefused|econnreset|enotfound|etimedout|eai_again|connection error|network error|fetch failed|socket hang up|getaddrinfo|unable to connect`)
+)
+
+// classifyFailure works out why an iteration failed from the agent's exit
+// error and the lines it wrote to stderr or reported as an error result.
+func classifyFailure(agent string, err error, lines []string, now time.Time) *AgentError {
+	ae := &AgentError{Agent: agent, Kind: FailureCrash, Err: err}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		ae.Kind = FailureMissingBinary
+		return ae
+	}
+
+	// The last lines hold the final error, so they take precedence
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		lower := strings.ToLower(line)
+		switch {
+		case rateLimitPattern.MatchString(lower):
+			ae.Kind = FailureRateLimit
+			ae.ResetAt = parseResetTime(lower, now)
+		case authPattern.MatchString(lower):
+			ae.Kind = FailureAuth
+		case networkPattern.MatchString(lower):
+			ae.Kind = FailureNetwork
+		default:
+			continue
+		}
+		ae.Detail = line
+		return ae
+	}
+	return ae
+}
+
+var (
+	resetUnixPattern  = regexp.MustCompile(`\|(\d{10})\b`)
+	resetClockPattern = regexp.MustCompile(`resets?\s+(?:at\s+)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)?`)
+	retryAfterPattern = regexp.MustCompile(`(?:retry|try again)\s+(?:after|in)\s+(\d+)\s*(s|sec|secs|seconds?|m|min|mins|minutes?|h|hours?)?\b`)
+)
+
+// parseResetTime extracts when a rate limit resets from a lower-cased message.
+// It understands a Unix timestamp after a "|" ("usage limit reached|1751234567"),
+// a clock time ("resets 3pm", "resets at 15:30") and a delay ("try again in
+// 30 seconds"). It returns the zero time if the message doesn't say.
+func parseResetTime(msg string, now time.Time) time.Time {
+	if m := resetUnixPattern.FindStringSubmatch(msg); m != nil {
+		sec, _ := strconv.ParseInt(m[1], 10, 64)
+		return time.Unix(sec, 0)
+	}
+
+	if m := resetClockPattern.FindStringSubmatch(msg); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		switch {
+		case m[3] == "pm" && hour < 12:
+			hour += 12
+		case m[3] == "am" && hour == 12:
+			hour = 0
+		}
+		if hour > 23 || minute > 59 {
+			return time.Time{}
+		}
+		reset := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !reset.After(now) {
+			reset = reset.AddDate(0, 0, 1)
+		}
+		return reset
+	}
+
+	if m := retryAfterPattern.FindStringSubmatch(msg); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Second
+		switch {
+		case strings.HasPrefix(m[2], "m"):
+			unit = time.Minute
+		case strings.HasPrefix(m[2], "h"):
+			unit = time.Hour
+		}
+		return now.Add(time.Duration(n) * unit)
+	}
+
+	return time.Time{}
+}
+
+// backoff returns the delay before a retry: exponential in the attempt number
+// (1-based), capped at max, with equal jitter so parallel loops spread out.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// retryDelay returns how long to wait before retrying after err. Rate limits
+// with a reported reset time wait until then, and other rate limits back off
+// from a minute; everything else backs off from the configured delay.
+func retryDelay(err error, attempt int, config RetryConfig, now time.Time) time.Duration {
+	var agentErr *AgentError
+	if errors.As(err, &agentErr) && agentErr.Kind == FailureRateLimit {
+		if agentErr.ResetAt.After(now) {
+			return agentErr.ResetAt.Sub(now) + rateLimitMargin
+		}
+		if config.BaseDelay > 0 {
+			return backoff(attempt, max(config.BaseDelay, rateLimitBaseDelay), max(config.MaxDelay, rateLimitMaxDelay))
+		}
+	}
+	return backoff(attempt, config.BaseDelay, config.MaxDelay)
+}
+
+// retryText returns the retry reason and the message shown for retrying after
+// err by the named agent. resume is the session the retry continues, if any.
+func retryText(agent string, err error, delay time.Duration, resume string, attempt, max int) (string, string) {
+	var stallErr *StallError
+	if errors.As(err, &stallErr) {
+		return RetryReasonStalled, fmt.Sprintf("%s stalled (%s), retrying (%d/%d)...", agent, stallErr.Reason, attempt, max)
+	}
+
+	action := "retrying"
+	if resume != "" {
+		action = "resuming session " + resume
+	}
+	wait := delay.Round(time.Second)
+
+	var agentErr *AgentError
+	if errors.As(err, &agentErr) {
+		switch agentErr.Kind {
+		case FailureRateLimit:
+			if !agentErr.ResetAt.IsZero() {
+				return RetryReasonRateLimited, fmt.Sprintf("%s hit a rate limit, %s at %s (%d/%d)...",
+					agent, action, time.Now().Add(delay).Format("15:04"), attempt, max)
+			}
+			return RetryReasonRateLimited, fmt.Sprintf("%s hit a rate limit, %s in %s (%d/%d)...", agent, action, wait, attempt, max)
+		case FailureNetwork:
+			return RetryReasonNetwork, fmt.Sprintf("%s network error, %s in %s (%d/%d)...", agent, action, wait, attempt, max)
+		}
+	}
+	return RetryReasonCrashed, fmt.Sprintf("%s crashed, %s (%d/%d)...", agent, action, attempt, max)
+}
@@ -0,0 +1,210 @@
+package loop
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.Local)
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name  string
+		err   error
+		lines []string
+		want  FailureKind
+	}{
+		{"no output", exitErr, nil, FailureCrash},
+		{"unrelated stderr", exitErr, []string{"panic: something broke"}, FailureCrash},
+		{"usage limit", exitErr, []string{"Claude AI usage limit reached|1748800000"}, FailureRateLimit},
+		{"429", exitErr, []string{"API Error: 429 Too Many Requests"}, FailureRateLimit},
+		{"overloaded", exitErr, []string{`API Error: 529 {"type":"overloaded_error"}`}, FailureRateLimit},
+		{"invalid key", exitErr, []string{"Invalid API key · Please run /login"}, FailureAuth},
+		{"credit", exitErr, []string{"Credit balance is too low"}, FailureAuth},
+		{"network", exitErr, []string{"Error: connect ECONNREFUSED 127.0.0.1:443"}, FailureNetwork},
+		{"missing binary", exec.ErrNotFound, nil, FailureMissingBinary},
+		{"last line wins", exitErr, []string{"fetch failed", "API Error: 401 unauthorized"}, FailureAuth},
+		{"auth error type", exitErr, []string{`API Error: 401 {"type":"error","error":{"type":"authentication_error"}}`}, FailureAuth},
+		{"429 in output", exitErr, []string{"Fixed 429 lint warnings"}, FailureCrash},
+		{"401 in output", exitErr, []string{"GET /api/users/401 returned 500"}, FailureCrash},
+		{"authentication in output", exitErr, []string{"Error: tests in src/authentication.test.ts failed"}, FailureCrash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyFailure("Claude", tt.err, tt.lines, now)
+			if got.Kind != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got.Kind)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Error("Expected the exit error to be wrapped")
+			}
+		})
+	}
+}
+
+func TestAgentError_Error(t *testing.T) {
+	crash := &AgentError{Agent: "Fake", Kind: FailureCrash, Err: errors.New("exit status 1")}
+	if got := crash.Error(); got != "Fake exited with error: exit status 1" {
+		t.Errorf("Expected the crash message unchanged, got %q", got)
+	}
+
+	auth := &AgentError{Agent: "Claude", Kind: FailureAuth, Detail: "Invalid API key", Err: errors.New("exit status 1")}
+	if !auth.Fatal() {
+		t.Error("Expected auth failures to be fatal")
+	}
+	if got := auth.Error(); !strings.Contains(got, "Invalid API key") || !strings.Contains(got, "/login") {
+		t.Errorf("Expected the detail and a fix-it hint, got %q", got)
+	}
+
+	if (&AgentError{Kind: FailureRateLimit}).Fatal() || (&AgentError{Kind: FailureNetwork}).Fatal() {
+		t.Error("Expected rate limits and network errors to be retried")
+	}
+}
+
+func TestParseResetTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		msg  string
+		want time.Time
+	}{
+		{"claude ai usage limit reached|1748800000", time.Unix(1748800000, 0)},
+		{"5-hour limit reached ∙ resets 3pm", time.Date(2025, 6, 1, 15, 0, 0, 0, time.Local)},
+		{"usage limit reached. resets at 14:30", time.Date(2025, 6, 1, 14, 30, 0, 0, time.Local)},
+		{"limit reached, resets 9am", time.Date(2025, 6, 2, 9, 0, 0, 0, time.Local)},
+		{"rate limited, try again in 30 seconds", now.Add(30 * time.Second)},
+		{"too many requests, retry after 2m", now.Add(2 * time.Minute)},
+		{"rate limit exceeded", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseResetTime(tt.msg, now); !got.Equal(tt.want) {
+			t.Errorf("parseResetTime(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base, max := time.Second, 10*time.Second
+	for attempt := 1; attempt <= 6; attempt++ {
+		want := base << (attempt - 1)
+		if want > max {
+			want = max
+		}
+		for i := 0; i < 20; i++ {
+			got := backoff(attempt, base, max)
+			if got < want/2 || got > want {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, got, want/2, want)
+			}
+		}
+	}
+
+	if got := backoff(3, 0, max); got != 0 {
+		t.Errorf("Expected no delay without a base delay, got %v", got)
+	}
+}
+
+func TestRetryDelay_RateLimit(t *testing.T) {
+	now := time.Now()
+	config := RetryConfig{BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	err := fmt.Errorf("wrapped: %w", &AgentError{Kind: FailureRateLimit, ResetAt: now.Add(time.Hour)})
+	if got := retryDelay(err, 1, config, now); got != time.Hour+rateLimitMargin {
+		t.Errorf("Expected to wait until the reset, got %v", got)
+	}
+
+	// Without a reset time, rate limits back off from a minute rather than
+	// the configured delay, so retries aren't used up before the limit lifts
+	err = &AgentError{Kind: FailureRateLimit}
+	if got := retryDelay(err, 1, config, now); got < rateLimitBaseDelay/2 || got > rateLimitBaseDelay {
+		t.Errorf("Expected a backoff delay from %v, got %v", rateLimitBaseDelay, got)
+	}
+	if got := retryDelay(err, 3, config, now); got < 2*rateLimitBaseDelay {
+		t.Errorf("Expected the rate limit backoff to grow past the max delay, got %v", got)
+	}
+
+	// Other failures use the configured backoff
+	if got := retryDelay(errors.New("exit status 1"), 1, config, now); got > time.Second {
+		t.Errorf("Expected a backoff delay, got %v", got)
+	}
+}
+
+func TestRetryText(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		resume string
+		want   string
+	}{
+		{"crash", errors.New("exit status 1"), "", "aider crashed, retrying (1/3)..."},
+		{"resume", errors.New("exit status 1"), "abc", "aider crashed, resuming session abc (1/3)..."},
+		{"network", &AgentError{Kind: FailureNetwork}, "", "aider network error, retrying in 0s (1/3)..."},
+		{"stall", &StallError{Agent: "aider", Reason: "no output for 1m0s"}, "", "aider stalled (no output for 1m0s), retrying (1/3)..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := retryText("aider", tt.err, 0, tt.resume, 1, 3); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoop_RunClassifiesFailures(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{stderr: []string{"API Error: 429 rate_limit_error"}, waitErr: errors.New("exit status 1")},
+		{stderr: []string{"Invalid API key · Please run /login"}, waitErr: errors.New("exit status 1")},
+	}}
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(agent)
+	l.SetRetryConfig(RetryConfig{MaxRetries: 3, Enabled: true})
+
+	events, err := collectEvents(t, l)
+
+	var agentErr *AgentError
+	if !errors.As(err, &agentErr) || agentErr.Kind != FailureAuth {
+		t.Fatalf("Expected an auth failure, got %v", err)
+	}
+	if n := len(agent.Requests()); n != 2 {
+		t.Errorf("Expected auth failures not to be retried, got %d runs", n)
+	}
+
+	var reasons []string
+	for _, e := range events {
+		if e.Type == EventRetrying {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+	if len(reasons) != 1 || reasons[0] != RetryReasonRateLimited {
+		t.Errorf("Expected one rate-limited retry, got %v", reasons)
+	}
+}
+
+func TestLoop_RunMissingBinary(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(&CommandAgent{Command: "chief-missing-agent", Output: OutputText})
+	l.SetRetryConfig(RetryConfig{MaxRetries: 2, Enabled: true})
+
+	events, err := collectEvents(t, l)
+	var agentErr *AgentError
+	if !errors.As(err, &agentErr) || agentErr.Kind != FailureMissingBinary {
+		t.Fatalf("Expected a missing binary failure, got %v", err)
+	}
+	for _, e := range events {
+		if e.Type == EventRetrying {
+			t.Error("Expected a missing binary not to be retried")
+		}
+	}
+}
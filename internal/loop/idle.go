@@ -0,0 +1,78 @@
+package loop
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minicodemonkey/chief/internal/git"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// DefaultMaxIdleIterations is how many iterations in a row may change nothing
+// before the loop stops.
+const DefaultMaxIdleIterations = 3
+
+// workState is a fingerprint of everything an iteration is expected to
+// change. Two equal states mean the iteration made no progress.
+type workState struct {
+	head     string   // Commit HEAD points to
+	changes  [32]byte // Hash of uncommitted changes outside the PRD directory
+	stories  string   // Passes, inProgress and blocked flags of every story
+	progress [32]byte // Hash of progress.md
+}
+
+// captureWorkState fingerprints the work dir, prd.json and progress.md.
+// Attempt counts are left out, since the loop updates them every iteration.
+func (l *Loop) captureWorkState() workState {
+	var state workState
+	workDir := l.effectiveWorkDir()
+	prdDir := filepath.Dir(l.prdPath)
+
+	if git.IsGitRepo(workDir) {
+		state.head, _ = git.HeadCommit(workDir)
+		var exclude []string
+		if rel, err := filepath.Rel(workDir, prdDir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			exclude = append(exclude, rel)
+		}
+		changes, _ := git.WorkingTreeChanges(workDir, exclude...)
+		state.changes = sha256.Sum256([]byte(changes))
+	}
+
+	if p, err := prd.LoadPRD(l.prdPath); err == nil {
+		var b strings.Builder
+		for _, story := range p.UserStories {
+			fmt.Fprintf(&b, "%s:%t:%t:%t\n", story.ID, story.Passes, story.InProgress, story.Blocked)
+		}
+		state.stories = b.String()
+	}
+
+	progress, _ := os.ReadFile(filepath.Join(prdDir, "progress.md"))
+	state.progress = sha256.Sum256(progress)
+	return state
+}
+
+// checkIdle compares the state after an iteration with the state before it.
+// It returns an event to stop the loop with once too many iterations in a
+// row changed nothing, or nil.
+func (l *Loop) checkIdle(before workState) *Event {
+	after := l.captureWorkState()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if after != before {
+		l.idle = 0
+		return nil
+	}
+	l.idle++
+	if l.maxIdle <= 0 || l.idle < l.maxIdle {
+		return nil
+	}
+	return &Event{
+		Type:      EventNoProgress,
+		Iteration: l.iteration,
+		Text:      fmt.Sprintf("No progress in %d iterations: nothing changed in git, prd.json or progress.md", l.idle),
+	}
+}
@@ -0,0 +1,100 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// initIdleRepo creates a git repository with a committed file and a PRD that
+// isn't ignored, so changes to prd.json and claude.log show up in git.
+func initIdleRepo(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write main.go: %v", err)
+	}
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "initial commit")
+
+	prdDir := filepath.Join(dir, ".chief", "prds", "feature")
+	if err := os.MkdirAll(prdDir, 0755); err != nil {
+		t.Fatalf("Failed to create PRD dir: %v", err)
+	}
+	prdPath := filepath.Join(prdDir, "prd.json")
+	p := &prd.PRD{Project: "Feature", UserStories: []prd.UserStory{{ID: "US-001", Priority: 1}}}
+	if err := p.Save(prdPath); err != nil {
+		t.Fatalf("Failed to save PRD: %v", err)
+	}
+	return dir, prdPath
+}
+
+func TestLoop_RunStopsWithoutProgress(t *testing.T) {
+	dir, prdPath := initIdleRepo(t)
+
+	agent := &fakeAgent{runs: make([]fakeRun, 10)}
+	l := NewLoopWithWorkDir(prdPath, dir, "test prompt", 10)
+	l.SetAgent(agent)
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := len(agent.Requests()); n != DefaultMaxIdleIterations {
+		t.Errorf("Expected %d runs before stopping, got %d", DefaultMaxIdleIterations, n)
+	}
+	if last := events[len(events)-1]; last.Type != EventNoProgress {
+		t.Errorf("Expected the loop to end with NoProgress, got %v", last.Type)
+	}
+}
+
+func TestLoop_RunContinuesWithProgress(t *testing.T) {
+	dir, prdPath := initIdleRepo(t)
+
+	runs := make([]fakeRun, 4)
+	for i := range runs {
+		i := i
+		runs[i].onStart = func() {
+			content := fmt.Sprintf("package main\n\n// Attempt %d\n", i)
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(content), 0644); err != nil {
+				t.Errorf("Failed to write main.go: %v", err)
+			}
+		}
+	}
+	agent := &fakeAgent{runs: runs}
+	l := NewLoopWithWorkDir(prdPath, dir, "test prompt", 4)
+	l.SetAgent(agent)
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := len(agent.Requests()); n != 4 {
+		t.Errorf("Expected all 4 iterations to run, got %d", n)
+	}
+	if last := events[len(events)-1]; last.Type != EventMaxIterationsReached {
+		t.Errorf("Expected the loop to end with MaxIterationsReached, got %v", last.Type)
+	}
+}
+
+func TestLoop_SetMaxIdleIterationsDisables(t *testing.T) {
+	dir, prdPath := initIdleRepo(t)
+
+	agent := &fakeAgent{runs: make([]fakeRun, 5)}
+	l := NewLoopWithWorkDir(prdPath, dir, "test prompt", 5)
+	l.SetAgent(agent)
+	l.SetMaxIdleIterations(0)
+
+	if _, err := collectEvents(t, l); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := len(agent.Requests()); n != 5 {
+		t.Errorf("Expected all 5 iterations to run without a limit, got %d", n)
+	}
+}
@@ -0,0 +1,144 @@
+package loop
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// journalFile is the name of a run's event journal.
+const journalFile = "events.jsonl"
+
+// RunsDir returns the directory holding the recorded runs of the PRD at prdPath.
+func RunsDir(prdPath string) string {
+	return filepath.Join(filepath.Dir(prdPath), "runs")
+}
+
+// RunDir returns the directory of one recorded run.
+func RunDir(prdPath string, run int) string {
+	return filepath.Join(RunsDir(prdPath), strconv.Itoa(run))
+}
+
+// JournalPath returns the path of a run's event journal.
+func JournalPath(prdPath string, run int) string {
+	return filepath.Join(RunDir(prdPath, run), journalFile)
+}
+
+// ListRuns returns the IDs of the recorded runs of a PRD, oldest first.
+func ListRuns(prdPath string) ([]int, error) {
+	entries, err := os.ReadDir(RunsDir(prdPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var runs []int
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if id, err := strconv.Atoi(entry.Name()); err == nil && id > 0 {
+			runs = append(runs, id)
+		}
+	}
+	sort.Ints(runs)
+	return runs, nil
+}
+
+// LatestRun returns the ID of the most recent recorded run, or 0 if the PRD
+// has never run.
+func LatestRun(prdPath string) int {
+	runs, err := ListRuns(prdPath)
+	if err != nil || len(runs) == 0 {
+		return 0
+	}
+	return runs[len(runs)-1]
+}
+
+// Journal appends the events of one run to its events.jsonl, one JSON object
+// per line, so what happened survives after Chief exits. It is safe for
+// concurrent use.
+type Journal struct {
+	mu   sync.Mutex
+	run  int
+	file *os.File
+	enc  *json.Encoder
+}
+
+// CreateJournal starts the journal of a new run of the PRD at prdPath, in
+// runs/<run-id>/ next to prd.json. Run IDs count up from 1.
+func CreateJournal(prdPath string) (*Journal, error) {
+	if err := os.MkdirAll(RunsDir(prdPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create runs directory: %w", err)
+	}
+
+	// Claim the next run ID; another loop may claim it first
+	run := LatestRun(prdPath) + 1
+	for {
+		err := os.Mkdir(RunDir(prdPath, run), 0755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create run directory: %w", err)
+		}
+		run++
+	}
+
+	file, err := os.OpenFile(JournalPath(prdPath, run), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event journal: %w", err)
+	}
+	return &Journal{run: run, file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Run returns the ID of the run the journal records.
+func (j *Journal) Run() int {
+	return j.run
+}
+
+// Write appends an event to the journal.
+func (j *Journal) Write(event Event) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.enc.Encode(event)
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// ReadJournal reads the events recorded in a journal file. Lines that can't
+// be parsed, such as one cut short by a crash, are skipped. Compressed
+// journals are read transparently.
+func ReadJournal(path string) ([]Event, error) {
+	file, err := openLog(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// Tool results can be large
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var events []Event
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,142 @@
+package loop
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateJournal_NumbersRuns(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := filepath.Join(tmpDir, "prd.json")
+
+	if got := LatestRun(prdPath); got != 0 {
+		t.Errorf("Expected no runs yet, got %d", got)
+	}
+
+	for want := 1; want <= 3; want++ {
+		j, err := CreateJournal(prdPath)
+		if err != nil {
+			t.Fatalf("CreateJournal failed: %v", err)
+		}
+		if j.Run() != want {
+			t.Errorf("Expected run %d, got %d", want, j.Run())
+		}
+		j.Close()
+	}
+
+	// Directories that aren't runs are ignored
+	if err := os.Mkdir(filepath.Join(RunsDir(prdPath), "notes"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runs, err := ListRuns(prdPath)
+	if err != nil {
+		t.Fatalf("ListRuns failed: %v", err)
+	}
+	if len(runs) != 3 || runs[0] != 1 || runs[2] != 3 {
+		t.Errorf("Expected runs [1 2 3], got %v", runs)
+	}
+	if got := LatestRun(prdPath); got != 3 {
+		t.Errorf("Expected latest run 3, got %d", got)
+	}
+}
+
+func TestJournal_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := filepath.Join(tmpDir, "prd.json")
+
+	j, err := CreateJournal(prdPath)
+	if err != nil {
+		t.Fatalf("CreateJournal failed: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	written := []Event{
+		{Type: EventStoryStarted, Iteration: 1, StoryID: "US-001", Time: now},
+		{Type: EventToolStart, Iteration: 1, Tool: "Read", ToolInput: map[string]interface{}{"file_path": "main.go"}, ToolUseID: "t1", Time: now},
+		{Type: EventError, Iteration: 1, Err: errors.New("exit status 1"), Time: now},
+	}
+	for _, e := range written {
+		if err := j.Write(e); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	j.Close()
+
+	// A line cut short by a crash is skipped
+	f, err := os.OpenFile(JournalPath(prdPath, 1), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"Complete","iter`)
+	f.Close()
+
+	events, err := ReadJournal(JournalPath(prdPath, 1))
+	if err != nil {
+		t.Fatalf("ReadJournal failed: %v", err)
+	}
+	if len(events) != len(written) {
+		t.Fatalf("Expected %d events, got %d", len(written), len(events))
+	}
+	for i, e := range events {
+		if e.Type != written[i].Type || e.Iteration != written[i].Iteration || !e.Time.Equal(now) {
+			t.Errorf("Event %d: expected %+v, got %+v", i, written[i], e)
+		}
+	}
+	if events[0].StoryID != "US-001" {
+		t.Errorf("Expected story ID US-001, got %q", events[0].StoryID)
+	}
+	if events[1].ToolInput["file_path"] != "main.go" || events[1].ToolUseID != "t1" {
+		t.Errorf("Expected tool input to round-trip, got %+v", events[1])
+	}
+	if events[2].Err == nil || events[2].Err.Error() != "exit status 1" {
+		t.Errorf("Expected error to round-trip, got %v", events[2].Err)
+	}
+}
+
+func TestLoop_RunWritesJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{
+		stdout: []string{
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+		},
+		onStart: markAllPassed(t, prdPath),
+	}}}
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(agent)
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if l.RunID() != 1 {
+		t.Errorf("Expected run 1, got %d", l.RunID())
+	}
+
+	recorded, err := ReadJournal(filepath.Join(tmpDir, "runs", "1", "events.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected a journal for run 1: %v", err)
+	}
+	if len(recorded) != len(events) {
+		t.Fatalf("Expected %d recorded events, got %d", len(events), len(recorded))
+	}
+	for i := range events {
+		if recorded[i].Type != events[i].Type {
+			t.Errorf("Event %d: expected %s, got %s", i, events[i].Type, recorded[i].Type)
+		}
+	}
+}
+
+func TestParseEventType(t *testing.T) {
+	for _, et := range []EventType{EventIterationStart, EventStoryStarted, EventComplete, EventNoProgress} {
+		if got := ParseEventType(et.String()); got != et {
+			t.Errorf("ParseEventType(%q) = %s", et.String(), got)
+		}
+	}
+	if got := ParseEventType("Bogus"); got != EventUnknown {
+		t.Errorf("Expected Unknown, got %s", got)
+	}
+}
@@ -7,48 +7,85 @@ package loop
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/minicodemonkey/chief/embed"
+	"github.com/minicodemonkey/chief/internal/config"
 	"github.com/minicodemonkey/chief/internal/prd"
 )
 
 // RetryConfig configures automatic retry behavior on Claude crashes.
+// Retries back off exponentially with jitter; rate limits that report a reset
+// time wait until then instead.
 type RetryConfig struct {
-	MaxRetries  int           // Maximum number of retry attempts (default: 3)
-	RetryDelays []time.Duration // Delays between retries (default: 0s, 5s, 15s)
-	Enabled     bool          // Whether retry is enabled (default: true)
+	MaxRetries    int           // Maximum number of retry attempts (default: 3)
+	BaseDelay     time.Duration // Delay before the first retry, doubled for each one after (default: 5s)
+	MaxDelay      time.Duration // Longest backoff delay (default: 2m)
+	Enabled       bool          // Whether retry is enabled (default: true)
+	ResumeSession bool          // Continue the crashed agent session instead of starting over (default: false)
 }
 
 // DefaultRetryConfig returns the default retry configuration.
 func DefaultRetryConfig() RetryConfig {
 	return RetryConfig{
-		MaxRetries:  3,
-		RetryDelays: []time.Duration{0, 5 * time.Second, 15 * time.Second},
-		Enabled:     true,
+		MaxRetries: 3,
+		BaseDelay:  5 * time.Second,
+		MaxDelay:   2 * time.Minute,
+		Enabled:    true,
 	}
 }
 
 // Loop manages the core agent loop that invokes Claude repeatedly until all stories are complete.
 type Loop struct {
-	prdPath     string
-	workDir     string
-	prompt      string
-	maxIter     int
-	iteration   int
-	events      chan Event
-	claudeCmd   *exec.Cmd
-	logFile     *os.File
-	mu          sync.Mutex
-	stopped     bool
-	paused      bool
-	retryConfig RetryConfig
+	prdPath        string
+	workDir        string
+	prompt         string
+	promptTemplate *PromptTemplate // Project prompt override, rendered each iteration
+	lastFailure    string          // Error from the previous attempt, for the prompt template
+	maxIter        int
+	iteration      int
+	events         chan Event
+	agent          Agent
+	process        AgentProcess
+	logFile        *os.File
+	mu             sync.Mutex
+	stopped        bool
+	paused         bool
+	retryConfig    RetryConfig
+	timeouts       TimeoutConfig       // Stall detection for each iteration
+	lastOutput     time.Time           // When the agent last wrote to stdout or stderr
+	budget         Budget              // Limits for the whole run
+	storyBudget    Budget              // Limits for each story
+	spent          budgetTracker       // Usage and time counted against the budgets
+	story          string              // Story most recently reported by the agent
+	sessionID      string              // Agent session of the current iteration, if reported
+	verify         config.VerifyConfig // Commands that check newly passed stories
+	verifyFeedback map[string]string   // Verification failures to report in the next prompt, by story ID
+	parallel       int                 // Independent stories to work on at once (< 2 = one at a time)
+	worktreeSetup  string              // Setup command for new story worktrees
+	workers        []*storyWorker      // Story workers of the running parallel round
+	model          string              // Default agent model (PRDs and stories can override it)
+	agentArgs      []string            // Extra agent arguments for every iteration
+	permissions    Permissions         // Permission profile the agent runs under
+	wrapper        []string            // Command template the agent is launched through
+	maxAttempts    int                 // Iterations a story may take before it's blocked (0 = no limit)
+	lastText       string              // Last message from the agent in the current iteration
+	maxIdle        int                 // Iterations in a row that may change nothing before the loop stops (0 = no limit)
+	idle           int                 // Iterations in a row that changed nothing
+	journal        *Journal            // Event journal of the current run
+	runID          int                 // ID of the current run (story workers share their parent's)
+	runStarted     time.Time           // When the current run started
+	runStories     []string            // Stories worked on in the current run
+	logPath        string              // Where to log agent output (empty = the run's claude.log)
+	retention      LogRetention        // How much run history to keep
+	skipJournal    bool                // Don't record a journal (story workers report through their parent)
 }
 
 // NewLoop creates a new Loop instance.
@@ -58,7 +95,11 @@ func NewLoop(prdPath, prompt string, maxIter int) *Loop {
 		prompt:      prompt,
 		maxIter:     maxIter,
 		events:      make(chan Event, 100),
+		agent:       &ClaudeAgent{},
 		retryConfig: DefaultRetryConfig(),
+		timeouts:    DefaultTimeoutConfig(),
+		permissions: DefaultPermissions(),
+		maxIdle:     DefaultMaxIdleIterations,
 	}
 }
 
@@ -71,7 +112,11 @@ func NewLoopWithWorkDir(prdPath, workDir string, prompt string, maxIter int) *Lo
 		prompt:      prompt,
 		maxIter:     maxIter,
 		events:      make(chan Event, 100),
+		agent:       &ClaudeAgent{},
 		retryConfig: DefaultRetryConfig(),
+		timeouts:    DefaultTimeoutConfig(),
+		permissions: DefaultPermissions(),
+		maxIdle:     DefaultMaxIdleIterations,
 	}
 }
 
@@ -96,16 +141,69 @@ func (l *Loop) Iteration() int {
 
 // Run executes the agent loop until completion or max iterations.
 func (l *Loop) Run(ctx context.Context) error {
-	// Open log file in PRD directory
-	prdDir := filepath.Dir(l.prdPath)
-	logPath := filepath.Join(prdDir, "claude.log")
+	// Readers wait for the channel to close, so close it however the run ends
+	defer close(l.events)
+
+	// Record every event in the run's journal
+	if !l.skipJournal {
+		journal, err := CreateJournal(l.prdPath)
+		if err != nil {
+			l.emit(Event{
+				Type: EventError,
+				Err:  err,
+			})
+			return err
+		}
+		l.mu.Lock()
+		l.journal = journal
+		l.runID = journal.Run()
+		l.runStarted = time.Now()
+		l.runStories = nil
+		l.mu.Unlock()
+		defer journal.Close()
+		defer l.finishRun()
+	}
+
+	// Open the log file for the agent's output
+	logPath := l.logFilePath()
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		err = fmt.Errorf("failed to create log directory: %w", err)
+		l.emit(Event{
+			Type: EventError,
+			Err:  err,
+		})
+		return err
+	}
 	var err error
 	l.logFile, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
+		err = fmt.Errorf("failed to open log file: %w", err)
+		l.emit(Event{
+			Type: EventError,
+			Err:  err,
+		})
+		return err
 	}
 	defer l.logFile.Close()
-	defer close(l.events)
+
+	if !l.skipJournal {
+		l.startRun()
+		l.seedBudget()
+	}
+
+	// Mark where each run starts in claude.log, for chief replay
+	if id := l.RunID(); id > 0 {
+		l.logLine(fmt.Sprintf("[run] %d", id))
+	}
+
+	// Don't start an iteration when earlier runs already spent the PRD budget
+	l.mu.Lock()
+	exceeded := l.spent.check(l.budget, Budget{}, "")
+	l.mu.Unlock()
+	if exceeded != nil {
+		l.emit(*exceeded)
+		return nil
+	}
 
 	for {
 		l.mu.Lock()
@@ -117,31 +215,76 @@ func (l *Loop) Run(ctx context.Context) error {
 			l.mu.Unlock()
 			return nil
 		}
+		l.mu.Unlock()
+
+		// Stop when blocked stories leave nothing to work on
+		if p, err := prd.LoadPRD(l.prdPath); err == nil && allBlocked(p) {
+			l.emit(Event{
+				Type:      EventAllStoriesBlocked,
+				Iteration: l.Iteration(),
+				Text:      "All remaining stories are blocked",
+			})
+			return nil
+		}
+
+		l.mu.Lock()
 		l.iteration++
 		currentIter := l.iteration
+		l.lastText = ""
 		l.mu.Unlock()
 
 		// Check if max iterations reached
 		if currentIter > l.maxIter {
-			l.events <- Event{
+			l.emit(Event{
 				Type:      EventMaxIterationsReached,
 				Iteration: currentIter - 1,
-			}
+			})
 			return nil
 		}
 
 		// Send iteration start event
-		l.events <- Event{
+		model, _ := l.agentOptions()
+		l.emit(Event{
 			Type:      EventIterationStart,
 			Iteration: currentIter,
+			Model:     model,
+		})
+
+		// Remember which stories already pass, so newly passed ones can be
+		// verified, and what the work looks like, to notice iterations that
+		// change nothing
+		passedBefore := l.passingStories()
+		stateBefore := l.captureWorkState()
+
+		// Run a single iteration with retry logic, or a round of independent
+		// stories side by side when parallelism is enabled
+		iterStart := time.Now()
+		var err error
+		var worked []string
+		if p, stories := l.parallelStories(); len(stories) > 1 {
+			for _, story := range stories {
+				worked = append(worked, story.ID)
+			}
+			l.logIterationStart(currentIter, worked)
+			err = l.runParallel(ctx, p, stories)
+			// Merged stories were already verified in their worktrees
+			if passedBefore != nil {
+				passedBefore = l.passingStories()
+			}
+		} else {
+			worked = l.nextStoryIDs()
+			l.logIterationStart(currentIter, worked)
+			err = l.runIterationWithRetry(ctx)
 		}
-
-		// Run a single iteration with retry logic
-		if err := l.runIterationWithRetry(ctx); err != nil {
-			l.events <- Event{
+		l.recordRunStories(worked)
+		l.mu.Lock()
+		l.spent.addElapsed(l.story, time.Since(iterStart))
+		l.mu.Unlock()
+		if err != nil {
+			l.emit(Event{
 				Type: EventError,
 				Err:  err,
-			}
+			})
 			return err
 		}
 
@@ -152,21 +295,58 @@ func (l *Loop) Run(ctx context.Context) error {
 		default:
 		}
 
+		// Don't take the agent's word for it: verify newly passed stories
+		if err := l.verifyNewlyPassed(ctx, passedBefore); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			l.emit(Event{
+				Type: EventError,
+				Err:  err,
+			})
+			return err
+		}
+
+		// Count the iteration against the stories it worked on
+		if err := l.recordAttempts(worked); err != nil {
+			l.emit(Event{
+				Type: EventError,
+				Err:  err,
+			})
+			return err
+		}
+
 		// Check prd.json for completion
 		p, err := prd.LoadPRD(l.prdPath)
 		if err != nil {
-			l.events <- Event{
+			l.emit(Event{
 				Type: EventError,
 				Err:  fmt.Errorf("failed to load PRD: %w", err),
-			}
+			})
 			return err
 		}
 
 		if p.AllComplete() {
-			l.events <- Event{
+			l.emit(Event{
 				Type:      EventComplete,
 				Iteration: currentIter,
-			}
+			})
+			return nil
+		}
+
+		// Stop instead of spinning through the remaining iterations
+		if idle := l.checkIdle(stateBefore); idle != nil {
+			l.emit(*idle)
+			return nil
+		}
+
+		// Stop if the run or the current story is over budget
+		l.mu.Lock()
+		exceeded := l.spent.check(l.budget, l.storyBudget, l.story)
+		l.mu.Unlock()
+		if exceeded != nil {
+			exceeded.Iteration = currentIter
+			l.emit(*exceeded)
 			return nil
 		}
 
@@ -180,6 +360,26 @@ func (l *Loop) Run(ctx context.Context) error {
 	}
 }
 
+// emit timestamps an event, records it in the journal and sends it to the
+// events channel.
+func (l *Loop) emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if l.journal != nil {
+		_ = l.journal.Write(event)
+	}
+	l.events <- event
+}
+
+// RunID returns the ID of the run recorded in the journal, or 0 before the
+// loop has started.
+func (l *Loop) RunID() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.runID
+}
+
 // runIterationWithRetry wraps runIteration with retry logic for crash recovery.
 func (l *Loop) runIterationWithRetry(ctx context.Context) error {
 	l.mu.Lock()
@@ -187,6 +387,7 @@ func (l *Loop) runIterationWithRetry(ctx context.Context) error {
 	l.mu.Unlock()
 
 	var lastErr error
+	var resume string // Session to continue on the next attempt
 	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
 		// Check if retry is enabled (except for first attempt)
 		if attempt > 0 {
@@ -194,24 +395,31 @@ func (l *Loop) runIterationWithRetry(ctx context.Context) error {
 				return lastErr
 			}
 
-			// Get delay for this retry
-			delayIdx := attempt - 1
-			if delayIdx >= len(config.RetryDelays) {
-				delayIdx = len(config.RetryDelays) - 1
-			}
-			delay := config.RetryDelays[delayIdx]
+			// Wait for a rate limit to reset, otherwise back off
+			delay := retryDelay(lastErr, attempt, config, time.Now())
 
-			// Emit retry event
+			// Emit retry event, saying why the attempt failed. A session that
+			// didn't stall can be resumed so the work so far isn't thrown away.
 			l.mu.Lock()
 			iter := l.iteration
+			session := l.sessionID
+			agentName := l.agent.Name()
 			l.mu.Unlock()
-			l.events <- Event{
+			resume = ""
+			var stallErr *StallError
+			if !errors.As(lastErr, &stallErr) && config.ResumeSession && session != "" {
+				resume = session
+			}
+			reason, text := retryText(agentName, lastErr, delay, resume, attempt, config.MaxRetries)
+			l.emit(Event{
 				Type:       EventRetrying,
 				Iteration:  iter,
 				RetryCount: attempt,
 				RetryMax:   config.MaxRetries,
-				Text:       fmt.Sprintf("Claude crashed, retrying (%d/%d)...", attempt, config.MaxRetries),
-			}
+				Text:       text,
+				Reason:     reason,
+				SessionID:  resume,
+			})
 
 			// Wait before retry
 			if delay > 0 {
@@ -232,7 +440,13 @@ func (l *Loop) runIterationWithRetry(ctx context.Context) error {
 		l.mu.Unlock()
 
 		// Run the iteration
-		err := l.runIteration(ctx)
+		err := l.runIteration(ctx, resume)
+		l.mu.Lock()
+		l.lastFailure = ""
+		if err != nil {
+			l.lastFailure = err.Error()
+		}
+		l.mu.Unlock()
 		if err == nil {
 			return nil // Success
 		}
@@ -250,62 +464,118 @@ func (l *Loop) runIterationWithRetry(ctx context.Context) error {
 			return nil
 		}
 
+		// A broken wrapper, missing binary, failed login or prompt template fails
+		// the same way every time
+		var wrapperErr *WrapperError
+		if errors.As(err, &wrapperErr) {
+			return err
+		}
+		var agentErr *AgentError
+		if errors.As(err, &agentErr) && agentErr.Fatal() {
+			return err
+		}
+		var templateErr *TemplateError
+		if errors.As(err, &templateErr) {
+			return err
+		}
+
 		lastErr = err
 	}
 
 	return fmt.Errorf("max retries (%d) exceeded: %w", config.MaxRetries, lastErr)
 }
 
-// runIteration spawns Claude and processes its output.
-func (l *Loop) runIteration(ctx context.Context) error {
-	// Build Claude command with required flags
+// runIteration spawns the agent and processes its output. When resumeSession
+// is set, the agent continues that session instead of starting a new one.
+func (l *Loop) runIteration(ctx context.Context, resumeSession string) error {
+	base, err := l.basePrompt()
+	if err != nil {
+		return err
+	}
+	prompt := l.verifyPrompt(base)
+	model, args := l.agentOptions()
+
 	l.mu.Lock()
-	l.claudeCmd = exec.CommandContext(ctx, "claude",
-		"--dangerously-skip-permissions",
-		"-p", l.prompt,
-		"--output-format", "stream-json",
-		"--verbose",
-	)
-	// Set working directory: use workDir if configured, otherwise default to PRD directory
-	l.claudeCmd.Dir = l.effectiveWorkDir()
+	agent := l.agent
+	req := AgentRequest{
+		Prompt: prompt,
+		// Use workDir if configured, otherwise default to PRD directory
+		WorkDir:       l.effectiveWorkDir(),
+		ResumeSession: resumeSession,
+		PRDDir:        filepath.Dir(l.prdPath),
+		Model:         model,
+		ExtraArgs:     args,
+		Permissions:   l.permissions,
+		Wrapper:       l.wrapper,
+	}
+	// Keep the resumed session if the agent crashes before reporting one
+	l.sessionID = resumeSession
 	l.mu.Unlock()
 
-	// Create pipes for stdout and stderr
-	stdout, err := l.claudeCmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	if resumeSession != "" {
+		l.logLine("[session] resuming " + resumeSession)
 	}
 
-	stderr, err := l.claudeCmd.StderrPipe()
+	proc, err := agent.Start(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		var wrapperErr *WrapperError
+		if errors.As(err, &wrapperErr) {
+			return err
+		}
+		if agentErr := classifyFailure(agent.Name(), err, nil, time.Now()); agentErr.Kind == FailureMissingBinary {
+			return agentErr
+		}
+		return fmt.Errorf("failed to start %s: %w", agent.Name(), err)
 	}
 
-	// Start the command
-	if err := l.claudeCmd.Start(); err != nil {
-		return fmt.Errorf("failed to start Claude: %w", err)
-	}
+	l.mu.Lock()
+	l.process = proc
+	l.lastOutput = time.Now()
+	timeouts := l.timeouts
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.process = nil
+		l.mu.Unlock()
+	}()
+
+	// Kill the agent if it goes quiet or runs too long
+	watchDone := make(chan struct{})
+	defer close(watchDone)
+	stalled := make(chan *StallError, 1)
+	go l.watchIteration(proc, agent.Name(), timeouts, watchDone, stalled)
 
 	// Process stdout in a separate goroutine
 	var wg sync.WaitGroup
+	var stdoutLines int
+	var errorResults, stderrTail []string
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		l.processOutput(stdout)
+		stdoutLines, errorResults = l.processOutput(proc.Stdout())
 	}()
 
 	// Log stderr to the log file
 	go func() {
 		defer wg.Done()
-		l.logStream(stderr, "[stderr] ")
+		stderrTail = l.logStream(proc.Stderr(), "[stderr] ")
 	}()
 
 	// Wait for output processing to complete
 	wg.Wait()
 
-	// Wait for the command to finish
-	if err := l.claudeCmd.Wait(); err != nil {
+	// Wait for the agent to finish
+	err = proc.Wait()
+
+	// A stall kill takes precedence over the exit status it caused
+	select {
+	case stallErr := <-stalled:
+		return stallErr
+	default:
+	}
+
+	if err != nil {
 		// If the context was cancelled, don't treat it as an error
 		if ctx.Err() != nil {
 			return ctx.Err()
@@ -317,45 +587,120 @@ func (l *Loop) runIteration(ctx context.Context) error {
 		if stopped {
 			return nil
 		}
-		return fmt.Errorf("Claude exited with error: %w", err)
+		// Without any output the agent most likely never ran
+		if len(req.Wrapper) > 0 && stdoutLines == 0 {
+			var lastStderr string
+			if len(stderrTail) > 0 {
+				lastStderr = stderrTail[len(stderrTail)-1]
+			}
+			return &WrapperError{Wrapper: req.Wrapper[0], Err: err, Stderr: lastStderr}
+		}
+		return classifyFailure(agent.Name(), err, append(stderrTail, errorResults...), time.Now())
 	}
 
+	return nil
+}
+
+// agentOptions returns the model and extra arguments for the next story. The
+// story's settings override the PRD's, which override the configured defaults.
+func (l *Loop) agentOptions() (string, []string) {
 	l.mu.Lock()
-	l.claudeCmd = nil
+	model := l.model
+	args := append([]string(nil), l.agentArgs...)
 	l.mu.Unlock()
 
-	return nil
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		return model, args
+	}
+	story := p.NextStory()
+	if m := p.ModelFor(story); m != "" {
+		model = m
+	}
+	return model, append(args, p.AgentArgsFor(story)...)
 }
 
-// processOutput reads stdout line by line, logs it, and parses events.
-func (l *Loop) processOutput(r io.Reader) {
+// processOutput reads stdout line by line, logs it, and parses events. It
+// returns the number of lines read and the text of any error results.
+func (l *Loop) processOutput(r io.Reader) (int, []string) {
 	scanner := bufio.NewScanner(r)
 	// Increase buffer size for long lines (Claude can output large JSON)
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
 
+	l.mu.Lock()
+	agent := l.agent
+	l.mu.Unlock()
+
+	lines := 0
+	var errorResults []string
 	for scanner.Scan() {
 		line := scanner.Text()
+		lines++
+		l.touch()
 
 		// Log raw output
 		l.logLine(line)
 
-		// Parse the line and emit event if valid
-		if event := ParseLine(line); event != nil {
+		// Parse the line and emit each of its events
+		for _, event := range agent.ParseLine(line) {
+			// The agent claiming completion doesn't complete the run: the loop
+			// reports it once prd.json says so and the stories are verified
+			if event.Type == EventComplete {
+				continue
+			}
 			l.mu.Lock()
 			event.Iteration = l.iteration
+			switch {
+			case event.Type == EventStoryStarted && event.StoryID != "":
+				l.story = event.StoryID
+			case event.Type == EventIterationResult && event.Usage != nil:
+				l.spent.addUsage(l.story, *event.Usage)
+			case event.Type == EventIterationStart && event.SessionID != "":
+				l.sessionID = event.SessionID
+			case event.Type == EventAssistantText && strings.TrimSpace(event.Text) != "":
+				l.lastText = event.Text
+			}
 			l.mu.Unlock()
-			l.events <- *event
+
+			// Record the session so a failed one can be opened by hand
+			if event.Type == EventIterationStart && event.SessionID != "" {
+				l.logLine("[session] " + event.SessionID)
+			}
+			if event.Type == EventIterationResult && event.Err != nil {
+				errorResults = append(errorResults, event.Err.Error())
+			}
+			l.emit(event)
 		}
 	}
+	return lines, errorResults
 }
 
-// logStream logs a stream with a prefix.
-func (l *Loop) logStream(r io.Reader, prefix string) {
+// stderrTailLines is how many stderr lines are kept to classify failures.
+const stderrTailLines = 10
+
+// logStream logs a stream with a prefix. It returns the last few non-empty
+// lines.
+func (l *Loop) logStream(r io.Reader, prefix string) []string {
 	scanner := bufio.NewScanner(r)
+	var tail []string
 	for scanner.Scan() {
+		l.touch()
 		l.logLine(prefix + scanner.Text())
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			if len(tail) == stderrTailLines {
+				tail = tail[1:]
+			}
+			tail = append(tail, line)
+		}
 	}
+	return tail
+}
+
+// logIterationStart marks the start of an iteration in the log file, with
+// the stories it works on.
+func (l *Loop) logIterationStart(iter int, stories []string) {
+	l.logLine(strings.TrimSpace(fmt.Sprintf("[iteration] %d %s", iter, strings.Join(stories, " "))))
 }
 
 // logLine writes a line to the log file.
@@ -365,16 +710,19 @@ func (l *Loop) logLine(line string) {
 	}
 }
 
-// Stop terminates the current Claude process and stops the loop.
+// Stop terminates the current agent process and stops the loop.
 func (l *Loop) Stop() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	l.stopped = true
 
-	if l.claudeCmd != nil && l.claudeCmd.Process != nil {
+	if l.process != nil {
 		// Kill the process
-		l.claudeCmd.Process.Kill()
+		l.process.Kill()
+	}
+	for _, w := range l.workers {
+		w.loop.Stop()
 	}
 }
 
@@ -383,6 +731,9 @@ func (l *Loop) Pause() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.paused = true
+	for _, w := range l.workers {
+		w.loop.Pause()
+	}
 }
 
 // Resume clears the pause flag.
@@ -415,11 +766,18 @@ func (l *Loop) effectiveWorkDir() string {
 	return filepath.Dir(l.prdPath)
 }
 
-// IsRunning returns whether a Claude process is currently running.
+// IsRunning returns whether an agent process is currently running.
 func (l *Loop) IsRunning() bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.claudeCmd != nil && l.claudeCmd.Process != nil
+	return l.process != nil
+}
+
+// SetAgent sets the agent used for subsequent iterations.
+func (l *Loop) SetAgent(agent Agent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.agent = agent
 }
 
 // SetMaxIterations updates the maximum iterations limit.
@@ -436,6 +794,94 @@ func (l *Loop) MaxIterations() int {
 	return l.maxIter
 }
 
+// SetMaxStoryAttempts sets how many iterations a story may take before it's
+// blocked and the loop moves on. Zero means no limit.
+func (l *Loop) SetMaxStoryAttempts(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxAttempts = n
+}
+
+// SetMaxIdleIterations sets how many iterations in a row may change nothing
+// (no commits, working-tree changes, story states or progress notes) before
+// the loop stops. Zero or less means no limit.
+func (l *Loop) SetMaxIdleIterations(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxIdle = n
+}
+
+// SetBudget sets the limits for the whole run and for each story.
+func (l *Loop) SetBudget(prdBudget, storyBudget Budget) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.budget = prdBudget
+	l.storyBudget = storyBudget
+}
+
+// SetVerifyConfig sets the commands that verify stories the agent marks as passing.
+func (l *Loop) SetVerifyConfig(config config.VerifyConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.verify = config
+}
+
+// SetPromptTemplate sets a project prompt template that replaces the built-in
+// prompt. It is rendered at the start of every iteration.
+func (l *Loop) SetPromptTemplate(tmpl *PromptTemplate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.promptTemplate = tmpl
+}
+
+// SetParallelism sets how many independent stories may be worked on at once.
+// Each story runs in its own worktree, prepared with the setup command.
+func (l *Loop) SetParallelism(maxStories int, setup string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.parallel = maxStories
+	l.worktreeSetup = setup
+}
+
+// SetAgentOptions sets the default model and extra arguments passed to the
+// agent. PRDs and stories can override the model and add arguments.
+func (l *Loop) SetAgentOptions(model string, args []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.model = model
+	l.agentArgs = args
+}
+
+// SetPermissions sets the permission profile the agent runs under.
+func (l *Loop) SetPermissions(p Permissions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.permissions = p
+}
+
+// SetWrapper sets the command template the agent is launched through, for
+// example a sandbox. The agent's command line is appended to it.
+func (l *Loop) SetWrapper(wrapper []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.wrapper = wrapper
+}
+
+// SetTimeoutConfig updates the stall detection timeouts.
+func (l *Loop) SetTimeoutConfig(config TimeoutConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timeouts = config
+}
+
+// SetLogRetention sets how much of the PRD's run history to keep. It's
+// applied to earlier runs whenever a run starts.
+func (l *Loop) SetLogRetention(r LogRetention) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.retention = r
+}
+
 // SetRetryConfig updates the retry configuration.
 func (l *Loop) SetRetryConfig(config RetryConfig) {
 	l.mu.Lock()
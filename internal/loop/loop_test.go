@@ -304,8 +304,9 @@ func TestLoop_LogFile(t *testing.T) {
 	}
 }
 
-// TestLoop_ChiefCompleteEvent tests detection of <chief-complete/> event.
-func TestLoop_ChiefCompleteEvent(t *testing.T) {
+// TestLoop_ChiefCompleteEventIgnored tests that <chief-complete/> from the
+// agent doesn't complete the run before the loop has checked prd.json.
+func TestLoop_ChiefCompleteEventIgnored(t *testing.T) {
 	l := NewLoop("/test/prd.json", "test", 5)
 	l.iteration = 1
 
@@ -314,9 +315,6 @@ func TestLoop_ChiefCompleteEvent(t *testing.T) {
 	go func() {
 		for event := range l.Events() {
 			events = append(events, event)
-			if event.Type == EventComplete {
-				break
-			}
 		}
 		done <- true
 	}()
@@ -332,17 +330,11 @@ func TestLoop_ChiefCompleteEvent(t *testing.T) {
 	close(l.events)
 	<-done
 
-	// Check that we got a Complete event
-	hasComplete := false
 	for _, e := range events {
 		if e.Type == EventComplete {
-			hasComplete = true
+			t.Error("Expected the agent's <chief-complete/> not to emit a Complete event")
 		}
 	}
-
-	if !hasComplete {
-		t.Error("Expected Complete event for <chief-complete/>")
-	}
 }
 
 // TestLoop_SetMaxIterations tests setting max iterations at runtime.
@@ -370,8 +362,11 @@ func TestDefaultRetryConfig(t *testing.T) {
 	if !config.Enabled {
 		t.Error("Expected Enabled to be true")
 	}
-	if len(config.RetryDelays) != 3 {
-		t.Errorf("Expected 3 retry delays, got %d", len(config.RetryDelays))
+	if config.BaseDelay != 5*time.Second {
+		t.Errorf("Expected BaseDelay 5s, got %v", config.BaseDelay)
+	}
+	if config.MaxDelay != 2*time.Minute {
+		t.Errorf("Expected MaxDelay 2m, got %v", config.MaxDelay)
 	}
 }
 
@@ -392,9 +387,9 @@ func TestLoop_SetRetryConfig(t *testing.T) {
 
 	// Set custom config
 	customConfig := RetryConfig{
-		MaxRetries:  5,
-		RetryDelays: []time.Duration{time.Second},
-		Enabled:     true,
+		MaxRetries: 5,
+		BaseDelay:  time.Second,
+		Enabled:    true,
 	}
 	l.SetRetryConfig(customConfig)
 
@@ -3,6 +3,8 @@ package loop
 import (
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -21,6 +23,7 @@ const (
 	LoopStateStopped
 	LoopStateComplete
 	LoopStateError
+	LoopStateQueued
 )
 
 func (s LoopState) String() string {
@@ -37,25 +40,97 @@ func (s LoopState) String() string {
 		return "Complete"
 	case LoopStateError:
 		return "Error"
+	case LoopStateQueued:
+		return "Queued"
 	default:
 		return "Unknown"
 	}
 }
 
+// ParseLoopState returns the LoopState with the given name, as returned by
+// String, or LoopStateReady.
+func ParseLoopState(name string) LoopState {
+	for s := LoopStateReady; s.String() != "Unknown"; s++ {
+		if s.String() == name {
+			return s
+		}
+	}
+	return LoopStateReady
+}
+
 // LoopInstance represents a single loop with its metadata.
 type LoopInstance struct {
-	Name        string
-	PRDPath     string
-	WorktreeDir string // Working directory for this PRD (empty = project root)
-	Branch      string // Git branch for this PRD (empty = current branch)
-	Loop        *Loop
-	State       LoopState
-	Iteration   int
-	StartTime   time.Time
-	Error       error
-	ctx         context.Context
-	cancel      context.CancelFunc
-	mu          sync.Mutex
+	Name           string
+	PRDPath        string
+	WorktreeDir    string // Working directory for this PRD (empty = project root)
+	Branch         string // Git branch for this PRD (empty = current branch)
+	Loop           *Loop
+	State          LoopState
+	Iteration      int
+	StartTime      time.Time
+	Error          error
+	Usage          Usage            // Cost and tokens for the current run
+	StoryUsage     map[string]Usage // Cost and tokens per story for the current run
+	IterationUsage map[int]Usage    // Cost and tokens per iteration for the current run
+	Profile        string           // Permission profile the current run uses
+	QueuePosition  int              // Place in the run queue while queued (1 = next to start)
+	currentStory   string           // Story most recently reported by the agent
+	priority       int              // PRD priority for the run queue
+	ctx            context.Context
+	cancel         context.CancelFunc
+	mu             sync.Mutex
+}
+
+// recordUsage accumulates iteration usage into the instance totals.
+// The caller must hold instance.mu.
+func (i *LoopInstance) recordUsage(iteration int, storyID string, u Usage) {
+	i.Usage.Add(u)
+
+	if i.IterationUsage == nil {
+		i.IterationUsage = make(map[int]Usage)
+	}
+	iu := i.IterationUsage[iteration]
+	iu.Add(u)
+	i.IterationUsage[iteration] = iu
+
+	if storyID != "" {
+		if i.StoryUsage == nil {
+			i.StoryUsage = make(map[string]Usage)
+		}
+		su := i.StoryUsage[storyID]
+		su.Add(u)
+		i.StoryUsage[storyID] = su
+	}
+}
+
+// snapshot returns a copy of the instance's public fields.
+// The caller must hold instance.mu.
+func (i *LoopInstance) snapshot() *LoopInstance {
+	c := &LoopInstance{
+		Name:        i.Name,
+		PRDPath:     i.PRDPath,
+		WorktreeDir: i.WorktreeDir,
+		Branch:      i.Branch,
+		State:       i.State,
+		Iteration:   i.Iteration,
+		StartTime:   i.StartTime,
+		Error:       i.Error,
+		Usage:       i.Usage,
+		Profile:     i.Profile,
+	}
+	if i.StoryUsage != nil {
+		c.StoryUsage = make(map[string]Usage, len(i.StoryUsage))
+		for k, v := range i.StoryUsage {
+			c.StoryUsage[k] = v
+		}
+	}
+	if i.IterationUsage != nil {
+		c.IterationUsage = make(map[int]Usage, len(i.IterationUsage))
+		for k, v := range i.IterationUsage {
+			c.IterationUsage[k] = v
+		}
+	}
+	return c
 }
 
 // ManagerEvent represents an event from any managed loop.
@@ -67,25 +142,33 @@ type ManagerEvent struct {
 
 // Manager manages multiple Loop instances for parallel PRD execution.
 type Manager struct {
-	instances   map[string]*LoopInstance
-	events      chan ManagerEvent
-	maxIter     int
-	retryConfig RetryConfig
-	baseDir        string                               // Project root directory (for CLAUDE.md etc.)
-	config         *config.Config                       // Project config for post-completion actions
+	instances      map[string]*LoopInstance
+	events         chan ManagerEvent
+	maxIter        int
+	retryConfig    RetryConfig
+	timeouts       TimeoutConfig
+	baseDir        string          // Project root directory (for CLAUDE.md etc.)
+	config         *config.Config  // Project config for post-completion actions
+	agent          Agent           // Agent override (nil = build from config)
+	prdBudget      Budget          // Per-run budget overrides (e.g. from CLI flags)
+	storyBudget    Budget          // Per-story budget overrides (e.g. from CLI flags)
+	queue          []*LoopInstance // Queued PRDs, in the order they were started
+	stopping       bool            // StopAll is in progress; don't start chained PRDs
 	mu             sync.RWMutex
 	wg             sync.WaitGroup
 	onComplete     func(prdName string)                  // Callback when a PRD completes
 	onPostComplete func(prdName, branch, workDir string) // Callback for post-completion actions (push, PR)
 }
 
-// NewManager creates a new loop manager.
+// NewManager creates a new loop manager. A maxIter of 0 or less gives each
+// PRD its number of remaining stories + 5.
 func NewManager(maxIter int) *Manager {
 	return &Manager{
 		instances:   make(map[string]*LoopInstance),
 		events:      make(chan ManagerEvent, 100),
 		maxIter:     maxIter,
 		retryConfig: DefaultRetryConfig(),
+		timeouts:    DefaultTimeoutConfig(),
 	}
 }
 
@@ -96,6 +179,64 @@ func (m *Manager) SetRetryConfig(config RetryConfig) {
 	m.retryConfig = config
 }
 
+// SetTimeoutConfig sets the stall detection timeouts for new loops.
+// Timeouts in the project config take precedence.
+func (m *Manager) SetTimeoutConfig(config TimeoutConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeouts = config
+}
+
+// timeoutConfig returns the effective stall detection timeouts for a new loop.
+func (m *Manager) timeoutConfig() (TimeoutConfig, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.config == nil {
+		return m.timeouts, nil
+	}
+	return TimeoutConfigFromConfig(m.timeouts, m.config.Timeouts)
+}
+
+// permissions returns the permission profile for a new loop.
+func (m *Manager) permissions() (Permissions, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.config == nil {
+		return DefaultPermissions(), nil
+	}
+	return NewPermissions(m.config.Permissions)
+}
+
+// logRetention returns the log retention settings from the project config.
+func (m *Manager) logRetention() (LogRetention, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.config == nil {
+		return LogRetention{}, nil
+	}
+	return LogRetentionFromConfig(m.config.Logs)
+}
+
+// loopLimit returns the maximum number of concurrent loops and the queue
+// order from the project config.
+func loopLimit(cfg *config.Config) (int, string, error) {
+	if cfg == nil {
+		return 0, config.QueueOrderFIFO, nil
+	}
+	c := cfg.Loops
+	if c.MaxConcurrent < 0 {
+		return 0, "", fmt.Errorf("invalid max concurrent loops %d", c.MaxConcurrent)
+	}
+	switch c.QueueOrder {
+	case "", config.QueueOrderFIFO:
+		return c.MaxConcurrent, config.QueueOrderFIFO, nil
+	case config.QueueOrderPriority:
+		return c.MaxConcurrent, config.QueueOrderPriority, nil
+	default:
+		return 0, "", fmt.Errorf("unknown queue order %q (use %q or %q)", c.QueueOrder, config.QueueOrderFIFO, config.QueueOrderPriority)
+	}
+}
+
 // DisableRetry disables automatic retry for new loops.
 func (m *Manager) DisableRetry() {
 	m.mu.Lock()
@@ -132,6 +273,54 @@ func (m *Manager) SetConfig(cfg *config.Config) {
 	m.config = cfg
 }
 
+// SetAgent overrides the agent used for new loops. When unset, the agent is
+// built from the project config's agent section.
+func (m *Manager) SetAgent(agent Agent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agent = agent
+}
+
+// newAgent returns the agent for a new loop.
+func (m *Manager) newAgent() (Agent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.agent != nil {
+		return m.agent, nil
+	}
+	if m.config != nil {
+		return NewAgent(m.config.Agent)
+	}
+	return &ClaudeAgent{}, nil
+}
+
+// SetBudget sets per-run and per-story budget limits for new loops. Limits set
+// here take precedence over the budget section of the project config.
+func (m *Manager) SetBudget(prdBudget, storyBudget Budget) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prdBudget = prdBudget
+	m.storyBudget = storyBudget
+}
+
+// budgets returns the effective per-run and per-story budgets for a new loop.
+func (m *Manager) budgets() (Budget, Budget, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var prdBudget, storyBudget Budget
+	if m.config != nil {
+		var err error
+		if prdBudget, err = BudgetFromConfig(m.config.Budget.PRD); err != nil {
+			return Budget{}, Budget{}, fmt.Errorf("prd: %w", err)
+		}
+		if storyBudget, err = BudgetFromConfig(m.config.Budget.Story); err != nil {
+			return Budget{}, Budget{}, fmt.Errorf("story: %w", err)
+		}
+	}
+	return prdBudget.Merge(m.prdBudget), storyBudget.Merge(m.storyBudget), nil
+}
+
 // Config returns the current project config.
 func (m *Manager) Config() *config.Config {
 	m.mu.RLock()
@@ -194,8 +383,11 @@ func (m *Manager) Unregister(name string) error {
 		return fmt.Errorf("PRD %s not found", name)
 	}
 
-	// Stop if running
-	if instance.State == LoopStateRunning {
+	// Stop if running or queued
+	instance.mu.Lock()
+	state := instance.State
+	instance.mu.Unlock()
+	if state == LoopStateRunning || state == LoopStateQueued {
 		m.Stop(name)
 	}
 
@@ -206,7 +398,9 @@ func (m *Manager) Unregister(name string) error {
 	return nil
 }
 
-// Start starts the loop for a specific PRD.
+// Start starts the loop for a specific PRD. When the maximum number of
+// concurrent loops is already running, the PRD is queued instead and starts
+// automatically once another loop finishes.
 func (m *Manager) Start(name string) error {
 	m.mu.Lock()
 	instance, exists := m.instances[name]
@@ -216,37 +410,235 @@ func (m *Manager) Start(name string) error {
 		return fmt.Errorf("PRD %s not found", name)
 	}
 
-	instance.mu.Lock()
-	if instance.State == LoopStateRunning {
-		instance.mu.Unlock()
-		return fmt.Errorf("PRD %s is already running", name)
+	agent, err := m.newAgent()
+	if err != nil {
+		return fmt.Errorf("invalid agent config: %w", err)
+	}
+
+	prdBudget, storyBudget, err := m.budgets()
+	if err != nil {
+		return fmt.Errorf("invalid budget config: %w", err)
+	}
+
+	timeouts, err := m.timeoutConfig()
+	if err != nil {
+		return fmt.Errorf("invalid timeout config: %w", err)
+	}
+
+	permissions, err := m.permissions()
+	if err != nil {
+		return fmt.Errorf("invalid permissions config: %w", err)
+	}
+
+	retention, err := m.logRetention()
+	if err != nil {
+		return fmt.Errorf("invalid logs config: %w", err)
+	}
+
+	maxLoops, _, err := loopLimit(m.Config())
+	if err != nil {
+		return fmt.Errorf("invalid loops config: %w", err)
+	}
+
+	if err := ValidateChains(m.Config(), prdsDirOf(instance.PRDPath)); err != nil {
+		return fmt.Errorf("invalid chains: %w", err)
+	}
+
+	m.mu.RLock()
+	var wrapper []string
+	if m.config != nil {
+		wrapper = m.config.Agent.Wrapper
+	}
+	m.mu.RUnlock()
+	if err := CheckWrapper(wrapper); err != nil {
+		return fmt.Errorf("invalid agent wrapper: %w", err)
+	}
+
+	m.mu.RLock()
+	baseDir := m.baseDir
+	m.mu.RUnlock()
+	promptTemplate, err := LoadPromptTemplate(baseDir, instance.PRDPath)
+	if err != nil {
+		return err
+	}
+
+	// Queued PRDs start in order of the PRD's priority when configured. With
+	// no default max iterations, each PRD gets its remaining stories + 5.
+	priority := 0
+	m.mu.RLock()
+	maxIter := m.maxIter
+	m.mu.RUnlock()
+	if p, err := prd.LoadPRD(instance.PRDPath); err == nil {
+		priority = p.Priority
+		if maxIter <= 0 {
+			maxIter = 5
+			for _, story := range p.UserStories {
+				if !story.Passes {
+					maxIter++
+				}
+			}
+		}
 	}
 
 	// Create a new loop instance, using worktree-aware constructor if WorktreeDir is set.
 	// When no worktree is configured, run from the project root (baseDir) so that
 	// CLAUDE.md and other project-level files are visible to Claude.
 	prompt := embed.GetPrompt(instance.PRDPath)
+	instance.mu.Lock()
 	workDir := instance.WorktreeDir
+	instance.mu.Unlock()
 	if workDir == "" {
-		m.mu.RLock()
-		workDir = m.baseDir
-		m.mu.RUnlock()
+		workDir = baseDir
 	}
-	instance.Loop = NewLoopWithWorkDir(instance.PRDPath, workDir, prompt, m.maxIter)
-	m.mu.RLock()
-	instance.Loop.SetRetryConfig(m.retryConfig)
-	m.mu.RUnlock()
+	l := NewLoopWithWorkDir(instance.PRDPath, workDir, prompt, maxIter)
+	l.SetAgent(agent)
+	l.SetBudget(prdBudget, storyBudget)
+	l.SetTimeoutConfig(timeouts)
+	l.SetPromptTemplate(promptTemplate)
+	l.SetPermissions(permissions)
+	l.SetWrapper(wrapper)
+	l.SetLogRetention(retention)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	retryConfig := m.retryConfig
+	if m.config != nil && m.config.Agent.ResumeOnRetry {
+		retryConfig.ResumeSession = true
+	}
+	l.SetRetryConfig(retryConfig)
+	if m.config != nil {
+		l.SetVerifyConfig(m.config.Verify)
+		l.SetParallelism(m.config.Parallel.MaxStories, m.config.Worktree.Setup)
+		l.SetAgentOptions(m.config.Agent.Model, m.config.Agent.ExtraArgs)
+		l.SetMaxStoryAttempts(m.config.Stories.MaxAttempts)
+		if n := m.config.Stories.MaxIdleIterations; n != 0 {
+			l.SetMaxIdleIterations(n)
+		}
+	}
+
+	instance.mu.Lock()
+	switch instance.State {
+	case LoopStateRunning:
+		instance.mu.Unlock()
+		return fmt.Errorf("PRD %s is already running", name)
+	case LoopStateQueued:
+		instance.mu.Unlock()
+		return fmt.Errorf("PRD %s is already queued", name)
+	}
+	instance.Loop = l
+	instance.Error = nil
+	instance.Usage = Usage{}
+	instance.StoryUsage = nil
+	instance.IterationUsage = nil
+	instance.Profile = permissions.Profile
+	instance.currentStory = ""
+	instance.priority = priority
+	instance.mu.Unlock()
+
+	if maxLoops > 0 && m.runningCount() >= maxLoops {
+		instance.mu.Lock()
+		instance.State = LoopStateQueued
+		instance.mu.Unlock()
+		m.queue = append(m.queue, instance)
+		return nil
+	}
+
+	m.launch(instance)
+	return nil
+}
+
+// launch starts the goroutine that runs an instance's loop.
+// The caller must hold m.mu.
+func (m *Manager) launch(instance *LoopInstance) {
+	instance.mu.Lock()
 	instance.ctx, instance.cancel = context.WithCancel(context.Background())
 	instance.State = LoopStateRunning
 	instance.StartTime = time.Now()
-	instance.Error = nil
 	instance.mu.Unlock()
 
-	// Start the loop in a goroutine
 	m.wg.Add(1)
 	go m.runLoop(instance)
+}
 
-	return nil
+// runningCount returns the number of running loops.
+// The caller must hold m.mu.
+func (m *Manager) runningCount() int {
+	count := 0
+	for _, instance := range m.instances {
+		instance.mu.Lock()
+		if instance.State == LoopStateRunning {
+			count++
+		}
+		instance.mu.Unlock()
+	}
+	return count
+}
+
+// orderedQueue returns the queued instances in the order they will start.
+// The caller must hold m.mu.
+func (m *Manager) orderedQueue() []*LoopInstance {
+	queue := append([]*LoopInstance(nil), m.queue...)
+	if _, order, err := loopLimit(m.config); err == nil && order == config.QueueOrderPriority {
+		// Lower priorities start first; PRDs without one go last
+		rank := func(i *LoopInstance) int {
+			if i.priority == 0 {
+				return math.MaxInt
+			}
+			return i.priority
+		}
+		sort.SliceStable(queue, func(i, j int) bool { return rank(queue[i]) < rank(queue[j]) })
+	}
+	return queue
+}
+
+// queuePosition returns an instance's place in the run queue, or 0 if it
+// isn't queued. The caller must hold m.mu.
+func (m *Manager) queuePosition(instance *LoopInstance) int {
+	for i, queued := range m.orderedQueue() {
+		if queued == instance {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// dequeue removes an instance from the run queue and reports whether it was
+// queued. The caller must hold m.mu.
+func (m *Manager) dequeue(instance *LoopInstance) bool {
+	for i, queued := range m.queue {
+		if queued == instance {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// startQueued starts queued PRDs while fewer than the maximum number of
+// loops are running.
+func (m *Manager) startQueued() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	maxLoops, _, _ := loopLimit(m.config)
+	for len(m.queue) > 0 && (maxLoops <= 0 || m.runningCount() < maxLoops) {
+		next := m.orderedQueue()[0]
+		m.dequeue(next)
+		m.launch(next)
+	}
+}
+
+// Queue returns the names of the queued PRDs in the order they will start.
+func (m *Manager) Queue() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var names []string
+	for _, instance := range m.orderedQueue() {
+		names = append(names, instance.Name)
+	}
+	return names
 }
 
 // runLoop runs a loop instance and forwards events.
@@ -266,8 +658,24 @@ func (m *Manager) runLoop(instance *LoopInstance) {
 
 				instance.mu.Lock()
 				instance.Iteration = event.Iteration
+				if event.Type == EventStoryStarted && event.StoryID != "" {
+					instance.currentStory = event.StoryID
+				}
+				// Parallel story workers tag their results with the story
+				storyID := instance.currentStory
+				if event.StoryID != "" {
+					storyID = event.StoryID
+				}
+				if event.Type == EventIterationResult && event.Usage != nil {
+					instance.recordUsage(event.Iteration, storyID, *event.Usage)
+				}
 				instance.mu.Unlock()
 
+				// Persist usage totals next to prd.json
+				if event.Type == EventIterationResult && event.Usage != nil {
+					_ = RecordUsage(instance.PRDPath, storyID, *event.Usage)
+				}
+
 				// Check if this is a completion event
 				completed := event.Type == EventComplete
 
@@ -324,8 +732,20 @@ func (m *Manager) runLoop(instance *LoopInstance) {
 			instance.State = LoopStatePaused
 		}
 	}
+	completed := instance.State == LoopStateComplete
 	instance.mu.Unlock()
 
+	// Start the PRDs chained after this one
+	m.mu.RLock()
+	stopping := m.stopping
+	m.mu.RUnlock()
+	if completed && !stopping {
+		m.startSuccessors(instance)
+	}
+
+	// Free the slot for the next queued PRD
+	m.startQueued()
+
 	<-done
 }
 
@@ -353,11 +773,17 @@ func (m *Manager) Pause(name string) error {
 	return nil
 }
 
-// Stop stops the loop for a specific PRD immediately.
+// Stop stops the loop for a specific PRD immediately. A queued PRD is
+// removed from the queue.
 func (m *Manager) Stop(name string) error {
-	m.mu.RLock()
+	m.mu.Lock()
 	instance, exists := m.instances[name]
-	m.mu.RUnlock()
+	if exists && m.dequeue(instance) {
+		instance.mu.Lock()
+		instance.State = LoopStateStopped
+		instance.mu.Unlock()
+	}
+	m.mu.Unlock()
 
 	if !exists {
 		return fmt.Errorf("PRD %s not found", name)
@@ -442,6 +868,10 @@ func (m *Manager) GetState(name string) (LoopState, int, error) {
 func (m *Manager) GetInstance(name string) *LoopInstance {
 	m.mu.RLock()
 	instance, exists := m.instances[name]
+	var position int
+	if exists {
+		position = m.queuePosition(instance)
+	}
 	m.mu.RUnlock()
 
 	if !exists {
@@ -452,16 +882,9 @@ func (m *Manager) GetInstance(name string) *LoopInstance {
 	defer instance.mu.Unlock()
 
 	// Return a copy to avoid race conditions
-	return &LoopInstance{
-		Name:        instance.Name,
-		PRDPath:     instance.PRDPath,
-		WorktreeDir: instance.WorktreeDir,
-		Branch:      instance.Branch,
-		State:       instance.State,
-		Iteration:   instance.Iteration,
-		StartTime:   instance.StartTime,
-		Error:       instance.Error,
-	}
+	c := instance.snapshot()
+	c.QueuePosition = position
+	return c
 }
 
 // GetAllInstances returns a snapshot of all loop instances.
@@ -471,19 +894,12 @@ func (m *Manager) GetAllInstances() []*LoopInstance {
 
 	result := make([]*LoopInstance, 0, len(m.instances))
 	for _, instance := range m.instances {
+		position := m.queuePosition(instance)
 		instance.mu.Lock()
-		copy := &LoopInstance{
-			Name:        instance.Name,
-			PRDPath:     instance.PRDPath,
-			WorktreeDir: instance.WorktreeDir,
-			Branch:      instance.Branch,
-			State:       instance.State,
-			Iteration:   instance.Iteration,
-			StartTime:   instance.StartTime,
-			Error:       instance.Error,
-		}
+		c := instance.snapshot()
+		c.QueuePosition = position
+		result = append(result, c)
 		instance.mu.Unlock()
-		result = append(result, copy)
 	}
 
 	return result
@@ -511,14 +927,22 @@ func (m *Manager) GetRunningCount() int {
 	return len(m.GetRunningPRDs())
 }
 
-// StopAll stops all running loops.
+// StopAll stops all running loops and empties the run queue.
 func (m *Manager) StopAll() {
-	m.mu.RLock()
+	// Empty the queue first so stopped loops don't start queued ones
+	m.mu.Lock()
+	for _, instance := range m.queue {
+		instance.mu.Lock()
+		instance.State = LoopStateStopped
+		instance.mu.Unlock()
+	}
+	m.queue = nil
+	m.stopping = true
 	names := make([]string, 0, len(m.instances))
 	for name := range m.instances {
 		names = append(names, name)
 	}
-	m.mu.RUnlock()
+	m.mu.Unlock()
 
 	for _, name := range names {
 		m.Stop(name)
@@ -526,6 +950,15 @@ func (m *Manager) StopAll() {
 
 	// Wait for all loops to finish
 	m.wg.Wait()
+
+	m.mu.Lock()
+	m.stopping = false
+	m.mu.Unlock()
+}
+
+// Wait blocks until all started loops have finished.
+func (m *Manager) Wait() {
+	m.wg.Wait()
 }
 
 // IsAnyRunning returns true if any loop is currently running.
@@ -1,8 +1,11 @@
 package loop
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -256,6 +259,7 @@ func TestLoopStateString(t *testing.T) {
 		{LoopStateStopped, "Stopped"},
 		{LoopStateComplete, "Complete"},
 		{LoopStateError, "Error"},
+		{LoopStateQueued, "Queued"},
 		{LoopState(99), "Unknown"},
 	}
 
@@ -266,6 +270,17 @@ func TestLoopStateString(t *testing.T) {
 	}
 }
 
+func TestParseLoopState(t *testing.T) {
+	for s := LoopStateReady; s <= LoopStateQueued; s++ {
+		if got := ParseLoopState(s.String()); got != s {
+			t.Errorf("Expected %s, got %s", s, got)
+		}
+	}
+	if got := ParseLoopState("Bogus"); got != LoopStateReady {
+		t.Errorf("Expected Ready for an unknown state, got %s", got)
+	}
+}
+
 func TestManagerSetCompletionCallback(t *testing.T) {
 	m := NewManager(10)
 
@@ -611,3 +626,276 @@ func TestManagerConcurrentAccessWithWorktreeFields(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestManagerStartInvalidAgentConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRDWithName(t, tmpDir, "test-prd")
+
+	m := NewManager(10)
+	cfg := config.Default()
+	cfg.Agent.Provider = "unknown"
+	m.SetConfig(cfg)
+	m.Register("test-prd", prdPath)
+
+	if err := m.Start("test-prd"); err == nil {
+		t.Error("expected error when agent config is invalid")
+	}
+	if state, _, _ := m.GetState("test-prd"); state != LoopStateReady {
+		t.Errorf("expected state Ready after failed start, got %v", state)
+	}
+}
+
+func TestManagerBudgets(t *testing.T) {
+	m := NewManager(10)
+	cfg := config.Default()
+	cfg.Budget.PRD = config.BudgetLimits{MaxCostUSD: 20, MaxDuration: "2h"}
+	cfg.Budget.Story = config.BudgetLimits{MaxTokens: 1000}
+	m.SetConfig(cfg)
+	m.SetBudget(Budget{MaxCostUSD: 5}, Budget{})
+
+	prdBudget, storyBudget, err := m.budgets()
+	if err != nil {
+		t.Fatalf("budgets() returned error: %v", err)
+	}
+	if prdBudget != (Budget{MaxCostUSD: 5, MaxDuration: 2 * time.Hour}) {
+		t.Errorf("expected CLI cost to override config, got %+v", prdBudget)
+	}
+	if storyBudget != (Budget{MaxTokens: 1000}) {
+		t.Errorf("unexpected story budget: %+v", storyBudget)
+	}
+}
+
+func TestManagerStartInvalidBudgetConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRDWithName(t, tmpDir, "test-prd")
+
+	m := NewManager(10)
+	cfg := config.Default()
+	cfg.Budget.Story.MaxDuration = "forever"
+	m.SetConfig(cfg)
+	m.Register("test-prd", prdPath)
+
+	if err := m.Start("test-prd"); err == nil {
+		t.Error("expected error when budget config is invalid")
+	}
+}
+
+func TestManagerStartUnknownPermissionProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRDWithName(t, tmpDir, "test-prd")
+
+	m := NewManager(10)
+	cfg := config.Default()
+	cfg.Permissions.Profile = "missing"
+	m.SetConfig(cfg)
+	m.Register("test-prd", prdPath)
+
+	if err := m.Start("test-prd"); err == nil {
+		t.Error("expected error when the permission profile doesn't exist")
+	}
+}
+
+func TestManagerStartMissingWrapper(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRDWithName(t, tmpDir, "test-prd")
+
+	m := NewManager(10)
+	cfg := config.Default()
+	cfg.Agent.Wrapper = []string{"chief-missing-sandbox", "--"}
+	m.SetConfig(cfg)
+	m.Register("test-prd", prdPath)
+
+	err := m.Start("test-prd")
+	var wrapperErr *WrapperError
+	if !errors.As(err, &wrapperErr) {
+		t.Errorf("expected a WrapperError when the wrapper doesn't exist, got %v", err)
+	}
+}
+
+// waitForState waits until a PRD's loop reaches the given state.
+func waitForState(t *testing.T, m *Manager, name string, want LoopState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		state, _, _ := m.GetState(name)
+		if state == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %s to be %s, got %s", name, want, state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newQueueTestManager creates a manager that runs at most maxLoops of the
+// given PRDs at once, each with an agent that runs until it's stopped.
+func newQueueTestManager(t *testing.T, maxLoops int, order string, names ...string) *Manager {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	m := NewManager(10)
+	cfg := config.Default()
+	cfg.Loops = config.LoopsConfig{MaxConcurrent: maxLoops, QueueOrder: order}
+	m.SetConfig(cfg)
+	m.SetBaseDir(tmpDir)
+	runs := make([]fakeRun, 10)
+	for i := range runs {
+		runs[i] = fakeRun{hang: true}
+	}
+	m.SetAgent(&fakeAgent{runs: runs})
+	for _, name := range names {
+		m.Register(name, createTestPRDWithName(t, tmpDir, name))
+	}
+
+	// Keep the events channel from filling up
+	go func() {
+		for range m.Events() {
+		}
+	}()
+	t.Cleanup(m.StopAll)
+	return m
+}
+
+func TestManagerQueuesBeyondMaxConcurrent(t *testing.T) {
+	m := newQueueTestManager(t, 1, "", "prd1", "prd2", "prd3")
+
+	for _, name := range []string{"prd1", "prd2", "prd3"} {
+		if err := m.Start(name); err != nil {
+			t.Fatalf("Start(%s) failed: %v", name, err)
+		}
+	}
+	waitForState(t, m, "prd1", LoopStateRunning)
+	waitForState(t, m, "prd2", LoopStateQueued)
+	waitForState(t, m, "prd3", LoopStateQueued)
+	if got := m.GetInstance("prd3").QueuePosition; got != 2 {
+		t.Errorf("Expected prd3 at queue position 2, got %d", got)
+	}
+	if err := m.Start("prd2"); err == nil {
+		t.Error("Expected an error when starting a queued PRD again")
+	}
+
+	// Stopping the running loop starts the next queued PRD
+	m.Stop("prd1")
+	waitForState(t, m, "prd2", LoopStateRunning)
+	if state, _, _ := m.GetState("prd3"); state != LoopStateQueued {
+		t.Errorf("Expected prd3 to stay queued, got %s", state)
+	}
+	if got := m.GetInstance("prd3").QueuePosition; got != 1 {
+		t.Errorf("Expected prd3 at queue position 1, got %d", got)
+	}
+	if got := m.GetRunningCount(); got != 1 {
+		t.Errorf("Expected 1 running loop, got %d", got)
+	}
+}
+
+func TestManagerStopQueued(t *testing.T) {
+	m := newQueueTestManager(t, 1, "", "prd1", "prd2")
+
+	m.Start("prd1")
+	m.Start("prd2")
+	waitForState(t, m, "prd2", LoopStateQueued)
+
+	if err := m.Stop("prd2"); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if state, _, _ := m.GetState("prd2"); state != LoopStateStopped {
+		t.Errorf("Expected the queued PRD to be stopped, got %s", state)
+	}
+	if queue := m.Queue(); len(queue) != 0 {
+		t.Errorf("Expected an empty queue, got %v", queue)
+	}
+
+	// Nothing is left to start when the running loop stops
+	m.Stop("prd1")
+	waitForState(t, m, "prd1", LoopStateStopped)
+	if state, _, _ := m.GetState("prd2"); state != LoopStateStopped {
+		t.Errorf("Expected the removed PRD to stay stopped, got %s", state)
+	}
+}
+
+func TestManagerQueuePriorityOrder(t *testing.T) {
+	m := newQueueTestManager(t, 1, config.QueueOrderPriority, "running", "none", "low", "high")
+	setPriority := func(name string, priority int) {
+		inst := m.GetInstance(name)
+		data, _ := os.ReadFile(inst.PRDPath)
+		data = []byte(strings.Replace(string(data), `"project"`, fmt.Sprintf(`"priority": %d, "project"`, priority), 1))
+		if err := os.WriteFile(inst.PRDPath, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setPriority("low", 5)
+	setPriority("high", 1)
+
+	for _, name := range []string{"running", "none", "low", "high"} {
+		if err := m.Start(name); err != nil {
+			t.Fatalf("Start(%s) failed: %v", name, err)
+		}
+	}
+	queue := m.Queue()
+	if strings.Join(queue, ",") != "high,low,none" {
+		t.Errorf("Expected queue [high low none], got %v", queue)
+	}
+
+	m.Stop("running")
+	waitForState(t, m, "high", LoopStateRunning)
+}
+
+func TestManagerStartInvalidLoopsConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRDWithName(t, tmpDir, "test-prd")
+
+	m := NewManager(10)
+	cfg := config.Default()
+	cfg.Loops.QueueOrder = "random"
+	m.SetConfig(cfg)
+	m.Register("test-prd", prdPath)
+
+	if err := m.Start("test-prd"); err == nil {
+		t.Error("expected error when the queue order is unknown")
+	}
+}
+
+func TestManagerDynamicMaxIterations(t *testing.T) {
+	m := newQueueTestManager(t, 0, "", "auth")
+	m.SetMaxIterations(0)
+
+	if err := m.Start("auth"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	m.mu.RLock()
+	l := m.instances["auth"].Loop
+	m.mu.RUnlock()
+	// One remaining story + 5
+	if got := l.MaxIterations(); got != 6 {
+		t.Errorf("Expected 6 max iterations, got %d", got)
+	}
+}
+
+func TestManagerStartUnwritablePRDDir(t *testing.T) {
+	m := newQueueTestManager(t, 0, "", "prd1")
+
+	// A file where the runs directory goes keeps the run from being recorded
+	prdPath := m.GetInstance("prd1").PRDPath
+	if err := os.WriteFile(RunsDir(prdPath), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Start("prd1"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	waitForState(t, m, "prd1", LoopStateError)
+
+	// The loop's events channel is closed, so waiting for it doesn't hang
+	waited := make(chan struct{})
+	go func() {
+		m.StopAll()
+		m.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected StopAll and Wait to return after the loop failed to start")
+	}
+}
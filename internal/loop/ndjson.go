@@ -0,0 +1,137 @@
+package loop
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// eventJSON is the machine-readable representation of an event, written as
+// one object per line in NDJSON output.
+type eventJSON struct {
+	Time       time.Time              `json:"time"`
+	PRD        string                 `json:"prd,omitempty"`
+	Type       string                 `json:"type"`
+	Iteration  int                    `json:"iteration"`
+	StoryID    string                 `json:"storyId,omitempty"`
+	Tool       string                 `json:"tool,omitempty"`
+	ToolInput  map[string]interface{} `json:"toolInput,omitempty"`
+	ToolUseID  string                 `json:"toolUseId,omitempty"`
+	Text       string                 `json:"text,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	RetryCount int                    `json:"retryCount,omitempty"`
+	RetryMax   int                    `json:"retryMax,omitempty"`
+	Reason     string                 `json:"reason,omitempty"`
+	SessionID  string                 `json:"sessionId,omitempty"`
+	Model      string                 `json:"model,omitempty"`
+	Usage      *Usage                 `json:"usage,omitempty"`
+	Completed  bool                   `json:"completed,omitempty"`
+}
+
+// toJSON converts an event to its JSON representation.
+func (e Event) toJSON() eventJSON {
+	ts := e.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	out := eventJSON{
+		Time:       ts,
+		Type:       e.Type.String(),
+		Iteration:  e.Iteration,
+		StoryID:    e.StoryID,
+		Tool:       e.Tool,
+		ToolInput:  e.ToolInput,
+		ToolUseID:  e.ToolUseID,
+		Text:       e.Text,
+		RetryCount: e.RetryCount,
+		RetryMax:   e.RetryMax,
+		Reason:     e.Reason,
+		SessionID:  e.SessionID,
+		Model:      e.Model,
+		Usage:      e.Usage,
+	}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	return out
+}
+
+// MarshalJSON encodes the event with its type as a string and its error as a message.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.toJSON())
+}
+
+// UnmarshalJSON decodes an event written by MarshalJSON. The error, if any,
+// is restored as a plain error carrying the original message.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var in eventJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*e = Event{
+		Type:       ParseEventType(in.Type),
+		Iteration:  in.Iteration,
+		Text:       in.Text,
+		Tool:       in.Tool,
+		ToolInput:  in.ToolInput,
+		StoryID:    in.StoryID,
+		RetryCount: in.RetryCount,
+		RetryMax:   in.RetryMax,
+		Time:       in.Time,
+		Usage:      in.Usage,
+		Reason:     in.Reason,
+		ToolUseID:  in.ToolUseID,
+		SessionID:  in.SessionID,
+		Model:      in.Model,
+	}
+	if in.Error != "" {
+		e.Err = errors.New(in.Error)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the manager event as a flat object including the PRD name.
+func (e ManagerEvent) MarshalJSON() ([]byte, error) {
+	out := e.Event.toJSON()
+	out.PRD = e.PRDName
+	out.Completed = e.Completed
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a manager event written by MarshalJSON.
+func (e *ManagerEvent) UnmarshalJSON(data []byte) error {
+	var in struct {
+		PRD       string `json:"prd"`
+		Completed bool   `json:"completed"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if err := e.Event.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	e.PRDName = in.PRD
+	e.Completed = in.Completed
+	return nil
+}
+
+// JSONEventWriter writes manager events as newline-delimited JSON.
+// It is safe for concurrent use.
+type JSONEventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONEventWriter creates a writer that emits one JSON object per line to w.
+func NewJSONEventWriter(w io.Writer) *JSONEventWriter {
+	return &JSONEventWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes a single event as a JSON line.
+func (w *JSONEventWriter) Write(event ManagerEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(event)
+}
@@ -0,0 +1,124 @@
+package loop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManagerEventMarshalJSON(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := ManagerEvent{
+		PRDName: "auth",
+		Event: Event{
+			Type:      EventToolStart,
+			Iteration: 3,
+			StoryID:   "US-002",
+			Tool:      "Read",
+			ToolInput: map[string]interface{}{"file_path": "main.go"},
+			Time:      ts,
+		},
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if got["prd"] != "auth" {
+		t.Errorf("expected prd auth, got %v", got["prd"])
+	}
+	if got["type"] != "ToolStart" {
+		t.Errorf("expected type ToolStart, got %v", got["type"])
+	}
+	if got["iteration"] != float64(3) {
+		t.Errorf("expected iteration 3, got %v", got["iteration"])
+	}
+	if got["storyId"] != "US-002" {
+		t.Errorf("expected storyId US-002, got %v", got["storyId"])
+	}
+	if got["time"] != "2026-01-02T03:04:05Z" {
+		t.Errorf("expected timestamp, got %v", got["time"])
+	}
+	input, _ := got["toolInput"].(map[string]interface{})
+	if input["file_path"] != "main.go" {
+		t.Errorf("expected tool input, got %v", got["toolInput"])
+	}
+}
+
+func TestManagerEventUnmarshalJSON(t *testing.T) {
+	event := ManagerEvent{
+		PRDName:   "auth",
+		Event:     Event{Type: EventComplete, Iteration: 4, Err: errors.New("boom"), Time: time.Now().UTC()},
+		Completed: true,
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var got ManagerEvent
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.PRDName != "auth" || !got.Completed || got.Event.Type != EventComplete || got.Event.Iteration != 4 {
+		t.Errorf("Expected the event to round-trip, got %+v", got)
+	}
+	if got.Event.Err == nil || got.Event.Err.Error() != "boom" {
+		t.Errorf("Expected error boom, got %v", got.Event.Err)
+	}
+}
+
+func TestEventMarshalJSONError(t *testing.T) {
+	data, err := json.Marshal(Event{Type: EventError, Err: errors.New("boom"), RetryCount: 2, RetryMax: 3})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got["error"] != "boom" {
+		t.Errorf("expected error message, got %v", got["error"])
+	}
+	if got["retryCount"] != float64(2) || got["retryMax"] != float64(3) {
+		t.Errorf("expected retry counts, got %v/%v", got["retryCount"], got["retryMax"])
+	}
+	if _, ok := got["time"]; !ok {
+		t.Error("expected a timestamp even when the event has none")
+	}
+}
+
+func TestJSONEventWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewJSONEventWriter(&buf)
+
+	w.Write(ManagerEvent{PRDName: "a", Event: Event{Type: EventIterationStart, Iteration: 1}})
+	w.Write(ManagerEvent{PRDName: "a", Event: Event{Type: EventComplete, Iteration: 1}, Completed: true})
+
+	scanner := bufio.NewScanner(&buf)
+	var lines []map[string]interface{}
+	for scanner.Scan() {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			t.Fatalf("line is not valid JSON: %q", scanner.Text())
+		}
+		lines = append(lines, obj)
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[1]["type"] != "Complete" || lines[1]["completed"] != true {
+		t.Errorf("unexpected second line: %v", lines[1])
+	}
+}
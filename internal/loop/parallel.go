@@ -0,0 +1,404 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/minicodemonkey/chief/embed"
+	"github.com/minicodemonkey/chief/internal/git"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// maxStoryIterations limits how many iterations a parallel story worker gets
+// before its story is handed back to the next round.
+const maxStoryIterations = 3
+
+// storyWorker runs a single story in its own worktree during a parallel round.
+type storyWorker struct {
+	story    prd.UserStory
+	dir      string // Story directory holding the single-story prd.json and logs
+	prdPath  string // Single-story prd.json the agent works from
+	worktree string // Worktree the agent runs in, outside the PRD directory
+	branch   string // Story branch, created from the PRD branch
+	progress int    // Length of the progress.md copied into dir
+	loop     *Loop
+	err      error
+}
+
+// parallelStories returns the stories to work on side by side in the next
+// round, or nil when the next iteration should run a single story as usual.
+func (l *Loop) parallelStories() (*prd.PRD, []prd.UserStory) {
+	l.mu.Lock()
+	maxStories := l.parallel
+	l.mu.Unlock()
+	if maxStories < 2 || !git.IsGitRepo(l.effectiveWorkDir()) {
+		return nil, nil
+	}
+
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		return nil, nil
+	}
+	ready := p.ReadyStories()
+	if len(ready) < 2 {
+		return nil, nil
+	}
+	if len(ready) > maxStories {
+		ready = ready[:maxStories]
+	}
+
+	stories := make([]prd.UserStory, len(ready))
+	for i, story := range ready {
+		stories[i] = *story
+	}
+	return p, stories
+}
+
+// runParallel works on independent stories at the same time, each in its own
+// worktree on a branch created from the PRD branch. Stories that complete are
+// merged back into the PRD branch and marked as passing in prd.json.
+func (l *Loop) runParallel(ctx context.Context, p *prd.PRD, stories []prd.UserStory) error {
+	l.mu.Lock()
+	iter := l.iteration
+	l.mu.Unlock()
+
+	repoDir := l.effectiveWorkDir()
+	base, err := git.GetCurrentBranch(repoDir)
+	if err != nil {
+		return fmt.Errorf("failed to detect PRD branch: %w", err)
+	}
+
+	var workers []*storyWorker
+	for _, story := range stories {
+		w, err := l.newStoryWorker(p, story, repoDir, base)
+		if err != nil {
+			l.emit(Event{
+				Type:      EventStoryFailed,
+				Iteration: iter,
+				StoryID:   story.ID,
+				Text:      fmt.Sprintf("%s could not be started: %v", story.ID, err),
+				Err:       err,
+			})
+			continue
+		}
+		workers = append(workers, w)
+	}
+	if len(workers) == 0 {
+		return fmt.Errorf("failed to start any of %d parallel stories", len(stories))
+	}
+
+	ids := make([]string, len(workers))
+	for i, w := range workers {
+		ids[i] = w.story.ID
+	}
+	l.setStoriesInProgress(ids, true)
+
+	l.mu.Lock()
+	l.workers = workers
+	l.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *storyWorker) {
+			defer wg.Done()
+			w.err = l.runStoryWorker(ctx, iter, w)
+		}(w)
+	}
+	wg.Wait()
+
+	l.mu.Lock()
+	l.workers = nil
+	l.mu.Unlock()
+
+	// Merge completed stories one at a time so each sees the previous merges
+	var merged []string
+	var firstErr error
+	for _, w := range workers {
+		if ctx.Err() == nil && l.finishStoryWorker(iter, repoDir, base, w) {
+			merged = append(merged, w.story.ID)
+		} else if w.err != nil && firstErr == nil {
+			firstErr = w.err
+		}
+		l.removeStoryWorker(repoDir, w)
+	}
+
+	l.setStoriesInProgress(ids, false)
+	if len(merged) > 0 {
+		if err := l.markStoriesPassed(merged); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(merged) == 0 && firstErr != nil && !l.IsStopped() {
+		return firstErr
+	}
+	return nil
+}
+
+// newStoryWorker prepares the story directory, single-story prd.json and
+// worktree for a story, and creates the loop that will work on it.
+func (l *Loop) newStoryWorker(p *prd.PRD, story prd.UserStory, repoDir, base string) (*storyWorker, error) {
+	prdDir, err := filepath.Abs(filepath.Dir(l.prdPath))
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(prdDir, "stories", story.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create story directory: %w", err)
+	}
+
+	// Story worktrees live next to the PRD worktrees rather than in the PRD
+	// directory, which the agents working on the PRD can write to. prdDir is
+	// <baseDir>/.chief/prds/<name>.
+	baseDir := filepath.Dir(filepath.Dir(filepath.Dir(prdDir)))
+	name := fmt.Sprintf("%s-%s", filepath.Base(prdDir), story.ID)
+	w := &storyWorker{
+		story:    story,
+		dir:      dir,
+		prdPath:  filepath.Join(dir, "prd.json"),
+		worktree: git.WorktreePathForPRD(baseDir, name),
+		branch:   "chief/" + name,
+	}
+
+	// The agent only sees its own story; dependencies have already passed
+	story.DependsOn = nil
+	story.Passes = false
+	sub := &prd.PRD{
+		Project:     p.Project,
+		Description: p.Description,
+		UserStories: []prd.UserStory{story},
+		Model:       p.Model,
+		AgentArgs:   p.AgentArgs,
+	}
+	if err := sub.Save(w.prdPath); err != nil {
+		return nil, err
+	}
+
+	// Start from the shared progress log so the agent sees the codebase patterns
+	progress, _ := os.ReadFile(prd.ProgressPath(l.prdPath))
+	if err := os.WriteFile(filepath.Join(dir, "progress.md"), progress, 0644); err != nil {
+		return nil, fmt.Errorf("failed to copy progress.md: %w", err)
+	}
+	w.progress = len(progress)
+
+	_, statErr := os.Stat(w.worktree)
+	fresh := os.IsNotExist(statErr)
+	if err := git.CreateWorktreeFrom(repoDir, w.worktree, w.branch, base); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	setup := l.worktreeSetup
+	agent := l.agent
+	retryConfig := l.retryConfig
+	timeouts := l.timeouts
+	feedback := l.verifyFeedback[story.ID]
+	verify := l.verify
+	promptTemplate := l.promptTemplate
+	model := l.model
+	agentArgs := l.agentArgs
+	permissions := l.permissions
+	wrapper := l.wrapper
+	l.mu.Unlock()
+
+	if fresh && setup != "" {
+		cmd := exec.Command("sh", "-c", setup)
+		cmd.Dir = w.worktree
+		if out, err := cmd.CombinedOutput(); err != nil {
+			l.removeStoryWorker(repoDir, w)
+			return nil, fmt.Errorf("worktree setup failed: %s\n%s", err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	w.loop = NewLoopWithWorkDir(w.prdPath, w.worktree, embed.GetPrompt(w.prdPath), maxStoryIterations)
+	if feedback != "" {
+		w.loop.verifyFeedback = map[string]string{story.ID: feedback}
+	}
+	// The worker's events are recorded in this loop's journal
+	w.loop.skipJournal = true
+	if w.loop.runID = l.RunID(); w.loop.runID > 0 {
+		w.loop.logPath = RunStoryLogPath(l.prdPath, w.loop.runID, story.ID)
+	}
+	w.loop.SetPromptTemplate(promptTemplate)
+	w.loop.SetAgent(agent)
+	w.loop.SetRetryConfig(retryConfig)
+	w.loop.SetTimeoutConfig(timeouts)
+	w.loop.SetAgentOptions(model, agentArgs)
+	w.loop.SetPermissions(permissions)
+	w.loop.SetWrapper(wrapper)
+	// The story is verified in its worktree, so only verified work is merged
+	w.loop.SetVerifyConfig(verify)
+	return w, nil
+}
+
+// runStoryWorker runs a worker's loop, forwarding its events as events of the
+// current iteration tagged with the worker's story.
+func (l *Loop) runStoryWorker(ctx context.Context, iter int, w *storyWorker) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range w.loop.Events() {
+			switch event.Type {
+			case EventIterationStart, EventComplete, EventMaxIterationsReached, EventError:
+				// The round reports the outcome of each story once it finishes
+				continue
+			}
+			event.Iteration = iter
+			if event.StoryID == "" {
+				event.StoryID = w.story.ID
+			}
+			if event.Type == EventIterationResult && event.Usage != nil {
+				l.mu.Lock()
+				l.spent.addUsage(w.story.ID, *event.Usage)
+				l.mu.Unlock()
+			}
+			l.emit(event)
+		}
+	}()
+
+	err := w.loop.Run(ctx)
+	<-done
+	return err
+}
+
+// finishStoryWorker merges a worker's branch into the PRD branch if its story
+// passed and was verified in the worker's worktree. It returns true if the
+// story was merged.
+func (l *Loop) finishStoryWorker(iter int, repoDir, base string, w *storyWorker) bool {
+	id := w.story.ID
+
+	// Carry the outcome of the worker's verification over to the next round
+	w.loop.mu.Lock()
+	feedback := w.loop.verifyFeedback[id]
+	w.loop.mu.Unlock()
+	l.mu.Lock()
+	if feedback != "" {
+		if l.verifyFeedback == nil {
+			l.verifyFeedback = make(map[string]string)
+		}
+		l.verifyFeedback[id] = feedback
+	} else {
+		delete(l.verifyFeedback, id)
+	}
+	l.mu.Unlock()
+
+	sub, err := prd.LoadPRD(w.prdPath)
+	if err != nil || !sub.AllComplete() {
+		reason := "did not complete"
+		if w.err != nil {
+			reason = "failed: " + w.err.Error()
+		} else if l.IsStopped() {
+			reason = "was stopped"
+		}
+		l.emit(Event{
+			Type:      EventStoryFailed,
+			Iteration: iter,
+			StoryID:   id,
+			Text:      fmt.Sprintf("%s %s", id, reason),
+			Err:       w.err,
+		})
+		return false
+	}
+
+	conflicts, err := git.MergeBranch(repoDir, w.branch)
+	if err != nil {
+		text := fmt.Sprintf("%s could not be merged into %s: %v", id, base, err)
+		if len(conflicts) > 0 {
+			text = fmt.Sprintf("%s could not be merged into %s: conflicts in %s", id, base, strings.Join(conflicts, ", "))
+		}
+		l.emit(Event{
+			Type:      EventStoryFailed,
+			Iteration: iter,
+			StoryID:   id,
+			Text:      text,
+			Err:       err,
+		})
+		return false
+	}
+
+	l.appendStoryProgress(w)
+	l.emit(Event{
+		Type:      EventStoryMerged,
+		Iteration: iter,
+		StoryID:   id,
+		Text:      fmt.Sprintf("Merged %s into %s", id, base),
+	})
+	return true
+}
+
+// appendStoryProgress appends what the worker added to its copy of
+// progress.md to the PRD's progress.md.
+func (l *Loop) appendStoryProgress(w *storyWorker) {
+	data, err := os.ReadFile(filepath.Join(w.dir, "progress.md"))
+	if err != nil || len(data) <= w.progress {
+		return
+	}
+	added := strings.TrimLeft(string(data[w.progress:]), "\n")
+	if added == "" {
+		return
+	}
+
+	f, err := os.OpenFile(prd.ProgressPath(l.prdPath), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if w.progress > 0 {
+		f.WriteString("\n")
+	}
+	f.WriteString(added)
+}
+
+// removeStoryWorker removes a worker's worktree and branch. A story that
+// didn't get merged starts over from the PRD branch in a later round.
+func (l *Loop) removeStoryWorker(repoDir string, w *storyWorker) {
+	if _, err := os.Stat(w.worktree); err == nil {
+		_ = git.ForceRemoveWorktree(repoDir, w.worktree)
+	}
+	_ = git.DeleteBranch(repoDir, w.branch)
+}
+
+// setStoriesInProgress updates the inProgress flag of stories in prd.json.
+func (l *Loop) setStoriesInProgress(ids []string, inProgress bool) {
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		return
+	}
+	for i := range p.UserStories {
+		for _, id := range ids {
+			if p.UserStories[i].ID == id && !p.UserStories[i].Passes {
+				p.UserStories[i].InProgress = inProgress
+			}
+		}
+	}
+	_ = p.Save(l.prdPath)
+}
+
+// markStoriesPassed sets passes: true for merged stories in prd.json.
+func (l *Loop) markStoriesPassed(ids []string) error {
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		return fmt.Errorf("failed to load PRD: %w", err)
+	}
+	for i := range p.UserStories {
+		for _, id := range ids {
+			if p.UserStories[i].ID == id {
+				p.UserStories[i].Passes = true
+				p.UserStories[i].InProgress = false
+			}
+		}
+	}
+	if err := p.Save(l.prdPath); err != nil {
+		return fmt.Errorf("failed to mark merged stories as passing: %w", err)
+	}
+	return nil
+}
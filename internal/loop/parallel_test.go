@@ -0,0 +1,298 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/git"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// storyAgent is a fake agent that implements whichever story its PRD points
+// at by committing a file, then marks the story as passed.
+type storyAgent struct {
+	t        *testing.T
+	prdPath  string            // PRD used when not running in a story worktree
+	contents map[string]string // File content to commit per story (default: story ID)
+	mu       sync.Mutex
+	workDirs []string
+}
+
+func (a *storyAgent) Name() string { return "Fake" }
+
+func (a *storyAgent) ParseLine(line string) []Event { return ParseLine(line) }
+
+func (a *storyAgent) Start(ctx context.Context, req AgentRequest) (AgentProcess, error) {
+	a.mu.Lock()
+	a.workDirs = append(a.workDirs, req.WorkDir)
+	a.mu.Unlock()
+
+	// Story worktrees are named <prd>-<story ID>, next to the PRD worktrees
+	prdPath := a.prdPath
+	if filepath.Base(filepath.Dir(req.WorkDir)) == "worktrees" {
+		prdDir := filepath.Dir(a.prdPath)
+		id := strings.TrimPrefix(filepath.Base(req.WorkDir), filepath.Base(prdDir)+"-")
+		prdPath = filepath.Join(prdDir, "stories", id, "prd.json")
+	}
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		return nil, err
+	}
+	story := p.NextStory()
+	if story == nil {
+		return nil, fmt.Errorf("no story to work on in %s", prdPath)
+	}
+
+	content := story.ID
+	if c, ok := a.contents[story.ID]; ok {
+		content = c
+	}
+	file := "shared.txt"
+	if _, ok := a.contents[story.ID]; !ok {
+		file = story.ID + ".txt"
+	}
+	if err := os.WriteFile(filepath.Join(req.WorkDir, file), []byte(content+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	runGit(a.t, req.WorkDir, "add", file)
+	runGit(a.t, req.WorkDir, "commit", "--allow-empty", "-m", "feat: "+story.ID)
+
+	story.Passes = true
+	if err := p.Save(prdPath); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(prd.ProgressPath(prdPath), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(f, "## 2024-01-01 - %s\n- Implemented %s\n---\n", story.ID, story.ID)
+	f.Close()
+
+	return &fakeProcess{
+		stdout: strings.NewReader(`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>` + story.ID + `</ralph-status>"}]}}` + "\n" +
+			`{"type":"result","subtype":"success","total_cost_usd":1,"num_turns":1}` + "\n"),
+		stderr: strings.NewReader(""),
+	}, nil
+}
+
+// runGit runs a git command in dir and fails the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %s", args, out)
+	}
+}
+
+// initParallelRepo creates a git repository with a PRD for parallel tests.
+func initParallelRepo(t *testing.T, stories []prd.UserStory) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+	runGit(t, dir, "checkout", "-b", "chief/feature")
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte(".chief/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+	runGit(t, dir, "add", ".gitignore")
+	runGit(t, dir, "commit", "-m", "initial commit")
+
+	prdDir := filepath.Join(dir, ".chief", "prds", "feature")
+	if err := os.MkdirAll(prdDir, 0755); err != nil {
+		t.Fatalf("Failed to create PRD dir: %v", err)
+	}
+	prdPath := filepath.Join(prdDir, "prd.json")
+	p := &prd.PRD{Project: "Feature", UserStories: stories}
+	if err := p.Save(prdPath); err != nil {
+		t.Fatalf("Failed to save PRD: %v", err)
+	}
+	return dir, prdPath
+}
+
+func TestLoopParallelStories(t *testing.T) {
+	dir, prdPath := initParallelRepo(t, []prd.UserStory{
+		{ID: "US-001", Priority: 1},
+		{ID: "US-002", Priority: 2},
+		{ID: "US-003", Priority: 3, DependsOn: []string{"US-001", "US-002"}},
+	})
+
+	agent := &storyAgent{t: t, prdPath: prdPath}
+	l := NewLoopWithWorkDir(prdPath, dir, "prompt", 5)
+	l.SetAgent(agent)
+	l.SetParallelism(2, "")
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var merged []string
+	var complete bool
+	for _, e := range events {
+		switch e.Type {
+		case EventStoryMerged:
+			merged = append(merged, e.StoryID)
+		case EventStoryFailed:
+			t.Errorf("Unexpected story failure: %s", e.Text)
+		case EventComplete:
+			complete = true
+		}
+	}
+	if strings.Join(merged, ",") != "US-001,US-002" {
+		t.Errorf("Expected US-001 and US-002 to be merged, got %v", merged)
+	}
+	if !complete {
+		t.Error("Expected the loop to complete")
+	}
+
+	// The independent stories ran in worktrees, the dependent one in the PRD work dir
+	if len(agent.workDirs) != 3 {
+		t.Fatalf("Expected 3 agent runs, got %d", len(agent.workDirs))
+	}
+	worktrees := filepath.Join(dir, ".chief", "worktrees")
+	for _, wd := range agent.workDirs[:2] {
+		if filepath.Dir(wd) != worktrees {
+			t.Errorf("Expected a story worktree in %s, got %s", worktrees, wd)
+		}
+	}
+	if agent.workDirs[2] != dir {
+		t.Errorf("Expected dependent story to run in %s, got %s", dir, agent.workDirs[2])
+	}
+
+	for _, id := range []string{"US-001", "US-002", "US-003"} {
+		if _, err := os.Stat(filepath.Join(dir, id+".txt")); err != nil {
+			t.Errorf("Expected %s.txt on the PRD branch: %v", id, err)
+		}
+	}
+
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		t.Fatalf("Failed to load PRD: %v", err)
+	}
+	if !p.AllComplete() {
+		t.Errorf("Expected all stories to pass, got %+v", p.UserStories)
+	}
+
+	progress, _ := os.ReadFile(prd.ProgressPath(prdPath))
+	for _, id := range []string{"US-001", "US-002", "US-003"} {
+		if !strings.Contains(string(progress), "- Implemented "+id) {
+			t.Errorf("Expected progress.md to contain %s, got:\n%s", id, progress)
+		}
+	}
+
+	if exists, _ := git.BranchExists(dir, "chief/feature-US-001"); exists {
+		t.Error("Expected story branch to be deleted after merging")
+	}
+}
+
+func TestLoopParallelStoriesMergeConflict(t *testing.T) {
+	dir, prdPath := initParallelRepo(t, []prd.UserStory{
+		{ID: "US-001", Priority: 1},
+		{ID: "US-002", Priority: 2},
+	})
+
+	agent := &storyAgent{
+		t:        t,
+		prdPath:  prdPath,
+		contents: map[string]string{"US-001": "one", "US-002": "two"},
+	}
+	l := NewLoopWithWorkDir(prdPath, dir, "prompt", 1)
+	l.SetAgent(agent)
+	l.SetParallelism(2, "")
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var failed *Event
+	for i := range events {
+		if events[i].Type == EventStoryFailed {
+			failed = &events[i]
+		}
+	}
+	if failed == nil {
+		t.Fatal("Expected a StoryFailed event for the conflicting story")
+	}
+	if failed.StoryID != "US-002" || !strings.Contains(failed.Text, "conflicts in shared.txt") {
+		t.Errorf("Unexpected failure event: %s %q", failed.StoryID, failed.Text)
+	}
+
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		t.Fatalf("Failed to load PRD: %v", err)
+	}
+	if !p.UserStories[0].Passes || p.UserStories[1].Passes {
+		t.Errorf("Expected only US-001 to pass, got %+v", p.UserStories)
+	}
+	if p.UserStories[1].InProgress {
+		t.Error("Expected US-002 not to be left in progress")
+	}
+	if exists, _ := git.BranchExists(dir, "chief/feature-US-002"); exists {
+		t.Error("Expected the conflicting story branch to be removed")
+	}
+}
+
+func TestLoopParallelStoriesVerifiedBeforeMerge(t *testing.T) {
+	dir, prdPath := initParallelRepo(t, []prd.UserStory{
+		{ID: "US-001", Priority: 1},
+		{ID: "US-002", Priority: 2},
+	})
+
+	agent := &storyAgent{t: t, prdPath: prdPath}
+	l := NewLoopWithWorkDir(prdPath, dir, "prompt", 1)
+	l.SetAgent(agent)
+	l.SetParallelism(2, "")
+	// US-001.txt only exists in the PRD work dir once US-001 is merged
+	l.SetVerifyConfig(config.VerifyConfig{Stories: map[string][]string{
+		"US-001": {"test -f US-001.txt"},
+		"US-002": {"exit 1"},
+	}})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var merged []string
+	var failedVerification bool
+	for _, e := range events {
+		switch e.Type {
+		case EventStoryMerged:
+			merged = append(merged, e.StoryID)
+		case EventVerificationFailed:
+			if e.StoryID == "US-002" {
+				failedVerification = true
+			}
+		}
+	}
+	if strings.Join(merged, ",") != "US-001" {
+		t.Errorf("Expected only US-001 to be merged, got %v", merged)
+	}
+	if !failedVerification {
+		t.Error("Expected US-002 to fail verification")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "US-002.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected unverified US-002 not to be merged, got %v", err)
+	}
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		t.Fatalf("Failed to load PRD: %v", err)
+	}
+	if !p.UserStories[0].Passes || p.UserStories[1].Passes {
+		t.Errorf("Expected only US-001 to pass, got %+v", p.UserStories)
+	}
+	if l.verifyFeedback["US-002"] == "" {
+		t.Error("Expected US-002's verification failure to be fed into the next round")
+	}
+}
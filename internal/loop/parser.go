@@ -2,7 +2,9 @@ package loop
 
 import (
 	"encoding/json"
+	"errors"
 	"strings"
+	"time"
 )
 
 // EventType represents the type of event parsed from Claude's stream-json output.
@@ -31,6 +33,27 @@ const (
 	EventError
 	// EventRetrying is emitted when retrying after a crash.
 	EventRetrying
+	// EventIterationResult is emitted when the agent reports its final result
+	// for an iteration, including cost and token usage.
+	EventIterationResult
+	// EventBudgetExceeded is emitted when a cost, token or time budget stops the loop.
+	EventBudgetExceeded
+	// EventVerificationStarted is emitted when Chief starts verifying a newly passed story.
+	EventVerificationStarted
+	// EventVerificationPassed is emitted when a story's verification commands succeed.
+	EventVerificationPassed
+	// EventVerificationFailed is emitted when a verification command fails and the story is reverted.
+	EventVerificationFailed
+	// EventStoryMerged is emitted when a story worked on in parallel is merged into the PRD branch.
+	EventStoryMerged
+	// EventStoryFailed is emitted when a story worked on in parallel could not be completed or merged.
+	EventStoryFailed
+	// EventStoryBlocked is emitted when a story reaches the attempt cap without passing.
+	EventStoryBlocked
+	// EventAllStoriesBlocked is emitted when the loop stops because every remaining story is blocked.
+	EventAllStoriesBlocked
+	// EventNoProgress is emitted when the loop stops because several iterations in a row changed nothing.
+	EventNoProgress
 )
 
 // String returns the string representation of an EventType.
@@ -56,11 +79,42 @@ func (e EventType) String() string {
 		return "Error"
 	case EventRetrying:
 		return "Retrying"
+	case EventIterationResult:
+		return "IterationResult"
+	case EventBudgetExceeded:
+		return "BudgetExceeded"
+	case EventVerificationStarted:
+		return "VerificationStarted"
+	case EventVerificationPassed:
+		return "VerificationPassed"
+	case EventVerificationFailed:
+		return "VerificationFailed"
+	case EventStoryMerged:
+		return "StoryMerged"
+	case EventStoryFailed:
+		return "StoryFailed"
+	case EventStoryBlocked:
+		return "StoryBlocked"
+	case EventAllStoriesBlocked:
+		return "AllStoriesBlocked"
+	case EventNoProgress:
+		return "NoProgress"
 	default:
 		return "Unknown"
 	}
 }
 
+// ParseEventType returns the EventType with the given name, as returned by
+// String, or EventUnknown.
+func ParseEventType(name string) EventType {
+	for t := EventIterationStart; t.String() != "Unknown"; t++ {
+		if t.String() == name {
+			return t
+		}
+	}
+	return EventUnknown
+}
+
 // Event represents a parsed event from Claude's stream-json output.
 type Event struct {
 	Type       EventType
@@ -70,15 +124,23 @@ type Event struct {
 	ToolInput  map[string]interface{}
 	StoryID    string
 	Err        error
-	RetryCount int // Current retry attempt (1-based)
-	RetryMax   int // Maximum retries allowed
+	RetryCount int       // Current retry attempt (1-based)
+	RetryMax   int       // Maximum retries allowed
+	Time       time.Time // When the event was emitted by the loop
+	Usage      *Usage    // Cost and token usage (for EventIterationResult)
+	Reason     string    // Why the iteration is being retried (for EventRetrying)
+	ToolUseID  string    // Links a tool result to its tool call (for EventToolStart and EventToolResult)
+	SessionID  string    // Agent session ID (for EventIterationStart and EventRetrying)
+	Model      string    // Agent model (for EventIterationStart)
 }
 
 // streamMessage represents the top-level structure of a stream-json line.
 type streamMessage struct {
-	Type    string          `json:"type"`
-	Subtype string          `json:"subtype,omitempty"`
-	Message json.RawMessage `json:"message,omitempty"`
+	Type      string          `json:"type"`
+	Subtype   string          `json:"subtype,omitempty"`
+	SessionID string          `json:"session_id,omitempty"`
+	Model     string          `json:"model,omitempty"`
+	Message   json.RawMessage `json:"message,omitempty"`
 }
 
 // assistantMessage represents the structure of an assistant message.
@@ -102,14 +164,31 @@ type userMessage struct {
 
 // toolResultBlock represents a tool result in a user message.
 type toolResultBlock struct {
-	Type      string `json:"type"`
-	ToolUseID string `json:"tool_use_id"`
-	Content   string `json:"content"`
+	Type      string          `json:"type"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"` // A string or a list of content blocks
 }
 
-// ParseLine parses a single line of stream-json output and returns an Event.
-// If the line cannot be parsed or is not relevant, it returns nil.
-func ParseLine(line string) *Event {
+// resultMessage represents the final "result" message of a Claude run.
+type resultMessage struct {
+	Subtype      string  `json:"subtype"`
+	IsError      bool    `json:"is_error"`
+	Result       string  `json:"result"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	NumTurns     int     `json:"num_turns"`
+	DurationMs   int64   `json:"duration_ms"`
+	Usage        struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+}
+
+// ParseLine parses a single line of stream-json output and returns its events,
+// one per content block. If the line cannot be parsed or is not relevant, it
+// returns nil.
+func ParseLine(line string) []Event {
 	line = strings.TrimSpace(line)
 	if line == "" {
 		return nil
@@ -123,7 +202,7 @@ func ParseLine(line string) *Event {
 	switch msg.Type {
 	case "system":
 		if msg.Subtype == "init" {
-			return &Event{Type: EventIterationStart}
+			return []Event{{Type: EventIterationStart, SessionID: msg.SessionID, Model: msg.Model}}
 		}
 		return nil
 
@@ -134,17 +213,17 @@ func ParseLine(line string) *Event {
 		return parseUserMessage(msg.Message)
 
 	case "result":
-		// Result messages indicate the end of an iteration
-		// We don't emit a specific event for this, but could in the future
-		return nil
+		// Result messages indicate the end of an iteration and carry usage
+		return parseResultMessage(line)
 
 	default:
 		return nil
 	}
 }
 
-// parseAssistantMessage parses an assistant message and returns appropriate events.
-func parseAssistantMessage(raw json.RawMessage) *Event {
+// parseAssistantMessage parses an assistant message and returns an event for
+// each text and tool_use block, in order.
+func parseAssistantMessage(raw json.RawMessage) []Event {
 	if raw == nil {
 		return nil
 	}
@@ -154,47 +233,80 @@ func parseAssistantMessage(raw json.RawMessage) *Event {
 		return nil
 	}
 
-	// Process content blocks - return the first meaningful event
-	// In practice, we might want to return multiple events, but for simplicity
-	// we return the first one found
+	var events []Event
 	for _, block := range msg.Content {
 		switch block.Type {
 		case "text":
-			text := block.Text
-			// Check for <chief-complete/> tag
-			if strings.Contains(text, "<chief-complete/>") {
-				return &Event{
-					Type: EventComplete,
-					Text: text,
-				}
-			}
-			// Check for story markers using ralph-status tags
-			if storyID := extractStoryID(text, "<ralph-status>", "</ralph-status>"); storyID != "" {
-				return &Event{
-					Type:    EventStoryStarted,
-					Text:    text,
-					StoryID: storyID,
-				}
-			}
-			return &Event{
-				Type: EventAssistantText,
-				Text: text,
-			}
+			events = append(events, parseTextBlock(block.Text))
 
 		case "tool_use":
-			return &Event{
+			events = append(events, Event{
 				Type:      EventToolStart,
 				Tool:      block.Name,
 				ToolInput: block.Input,
-			}
+				ToolUseID: block.ID,
+			})
 		}
 	}
 
-	return nil
+	return events
 }
 
-// parseUserMessage parses a user message (typically tool results).
-func parseUserMessage(raw json.RawMessage) *Event {
+// parseTextBlock classifies a text block as completion, story start or plain text.
+func parseTextBlock(text string) Event {
+	// Check for <chief-complete/> tag
+	if strings.Contains(text, "<chief-complete/>") {
+		return Event{
+			Type: EventComplete,
+			Text: text,
+		}
+	}
+	// Check for story markers using ralph-status tags
+	if storyID := extractStoryID(text, "<ralph-status>", "</ralph-status>"); storyID != "" {
+		return Event{
+			Type:    EventStoryStarted,
+			Text:    text,
+			StoryID: storyID,
+		}
+	}
+	return Event{
+		Type: EventAssistantText,
+		Text: text,
+	}
+}
+
+// parseResultMessage parses the final result message of an iteration.
+func parseResultMessage(line string) []Event {
+	var msg resultMessage
+	if err := json.Unmarshal([]byte(line), &msg); err != nil {
+		return nil
+	}
+
+	// An error result carries the API error, used to classify the failure
+	var resultErr error
+	if msg.IsError && msg.Result != "" {
+		resultErr = errors.New(msg.Result)
+	}
+
+	return []Event{{
+		Type: EventIterationResult,
+		Text: msg.Subtype,
+		Err:  resultErr,
+		Usage: &Usage{
+			CostUSD:             msg.TotalCostUSD,
+			InputTokens:         msg.Usage.InputTokens,
+			OutputTokens:        msg.Usage.OutputTokens,
+			CacheCreationTokens: msg.Usage.CacheCreationInputTokens,
+			CacheReadTokens:     msg.Usage.CacheReadInputTokens,
+			NumTurns:            msg.NumTurns,
+			DurationMs:          msg.DurationMs,
+			Iterations:          1,
+		},
+	}}
+}
+
+// parseUserMessage parses a user message and returns an event for each tool result.
+func parseUserMessage(raw json.RawMessage) []Event {
 	if raw == nil {
 		return nil
 	}
@@ -204,16 +316,43 @@ func parseUserMessage(raw json.RawMessage) *Event {
 		return nil
 	}
 
+	var events []Event
 	for _, block := range msg.Content {
 		if block.Type == "tool_result" {
-			return &Event{
-				Type: EventToolResult,
-				Text: block.Content,
-			}
+			events = append(events, Event{
+				Type:      EventToolResult,
+				Text:      toolResultText(block.Content),
+				ToolUseID: block.ToolUseID,
+			})
 		}
 	}
 
-	return nil
+	return events
+}
+
+// toolResultText extracts the text of a tool result, whose content is either
+// a string or a list of content blocks.
+func toolResultText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
 }
 
 // extractStoryID extracts a story ID from text between start and end tags.
@@ -20,6 +20,9 @@ func TestEventTypeString(t *testing.T) {
 		{EventMaxIterationsReached, "MaxIterationsReached"},
 		{EventError, "Error"},
 		{EventRetrying, "Retrying"},
+		{EventIterationResult, "IterationResult"},
+		{EventStoryMerged, "StoryMerged"},
+		{EventStoryFailed, "StoryFailed"},
 	}
 
 	for _, tt := range tests {
@@ -61,15 +64,18 @@ func TestParseLineInvalidJSON(t *testing.T) {
 }
 
 func TestParseLineSystemInit(t *testing.T) {
-	line := `{"type":"system","subtype":"init","cwd":"/test","session_id":"abc123","tools":["Read","Write"]}`
+	line := `{"type":"system","subtype":"init","cwd":"/test","session_id":"abc123","model":"claude-sonnet","tools":["Read","Write"]}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
-	}
+	event := parseOne(t, line)
 	if event.Type != EventIterationStart {
 		t.Errorf("event.Type = %v, want EventIterationStart", event.Type)
 	}
+	if event.SessionID != "abc123" {
+		t.Errorf("event.SessionID = %q, want %q", event.SessionID, "abc123")
+	}
+	if event.Model != "claude-sonnet" {
+		t.Errorf("event.Model = %q, want %q", event.Model, "claude-sonnet")
+	}
 }
 
 func TestParseLineSystemOtherSubtype(t *testing.T) {
@@ -84,10 +90,7 @@ func TestParseLineSystemOtherSubtype(t *testing.T) {
 func TestParseLineAssistantText(t *testing.T) {
 	line := `{"type":"assistant","message":{"content":[{"type":"text","text":"Hello, I will help you."}]}}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
-	}
+	event := parseOne(t, line)
 	if event.Type != EventAssistantText {
 		t.Errorf("event.Type = %v, want EventAssistantText", event.Type)
 	}
@@ -104,10 +107,7 @@ func TestParseLineChiefComplete(t *testing.T) {
 	}
 
 	for _, line := range tests {
-		event := ParseLine(line)
-		if event == nil {
-			t.Fatalf("ParseLine(%q) returned nil, want event", line)
-		}
+		event := parseOne(t, line)
 		if event.Type != EventComplete {
 			t.Errorf("ParseLine(%q): event.Type = %v, want EventComplete", line, event.Type)
 		}
@@ -117,10 +117,7 @@ func TestParseLineChiefComplete(t *testing.T) {
 func TestParseLineToolUse(t *testing.T) {
 	line := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"toolu_123","name":"Read","input":{"file_path":"/test/file.go"}}]}}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
-	}
+	event := parseOne(t, line)
 	if event.Type != EventToolStart {
 		t.Errorf("event.Type = %v, want EventToolStart", event.Type)
 	}
@@ -138,10 +135,7 @@ func TestParseLineToolUse(t *testing.T) {
 func TestParseLineToolResult(t *testing.T) {
 	line := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_123","content":"File contents here"}]}}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
-	}
+	event := parseOne(t, line)
 	if event.Type != EventToolResult {
 		t.Errorf("event.Type = %v, want EventToolResult", event.Type)
 	}
@@ -153,10 +147,7 @@ func TestParseLineToolResult(t *testing.T) {
 func TestParseLineStoryStarted(t *testing.T) {
 	line := `{"type":"assistant","message":{"content":[{"type":"text","text":"Working on the next story.\n<ralph-status>US-003</ralph-status>\nLet me implement this."}]}}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
-	}
+	event := parseOne(t, line)
 	if event.Type != EventStoryStarted {
 		t.Errorf("event.Type = %v, want EventStoryStarted", event.Type)
 	}
@@ -168,10 +159,52 @@ func TestParseLineStoryStarted(t *testing.T) {
 func TestParseLineResultMessage(t *testing.T) {
 	line := `{"type":"result","subtype":"success","is_error":false,"result":"Done"}`
 
-	event := ParseLine(line)
-	// Result messages are not emitted as events
-	if event != nil {
-		t.Errorf("ParseLine returned %v, want nil for result message", event)
+	event := parseOne(t, line)
+	if event.Type != EventIterationResult {
+		t.Errorf("event.Type = %v, want %v", event.Type, EventIterationResult)
+	}
+	if event.Usage == nil || !(*event.Usage == Usage{Iterations: 1}) {
+		t.Errorf("event.Usage = %+v, want empty usage for one iteration", event.Usage)
+	}
+}
+
+func TestParseLineResultMessageUsage(t *testing.T) {
+	line := `{"type":"result","subtype":"success","is_error":false,"duration_ms":65432,"num_turns":12,"result":"Done","session_id":"abc","total_cost_usd":0.4321,"usage":{"input_tokens":120,"cache_creation_input_tokens":3000,"cache_read_input_tokens":45000,"output_tokens":2100}}`
+
+	event := parseOne(t, line)
+	if event.Usage == nil {
+		t.Fatal("ParseLine returned no usage for result message")
+	}
+
+	want := Usage{
+		CostUSD:             0.4321,
+		InputTokens:         120,
+		OutputTokens:        2100,
+		CacheCreationTokens: 3000,
+		CacheReadTokens:     45000,
+		NumTurns:            12,
+		DurationMs:          65432,
+		Iterations:          1,
+	}
+	if *event.Usage != want {
+		t.Errorf("event.Usage = %+v, want %+v", *event.Usage, want)
+	}
+	if event.Text != "success" {
+		t.Errorf("event.Text = %q, want subtype %q", event.Text, "success")
+	}
+}
+
+func TestParseLineResultMessageError(t *testing.T) {
+	line := `{"type":"result","subtype":"success","is_error":true,"result":"Claude AI usage limit reached|1748800000"}`
+
+	event := parseOne(t, line)
+	if event.Err == nil || event.Err.Error() != "Claude AI usage limit reached|1748800000" {
+		t.Errorf("event.Err = %v, want the error result", event.Err)
+	}
+
+	event = parseOne(t, `{"type":"result","subtype":"success","is_error":false,"result":"Done"}`)
+	if event.Err != nil {
+		t.Errorf("event.Err = %v, want nil for a successful result", event.Err)
 	}
 }
 
@@ -197,10 +230,7 @@ func TestParseLineRealWorldSystemInit(t *testing.T) {
 	// Real example from Claude output
 	line := `{"type":"system","subtype":"init","cwd":"/Users/codemonkey/projects/chief","session_id":"7cdf33f6-72ec-4c0e-94fb-e2b637e109da","tools":["Task","Bash","Read"],"model":"claude-opus-4-5-20251101","permissionMode":"default"}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
-	}
+	event := parseOne(t, line)
 	if event.Type != EventIterationStart {
 		t.Errorf("event.Type = %v, want EventIterationStart", event.Type)
 	}
@@ -210,10 +240,7 @@ func TestParseLineRealWorldToolUse(t *testing.T) {
 	// Real example from Claude output
 	line := `{"type":"assistant","message":{"model":"claude-opus-4-5-20251101","id":"msg_01XPBzHqPaCuQMUFm77eHe4D","type":"message","role":"assistant","content":[{"type":"tool_use","id":"toolu_01MiR5Ps9inHigemS2gxEz5R","name":"Read","input":{"file_path":"/Users/codemonkey/projects/chief/go.mod"}}]}}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
-	}
+	event := parseOne(t, line)
 	if event.Type != EventToolStart {
 		t.Errorf("event.Type = %v, want EventToolStart", event.Type)
 	}
@@ -272,36 +299,70 @@ func TestExtractStoryID(t *testing.T) {
 }
 
 func TestParseLineMultipleContentBlocks(t *testing.T) {
-	// When there are multiple content blocks, we return the first meaningful one
-	// This tests that text comes before tool_use in the content array
-	line := `{"type":"assistant","message":{"content":[{"type":"text","text":"First"},{"type":"tool_use","name":"Read","input":{}}]}}`
+	// Every content block produces an event, in order
+	line := `{"type":"assistant","message":{"content":[{"type":"text","text":"First"},{"type":"tool_use","id":"toolu_1","name":"Read","input":{}},{"type":"tool_use","id":"toolu_2","name":"Edit","input":{"file_path":"/a.go"}}]}}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
+	events := ParseLine(line)
+	if len(events) != 3 {
+		t.Fatalf("ParseLine returned %d events, want 3", len(events))
 	}
-	// Should return the text event since it comes first
-	if event.Type != EventAssistantText {
-		t.Errorf("event.Type = %v, want EventAssistantText", event.Type)
+	if events[0].Type != EventAssistantText || events[0].Text != "First" {
+		t.Errorf("events[0] = %+v, want AssistantText %q", events[0], "First")
+	}
+	if events[1].Type != EventToolStart || events[1].Tool != "Read" || events[1].ToolUseID != "toolu_1" {
+		t.Errorf("events[1] = %+v, want Read tool start toolu_1", events[1])
 	}
-	if event.Text != "First" {
-		t.Errorf("event.Text = %q, want %q", event.Text, "First")
+	if events[2].Type != EventToolStart || events[2].Tool != "Edit" || events[2].ToolUseID != "toolu_2" {
+		t.Errorf("events[2] = %+v, want Edit tool start toolu_2", events[2])
 	}
 }
 
 func TestParseLineToolUseFirst(t *testing.T) {
-	// When tool_use comes first
+	// When tool_use comes first, the text after it is still emitted
 	line := `{"type":"assistant","message":{"content":[{"type":"tool_use","name":"Write","input":{"file_path":"/test"}},{"type":"text","text":"Second"}]}}`
 
-	event := ParseLine(line)
-	if event == nil {
-		t.Fatal("ParseLine returned nil, want event")
+	events := ParseLine(line)
+	if len(events) != 2 {
+		t.Fatalf("ParseLine returned %d events, want 2", len(events))
 	}
-	// Should return the tool_use event since it comes first
-	if event.Type != EventToolStart {
-		t.Errorf("event.Type = %v, want EventToolStart", event.Type)
+	if events[0].Type != EventToolStart || events[0].Tool != "Write" {
+		t.Errorf("events[0] = %+v, want Write tool start", events[0])
 	}
-	if event.Tool != "Write" {
-		t.Errorf("event.Tool = %q, want %q", event.Tool, "Write")
+	if events[1].Type != EventAssistantText || events[1].Text != "Second" {
+		t.Errorf("events[1] = %+v, want AssistantText %q", events[1], "Second")
+	}
+}
+
+func TestParseLineSkipsThinkingBlocks(t *testing.T) {
+	line := `{"type":"assistant","message":{"content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"Done thinking"}]}}`
+
+	event := parseOne(t, line)
+	if event.Type != EventAssistantText || event.Text != "Done thinking" {
+		t.Errorf("event = %+v, want AssistantText %q", event, "Done thinking")
+	}
+}
+
+func TestParseLineMultipleToolResults(t *testing.T) {
+	line := `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"one"},{"type":"tool_result","tool_use_id":"toolu_2","content":[{"type":"text","text":"two"},{"type":"text","text":"lines"}]}]}}`
+
+	events := ParseLine(line)
+	if len(events) != 2 {
+		t.Fatalf("ParseLine returned %d events, want 2", len(events))
+	}
+	if events[0].Text != "one" || events[0].ToolUseID != "toolu_1" {
+		t.Errorf("events[0] = %+v, want result %q for toolu_1", events[0], "one")
+	}
+	if events[1].Text != "two\nlines" || events[1].ToolUseID != "toolu_2" {
+		t.Errorf("events[1] = %+v, want block content joined for toolu_2", events[1])
+	}
+}
+
+// parseOne parses a line that must produce exactly one event.
+func parseOne(t *testing.T, line string) Event {
+	t.Helper()
+	events := ParseLine(line)
+	if len(events) != 1 {
+		t.Fatalf("ParseLine(%q) returned %d events, want 1", line, len(events))
 	}
+	return events[0]
 }
@@ -0,0 +1,122 @@
+package loop
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+// Built-in permission profile names.
+const (
+	// ProfileDefault is used when the config doesn't select a profile.
+	ProfileDefault = "default"
+	// ProfileUnrestricted is reported when permission checks are skipped.
+	ProfileUnrestricted = "unrestricted"
+)
+
+// defaultAllowedTools are the tools of the built-in default profile: reading
+// and editing files and running shell commands, but no web or MCP tools.
+var defaultAllowedTools = []string{
+	"Read", "Glob", "Grep", "LS",
+	"Edit", "MultiEdit", "Write", "NotebookEdit",
+	"TodoWrite", "Task", "Bash",
+}
+
+// Permissions is the permission profile the agent runs under.
+type Permissions struct {
+	Profile         string   // Profile name, shown in the TUI
+	SkipAll         bool     // Run without permission checks (--dangerously-skip-permissions)
+	AllowedTools    []string // Tools the agent may use
+	DisallowedTools []string // Tools the agent may never use
+	BashCommands    []string // Allowed Bash command patterns
+	WritablePaths   []string // Paths the agent may edit and write
+}
+
+// DefaultPermissions returns the built-in default profile.
+func DefaultPermissions() Permissions {
+	return Permissions{
+		Profile:      ProfileDefault,
+		AllowedTools: append([]string(nil), defaultAllowedTools...),
+	}
+}
+
+// NewPermissions returns the permission profile selected by the config.
+// Permission checks are only skipped when the config explicitly opts in.
+func NewPermissions(cfg config.PermissionsConfig) (Permissions, error) {
+	if cfg.SkipPermissions {
+		if cfg.Profile != "" {
+			return Permissions{}, fmt.Errorf("permissions.profile %q can't be combined with permissions.skipPermissions", cfg.Profile)
+		}
+		return Permissions{Profile: ProfileUnrestricted, SkipAll: true}, nil
+	}
+
+	name := cfg.Profile
+	if name == "" {
+		name = ProfileDefault
+	}
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		if name == ProfileDefault {
+			return DefaultPermissions(), nil
+		}
+		return Permissions{}, fmt.Errorf("unknown permission profile %q", name)
+	}
+
+	return Permissions{
+		Profile:         name,
+		AllowedTools:    profile.AllowedTools,
+		DisallowedTools: profile.DisallowedTools,
+		BashCommands:    profile.Bash,
+		WritablePaths:   profile.WritablePaths,
+	}, nil
+}
+
+// writeTools are the tools that edit files. Allowed without a path, they
+// can write anywhere.
+var writeTools = []string{"Edit", "MultiEdit", "Write", "NotebookEdit"}
+
+// claudeArgs translates the profile into Claude Code's permission flags. The
+// PRD directory is always writable so the agent can update prd.json and
+// progress.md, even when it lives outside the work dir. With writable paths,
+// the write tools are only allowed for those paths.
+func (p Permissions) claudeArgs(prdDir string) []string {
+	if p.SkipAll {
+		return []string{"--dangerously-skip-permissions"}
+	}
+
+	var allowed []string
+	for _, tool := range p.AllowedTools {
+		if len(p.WritablePaths) > 0 && slices.Contains(writeTools, tool) {
+			continue
+		}
+		allowed = append(allowed, tool)
+	}
+	for _, pattern := range p.BashCommands {
+		allowed = append(allowed, "Bash("+pattern+")")
+	}
+	writable := append([]string(nil), p.WritablePaths...)
+	if prdDir != "" {
+		if abs, err := filepath.Abs(prdDir); err == nil {
+			prdDir = abs
+		}
+		// A leading "//" marks an absolute path in Claude's permission rules
+		writable = append(writable, "/"+filepath.ToSlash(prdDir)+"/**")
+	}
+	for _, path := range writable {
+		allowed = append(allowed, "Edit("+path+")", "Write("+path+")")
+	}
+
+	var args []string
+	if prdDir != "" {
+		args = append(args, "--add-dir", prdDir)
+	}
+	for _, tool := range allowed {
+		args = append(args, "--allowedTools", tool)
+	}
+	for _, tool := range p.DisallowedTools {
+		args = append(args, "--disallowedTools", tool)
+	}
+	return args
+}
@@ -0,0 +1,167 @@
+package loop
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+func TestNewPermissions(t *testing.T) {
+	profiles := map[string]config.PermissionProfile{
+		"restricted": {AllowedTools: []string{"Read"}, Bash: []string{"npm test"}},
+	}
+
+	tests := []struct {
+		name        string
+		cfg         config.PermissionsConfig
+		wantProfile string
+		wantSkip    bool
+		wantErr     bool
+	}{
+		{name: "no config uses the built-in default", cfg: config.PermissionsConfig{}, wantProfile: ProfileDefault},
+		{name: "named profile", cfg: config.PermissionsConfig{Profile: "restricted", Profiles: profiles}, wantProfile: "restricted"},
+		{name: "unknown profile", cfg: config.PermissionsConfig{Profile: "missing", Profiles: profiles}, wantErr: true},
+		{name: "explicit opt-in skips checks", cfg: config.PermissionsConfig{SkipPermissions: true}, wantProfile: ProfileUnrestricted, wantSkip: true},
+		{name: "opt-in with a profile", cfg: config.PermissionsConfig{Profile: "restricted", SkipPermissions: true, Profiles: profiles}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPermissions(tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewPermissions returned error: %v", err)
+			}
+			if p.Profile != tt.wantProfile || p.SkipAll != tt.wantSkip {
+				t.Errorf("Expected profile %q (skip %v), got %q (skip %v)", tt.wantProfile, tt.wantSkip, p.Profile, p.SkipAll)
+			}
+		})
+	}
+}
+
+func TestNewPermissions_OverrideDefault(t *testing.T) {
+	p, err := NewPermissions(config.PermissionsConfig{
+		Profiles: map[string]config.PermissionProfile{"default": {AllowedTools: []string{"Read"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewPermissions returned error: %v", err)
+	}
+	if strings.Join(p.AllowedTools, ",") != "Read" {
+		t.Errorf("Expected the configured default profile, got %v", p.AllowedTools)
+	}
+}
+
+func TestPermissions_ClaudeArgs(t *testing.T) {
+	args := strings.Join(Permissions{SkipAll: true}.claudeArgs("/prds/main"), " ")
+	if args != "--dangerously-skip-permissions" {
+		t.Errorf("Expected only --dangerously-skip-permissions, got: %s", args)
+	}
+
+	p := Permissions{
+		Profile:         "restricted",
+		AllowedTools:    []string{"Read", "Grep"},
+		DisallowedTools: []string{"WebFetch"},
+		BashCommands:    []string{"npm test", "git commit:*"},
+		WritablePaths:   []string{"src/**"},
+	}
+	args = strings.Join(p.claudeArgs("/prds/main"), " ")
+	for _, want := range []string{
+		"--add-dir /prds/main",
+		"--allowedTools Read",
+		"--allowedTools Grep",
+		"--allowedTools Bash(npm test)",
+		"--allowedTools Bash(git commit:*)",
+		"--allowedTools Edit(src/**)",
+		"--allowedTools Write(src/**)",
+		"--allowedTools Edit(//prds/main/**)",
+		"--disallowedTools WebFetch",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Expected %q in args: %s", want, args)
+		}
+	}
+	if strings.Contains(args, "--dangerously-skip-permissions") {
+		t.Errorf("Expected permission checks to stay on, got: %s", args)
+	}
+}
+
+func TestPermissions_ClaudeArgsScopeWrites(t *testing.T) {
+	// The default profile's plain write tools would allow writes anywhere
+	p := DefaultPermissions()
+	p.WritablePaths = []string{"src/**"}
+	args := p.claudeArgs("/prds/main")
+	for i, arg := range args {
+		if arg == "--allowedTools" && slices.Contains(writeTools, args[i+1]) {
+			t.Errorf("Expected no unscoped %s with writable paths, got: %v", args[i+1], args)
+		}
+	}
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"--allowedTools Edit(src/**)", "--allowedTools Write(src/**)", "--allowedTools Read", "--allowedTools Bash"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in args: %s", want, joined)
+		}
+	}
+
+	// Without writable paths the profile's tools are passed as they are
+	if args := DefaultPermissions().claudeArgs(""); !slices.Contains(args, "Edit") {
+		t.Errorf("Expected plain Edit without writable paths, got: %v", args)
+	}
+}
+
+func TestClaudeAgent_ArgsPermissions(t *testing.T) {
+	agent := &ClaudeAgent{}
+
+	args := strings.Join(agent.args(AgentRequest{Prompt: "p", PRDDir: "/prds/main", Permissions: DefaultPermissions()}), " ")
+	if strings.Contains(args, "--dangerously-skip-permissions") {
+		t.Errorf("Expected the default profile not to skip permissions, got: %s", args)
+	}
+	if !strings.Contains(args, "--allowedTools Bash") || !strings.Contains(args, "--add-dir /prds/main") {
+		t.Errorf("Expected the default profile's tools and the PRD dir, got: %s", args)
+	}
+}
+
+func TestLoop_PRDCannotOverridePermissions(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	// The agent grants itself every permission through prd.json
+	agent := &fakeAgent{runs: []fakeRun{
+		{onStart: func() {
+			data, err := os.ReadFile(prdPath)
+			if err != nil {
+				t.Errorf("Failed to read PRD: %v", err)
+				return
+			}
+			var raw map[string]any
+			json.Unmarshal(data, &raw)
+			raw["agentArgs"] = []string{"--dangerously-skip-permissions"}
+			data, _ = json.Marshal(raw)
+			os.WriteFile(prdPath, data, 0644)
+		}},
+		{},
+	}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 3)
+	l.SetAgent(agent)
+	l.DisableRetry()
+	l.SetPermissions(DefaultPermissions())
+
+	if _, err := collectEvents(t, l); err == nil || !strings.Contains(err.Error(), "agentArgs can't contain --dangerously-skip-permissions") {
+		t.Errorf("Expected the run to stop on the permission flag, got %v", err)
+	}
+	for _, req := range agent.Requests() {
+		args := append(req.Permissions.claudeArgs(req.PRDDir), req.ExtraArgs...)
+		if slices.Contains(args, "--dangerously-skip-permissions") {
+			t.Errorf("Expected the profile to hold, got args %v", args)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package loop
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, which has no process groups to kill.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills p; on Windows its children are left running.
+func killProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,140 @@
+package loop
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// promptFile is the name of prompt template overrides, both in .chief/ and in
+// a PRD's directory.
+const promptFile = "prompt.md"
+
+// PromptData holds the variables available to prompt templates.
+type PromptData struct {
+	PRDPath         string         // Path to prd.json
+	PRDDir          string         // Directory holding prd.json, progress.md and claude.log
+	ProgressPath    string         // Path to progress.md
+	WorkDir         string         // Directory the agent runs in
+	Iteration       int            // Current iteration, starting at 1
+	Project         string         // Project name from prd.json
+	Story           *prd.UserStory // Next story to work on (nil when none is ready)
+	PreviousFailure string         // Error from the previous attempt at this iteration, if it failed
+}
+
+// NewPromptData builds the template variables for an iteration of a PRD.
+func NewPromptData(prdPath, workDir string, iteration int) PromptData {
+	data := PromptData{
+		PRDPath:      prdPath,
+		PRDDir:       filepath.Dir(prdPath),
+		ProgressPath: prd.ProgressPath(prdPath),
+		WorkDir:      workDir,
+		Iteration:    iteration,
+	}
+	if p, err := prd.LoadPRD(prdPath); err == nil {
+		data.Project = p.Project
+		data.Story = p.NextStory()
+	}
+	return data
+}
+
+// PromptTemplate is a project-supplied agent prompt, rendered with text/template.
+type PromptTemplate struct {
+	Path string // File the template was loaded from
+	tmpl *template.Template
+}
+
+// LoadPromptTemplate loads the prompt override for a PRD: prompt.md in the PRD
+// directory, or else .chief/prompt.md in baseDir. It returns nil when neither
+// exists, in which case the built-in prompt is used.
+func LoadPromptTemplate(baseDir, prdPath string) (*PromptTemplate, error) {
+	paths := []string{filepath.Join(filepath.Dir(prdPath), promptFile)}
+	if baseDir != "" {
+		paths = append(paths, filepath.Join(baseDir, ".chief", promptFile))
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		return ParsePromptTemplate(path, string(data))
+	}
+	return nil, nil
+}
+
+// TemplateError is returned when a prompt template fails to render. The
+// template renders the same way on every attempt, so it isn't retried.
+type TemplateError struct {
+	Path string // File the template was loaded from
+	Err  error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("failed to render prompt template %s: %v", e.Path, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// samplePromptData is used to check templates when they are parsed, so that
+// unknown variables are reported before the loop starts.
+var samplePromptData = PromptData{
+	PRDPath:      filepath.Join(".chief", "prds", "sample", "prd.json"),
+	PRDDir:       filepath.Join(".chief", "prds", "sample"),
+	ProgressPath: filepath.Join(".chief", "prds", "sample", "progress.md"),
+	WorkDir:      ".",
+	Iteration:    1,
+	Project:      "Sample",
+	Story:        &prd.UserStory{ID: "US-001", Title: "Sample story"},
+}
+
+// ParsePromptTemplate parses a prompt template and checks that it renders
+// with sample variables. The path is used in error messages.
+func ParsePromptTemplate(path, text string) (*PromptTemplate, error) {
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+	if err := tmpl.Execute(io.Discard, samplePromptData); err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+	return &PromptTemplate{Path: path, tmpl: tmpl}, nil
+}
+
+// Render executes the template with the given variables.
+func (t *PromptTemplate) Render(data PromptData) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", &TemplateError{Path: t.Path, Err: err}
+	}
+	return b.String(), nil
+}
+
+// basePrompt returns the prompt for the current iteration: the rendered
+// project template if one is set, otherwise the built-in prompt.
+func (l *Loop) basePrompt() (string, error) {
+	l.mu.Lock()
+	tmpl := l.promptTemplate
+	prompt := l.prompt
+	iter := l.iteration
+	failure := l.lastFailure
+	workDir := l.effectiveWorkDir()
+	l.mu.Unlock()
+
+	if tmpl == nil {
+		return prompt, nil
+	}
+	data := NewPromptData(l.prdPath, workDir, iter)
+	data.PreviousFailure = failure
+	return tmpl.Render(data)
+}
@@ -0,0 +1,174 @@
+package loop
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPromptTemplate(t *testing.T) {
+	baseDir := t.TempDir()
+	prdDir := filepath.Join(baseDir, ".chief", "prds", "main")
+	if err := os.MkdirAll(prdDir, 0755); err != nil {
+		t.Fatalf("Failed to create PRD dir: %v", err)
+	}
+	prdPath := filepath.Join(prdDir, "prd.json")
+
+	tmpl, err := LoadPromptTemplate(baseDir, prdPath)
+	if err != nil {
+		t.Fatalf("LoadPromptTemplate returned error: %v", err)
+	}
+	if tmpl != nil {
+		t.Fatalf("Expected no template without prompt.md, got %s", tmpl.Path)
+	}
+
+	projectPath := filepath.Join(baseDir, ".chief", "prompt.md")
+	if err := os.WriteFile(projectPath, []byte("project"), 0644); err != nil {
+		t.Fatalf("Failed to write prompt.md: %v", err)
+	}
+	tmpl, err = LoadPromptTemplate(baseDir, prdPath)
+	if err != nil {
+		t.Fatalf("LoadPromptTemplate returned error: %v", err)
+	}
+	if tmpl == nil || tmpl.Path != projectPath {
+		t.Fatalf("Expected the project template, got %+v", tmpl)
+	}
+
+	// The PRD's own prompt.md takes precedence
+	prdPromptPath := filepath.Join(prdDir, "prompt.md")
+	if err := os.WriteFile(prdPromptPath, []byte("prd"), 0644); err != nil {
+		t.Fatalf("Failed to write prompt.md: %v", err)
+	}
+	tmpl, err = LoadPromptTemplate(baseDir, prdPath)
+	if err != nil {
+		t.Fatalf("LoadPromptTemplate returned error: %v", err)
+	}
+	if tmpl == nil || tmpl.Path != prdPromptPath {
+		t.Fatalf("Expected the PRD template, got %+v", tmpl)
+	}
+}
+
+func TestLoadPromptTemplate_Invalid(t *testing.T) {
+	baseDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(baseDir, ".chief"), 0755); err != nil {
+		t.Fatalf("Failed to create .chief: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, ".chief", "prompt.md"), []byte("{{.Story.ID"), 0644); err != nil {
+		t.Fatalf("Failed to write prompt.md: %v", err)
+	}
+
+	_, err := LoadPromptTemplate(baseDir, filepath.Join(baseDir, ".chief", "prds", "main", "prd.json"))
+	if err == nil {
+		t.Fatal("Expected an error for an invalid template")
+	}
+	if !strings.Contains(err.Error(), "invalid prompt template") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestPromptTemplate_Render(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	tmpl, err := ParsePromptTemplate("prompt.md", "{{.Project}} #{{.Iteration}}: {{.Story.ID}} {{.Story.Title}} in {{.WorkDir}}, log {{.ProgressPath}}{{if .PreviousFailure}} after {{.PreviousFailure}}{{end}}")
+	if err != nil {
+		t.Fatalf("ParsePromptTemplate returned error: %v", err)
+	}
+
+	data := NewPromptData(prdPath, "/work", 2)
+	data.PreviousFailure = "exit status 1"
+	got, err := tmpl.Render(data)
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	want := "Test Project #2: US-001 Test Story in /work, log " + filepath.Join(tmpDir, "progress.md") + " after exit status 1"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Unknown variables are reported when the template is parsed rather than
+	// rendered as "<no value>"
+	if _, err := ParsePromptTemplate("prompt.md", "{{.Unknown}}"); err == nil || !strings.Contains(err.Error(), "invalid prompt template prompt.md") {
+		t.Errorf("Expected an error for an unknown variable, got %v", err)
+	}
+}
+
+func TestLoop_PromptTemplateErrorNotRetried(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	// The sample data doesn't reach the unknown variable, the PRD's does
+	tmpl, err := ParsePromptTemplate(filepath.Join(tmpDir, "prompt.md"), `{{if eq .Project "Test Project"}}{{.Unknown}}{{end}}`)
+	if err != nil {
+		t.Fatalf("ParsePromptTemplate returned error: %v", err)
+	}
+
+	agent := &fakeAgent{}
+	l := NewLoop(prdPath, "built-in prompt", 3)
+	l.SetAgent(agent)
+	l.SetPromptTemplate(tmpl)
+	l.SetRetryConfig(RetryConfig{MaxRetries: 3, Enabled: true, BaseDelay: time.Millisecond})
+
+	events, err := collectEvents(t, l)
+	var templateErr *TemplateError
+	if !errors.As(err, &templateErr) {
+		t.Fatalf("Expected a template error, got %v", err)
+	}
+
+	var retried bool
+	var failure *Event
+	for i := range events {
+		switch events[i].Type {
+		case EventRetrying:
+			retried = true
+		case EventError:
+			failure = &events[i]
+		}
+	}
+	if retried {
+		t.Error("Expected a template error not to be retried")
+	}
+	if failure == nil || !strings.Contains(failure.Err.Error(), filepath.Join(tmpDir, "prompt.md")) {
+		t.Errorf("Expected an error event naming the template, got %+v", failure)
+	}
+	if len(agent.Requests()) != 0 {
+		t.Errorf("Expected the agent not to run, got %d runs", len(agent.Requests()))
+	}
+}
+
+func TestLoop_RunUsesPromptTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	tmpl, err := ParsePromptTemplate("prompt.md", "Work on {{.Story.ID}} (iteration {{.Iteration}}){{if .PreviousFailure}}, last attempt: {{.PreviousFailure}}{{end}}")
+	if err != nil {
+		t.Fatalf("ParsePromptTemplate returned error: %v", err)
+	}
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{waitErr: errors.New("exit status 1")},
+		{onStart: markAllPassed(t, prdPath)},
+	}}
+	l := NewLoop(prdPath, "built-in prompt", 3)
+	l.SetAgent(agent)
+	l.SetPromptTemplate(tmpl)
+	l.SetRetryConfig(RetryConfig{MaxRetries: 1, Enabled: true})
+
+	if _, err := collectEvents(t, l); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	requests := agent.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 agent runs, got %d", len(requests))
+	}
+	if requests[0].Prompt != "Work on US-001 (iteration 1)" {
+		t.Errorf("Unexpected first prompt: %q", requests[0].Prompt)
+	}
+	if !strings.HasPrefix(requests[1].Prompt, "Work on US-001 (iteration 1), last attempt: ") {
+		t.Errorf("Expected the retry prompt to include the previous failure, got %q", requests[1].Prompt)
+	}
+}
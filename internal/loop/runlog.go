@@ -0,0 +1,388 @@
+package loop
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+// Files kept for each run, next to its events.jsonl.
+const (
+	runLogFile = "claude.log" // Raw agent output
+	indexFile  = "index.json" // Index of the runs in runs/
+)
+
+// RunLogPath returns the path of a run's claude.log.
+func RunLogPath(prdPath string, run int) string {
+	return filepath.Join(RunDir(prdPath, run), runLogFile)
+}
+
+// RunStoryLogPath returns the path of the claude.log a story writes when it
+// is worked on in parallel during a run.
+func RunStoryLogPath(prdPath string, run int, storyID string) string {
+	return filepath.Join(RunDir(prdPath, run), "stories", storyID, runLogFile)
+}
+
+// RunIndexPath returns the path of the index of a PRD's runs.
+func RunIndexPath(prdPath string) string {
+	return filepath.Join(RunsDir(prdPath), indexFile)
+}
+
+// RunInfo describes a recorded run in the runs index.
+type RunInfo struct {
+	ID         int       `json:"id"`
+	Started    time.Time `json:"started"`
+	Ended      time.Time `json:"ended,omitzero"`
+	Iterations int       `json:"iterations,omitempty"`
+	Stories    []string  `json:"stories,omitempty"`    // Stories worked on
+	Size       int64     `json:"size,omitempty"`       // Bytes the run takes up on disk
+	Compressed bool      `json:"compressed,omitempty"` // The run's logs are gzipped
+}
+
+// indexMu serializes updates to runs indexes within the process.
+var indexMu sync.Mutex
+
+// ReadRunIndex reads the index of a PRD's runs, oldest first. Runs recorded
+// before the index existed are missing from it.
+func ReadRunIndex(prdPath string) ([]RunInfo, error) {
+	data, err := os.ReadFile(RunIndexPath(prdPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var index struct {
+		Runs []RunInfo `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", RunIndexPath(prdPath), err)
+	}
+	return index.Runs, nil
+}
+
+// updateRunIndex applies fn to the runs index and writes it back.
+func updateRunIndex(prdPath string, fn func([]RunInfo) []RunInfo) error {
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	runs, err := ReadRunIndex(prdPath)
+	if err != nil {
+		// Rebuild a damaged index rather than failing the run
+		runs = nil
+	}
+	runs = fn(runs)
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+
+	data, err := json.MarshalIndent(struct {
+		Runs []RunInfo `json:"runs"`
+	}{runs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write atomically so readers never see half an index
+	tmp := RunIndexPath(prdPath) + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write runs index: %w", err)
+	}
+	return os.Rename(tmp, RunIndexPath(prdPath))
+}
+
+// setRunInfo adds or replaces a run in the runs index.
+func setRunInfo(prdPath string, info RunInfo) error {
+	return updateRunIndex(prdPath, func(runs []RunInfo) []RunInfo {
+		for i := range runs {
+			if runs[i].ID == info.ID {
+				runs[i] = info
+				return runs
+			}
+		}
+		return append(runs, info)
+	})
+}
+
+// LogRetention limits how much of a PRD's run history is kept.
+type LogRetention struct {
+	MaxAge   time.Duration // Delete runs that ended longer ago than this (0 = no limit)
+	MaxSize  int64         // Delete the oldest runs once all runs take up more bytes than this (0 = no limit)
+	Compress bool          // Gzip the logs of finished runs
+}
+
+// LogRetentionFromConfig converts the logs settings of the project config.
+func LogRetentionFromConfig(c config.LogsConfig) (LogRetention, error) {
+	r := LogRetention{Compress: c.Compress}
+	if c.MaxAge != "" {
+		d, err := time.ParseDuration(c.MaxAge)
+		if err != nil || d < 0 {
+			return r, fmt.Errorf("invalid log max age %q", c.MaxAge)
+		}
+		r.MaxAge = d
+	}
+	if c.MaxSizeMB < 0 {
+		return r, fmt.Errorf("invalid log max size %d", c.MaxSizeMB)
+	}
+	r.MaxSize = int64(c.MaxSizeMB) << 20
+	return r, nil
+}
+
+// PruneRuns applies the retention settings to a PRD's runs: runs past the
+// maximum age are deleted, then the oldest runs until the rest fit in the
+// maximum size, and the logs of the remaining runs are compressed. The run
+// in progress, keep, is never touched.
+func PruneRuns(prdPath string, r LogRetention, keep int, now time.Time) error {
+	ids, err := ListRuns(prdPath)
+	if err != nil {
+		return err
+	}
+	index, err := ReadRunIndex(prdPath)
+	if err != nil {
+		index = nil
+	}
+	known := make(map[int]RunInfo, len(index))
+	for _, info := range index {
+		known[info.ID] = info
+	}
+
+	var errs []error
+	var kept []RunInfo
+	var total int64
+	for _, id := range ids {
+		info, ok := known[id]
+		if !ok {
+			info = RunInfo{ID: id}
+			if stat, err := os.Stat(RunDir(prdPath, id)); err == nil {
+				info.Started = stat.ModTime()
+				info.Ended = stat.ModTime()
+			}
+		}
+		if id == keep {
+			info.Size = dirSize(RunDir(prdPath, id))
+			total += info.Size
+			kept = append(kept, info)
+			continue
+		}
+
+		ended := info.Ended
+		if ended.IsZero() {
+			ended = info.Started
+		}
+		if r.MaxAge > 0 && !ended.IsZero() && now.Sub(ended) > r.MaxAge {
+			if err := os.RemoveAll(RunDir(prdPath, id)); err != nil {
+				errs = append(errs, err)
+				kept = append(kept, info)
+			}
+			continue
+		}
+
+		if r.Compress && !info.Compressed {
+			if err := compressRun(RunDir(prdPath, id)); err != nil {
+				errs = append(errs, err)
+			} else {
+				info.Compressed = true
+			}
+		}
+		info.Size = dirSize(RunDir(prdPath, id))
+		total += info.Size
+		kept = append(kept, info)
+	}
+
+	// Delete the oldest runs until the rest fit
+	if r.MaxSize > 0 {
+		for i := 0; i < len(kept) && total > r.MaxSize; {
+			if kept[i].ID == keep {
+				i++
+				continue
+			}
+			if err := os.RemoveAll(RunDir(prdPath, kept[i].ID)); err != nil {
+				errs = append(errs, err)
+				i++
+				continue
+			}
+			total -= kept[i].Size
+			kept = append(kept[:i], kept[i+1:]...)
+		}
+	}
+
+	if len(ids) > 0 {
+		if err := updateRunIndex(prdPath, func([]RunInfo) []RunInfo { return kept }); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compressRun gzips the logs and journals in a run directory.
+func compressRun(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, ".gz") {
+			return err
+		}
+		return gzipFile(path)
+	})
+}
+
+// gzipFile replaces a file with a gzipped copy named <path>.gz.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// dirSize returns the total size of the files in a directory.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// openLog opens a log or journal for reading, falling back to its gzipped
+// copy once the run has been compressed.
+func openLog(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err == nil {
+		return file, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	gz, gzErr := os.Open(path + ".gz")
+	if gzErr != nil {
+		// Report the missing log, not the missing gzipped copy
+		return nil, err
+	}
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		gz.Close()
+		return nil, err
+	}
+	return &gzipFileReader{Reader: zr, file: gz}, nil
+}
+
+// gzipFileReader closes both the gzip reader and the file underneath.
+type gzipFileReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipFileReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
+
+// LogExists reports whether a log or journal exists, gzipped or not.
+func LogExists(path string) bool {
+	for _, p := range []string{path, path + ".gz"} {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// logFilePath returns where the loop logs the agent's output: the run's
+// claude.log, or the one in the PRD directory for loops that don't record a
+// run.
+func (l *Loop) logFilePath() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch {
+	case l.logPath != "":
+		return l.logPath
+	case l.runID > 0:
+		return RunLogPath(l.prdPath, l.runID)
+	default:
+		return LogPath(l.prdPath)
+	}
+}
+
+// startRun adds the run to the runs index and applies the retention
+// settings to earlier runs.
+func (l *Loop) startRun() {
+	l.mu.Lock()
+	info := RunInfo{ID: l.runID, Started: l.runStarted}
+	retention := l.retention
+	l.mu.Unlock()
+
+	if err := setRunInfo(l.prdPath, info); err != nil {
+		l.logLine("[logs] " + err.Error())
+	}
+	if err := PruneRuns(l.prdPath, retention, info.ID, info.Started); err != nil {
+		l.logLine("[logs] failed to prune old runs: " + err.Error())
+	}
+}
+
+// recordRunStories remembers the stories an iteration worked on for the
+// runs index.
+func (l *Loop) recordRunStories(ids []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		found := false
+		for _, s := range l.runStories {
+			if s == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			l.runStories = append(l.runStories, id)
+		}
+	}
+}
+
+// finishRun records how far the run got in the runs index.
+func (l *Loop) finishRun() {
+	l.mu.Lock()
+	info := RunInfo{
+		ID:         l.runID,
+		Started:    l.runStarted,
+		Ended:      time.Now(),
+		Iterations: l.iteration,
+		Stories:    l.runStories,
+	}
+	l.mu.Unlock()
+
+	info.Size = dirSize(RunDir(l.prdPath, info.ID))
+	_ = setRunInfo(l.prdPath, info)
+}
@@ -0,0 +1,173 @@
+package loop
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+// createTestRun records a finished run with a log of the given size.
+func createTestRun(t *testing.T, prdPath string, ended time.Time, logSize int) int {
+	t.Helper()
+	j, err := CreateJournal(prdPath)
+	if err != nil {
+		t.Fatalf("CreateJournal failed: %v", err)
+	}
+	j.Write(Event{Type: EventIterationStart, Iteration: 1, Time: ended})
+	j.Close()
+	if err := os.WriteFile(RunLogPath(prdPath, j.Run()), []byte(strings.Repeat("x", logSize)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := setRunInfo(prdPath, RunInfo{ID: j.Run(), Started: ended.Add(-time.Hour), Ended: ended}); err != nil {
+		t.Fatal(err)
+	}
+	return j.Run()
+}
+
+func TestPruneRuns_MaxAge(t *testing.T) {
+	prdPath := filepath.Join(t.TempDir(), "prd.json")
+	now := time.Now()
+	old := createTestRun(t, prdPath, now.Add(-48*time.Hour), 10)
+	recent := createTestRun(t, prdPath, now.Add(-time.Hour), 10)
+	current := createTestRun(t, prdPath, now, 10)
+
+	if err := PruneRuns(prdPath, LogRetention{MaxAge: 24 * time.Hour}, current, now); err != nil {
+		t.Fatalf("PruneRuns failed: %v", err)
+	}
+	runs, _ := ListRuns(prdPath)
+	if len(runs) != 2 || runs[0] != recent || runs[1] != current {
+		t.Errorf("Expected run %d to be deleted, got runs %v", old, runs)
+	}
+	index, _ := ReadRunIndex(prdPath)
+	if len(index) != 2 || index[0].ID != recent {
+		t.Errorf("Expected the index to drop the deleted run, got %+v", index)
+	}
+}
+
+func TestPruneRuns_MaxSize(t *testing.T) {
+	prdPath := filepath.Join(t.TempDir(), "prd.json")
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		createTestRun(t, prdPath, now, 1000)
+	}
+
+	// Each run takes up a little over 1000 bytes with its journal
+	if err := PruneRuns(prdPath, LogRetention{MaxSize: 2500}, 4, now); err != nil {
+		t.Fatalf("PruneRuns failed: %v", err)
+	}
+	runs, _ := ListRuns(prdPath)
+	if len(runs) != 2 || runs[0] != 3 || runs[1] != 4 {
+		t.Errorf("Expected only the two newest runs to be kept, got %v", runs)
+	}
+
+	// The run in progress is kept even if it alone is too big
+	if err := PruneRuns(prdPath, LogRetention{MaxSize: 1}, 4, now); err != nil {
+		t.Fatalf("PruneRuns failed: %v", err)
+	}
+	if runs, _ := ListRuns(prdPath); len(runs) != 1 || runs[0] != 4 {
+		t.Errorf("Expected the current run to be kept, got %v", runs)
+	}
+}
+
+func TestPruneRuns_Compress(t *testing.T) {
+	prdPath := filepath.Join(t.TempDir(), "prd.json")
+	now := time.Now()
+	old := createTestRun(t, prdPath, now, 5000)
+	current := createTestRun(t, prdPath, now, 10)
+
+	if err := PruneRuns(prdPath, LogRetention{Compress: true}, current, now); err != nil {
+		t.Fatalf("PruneRuns failed: %v", err)
+	}
+
+	if _, err := os.Stat(RunLogPath(prdPath, old)); !os.IsNotExist(err) {
+		t.Error("Expected the old run's log to be replaced by a gzipped copy")
+	}
+	if _, err := os.Stat(RunLogPath(prdPath, current)); err != nil {
+		t.Errorf("Expected the current run's log to be left alone: %v", err)
+	}
+	if !LogExists(RunLogPath(prdPath, old)) {
+		t.Error("Expected LogExists to find the gzipped log")
+	}
+
+	// Compressed journals and logs read back transparently
+	events, err := ReadJournal(JournalPath(prdPath, old))
+	if err != nil || len(events) != 1 {
+		t.Errorf("Expected 1 event from the compressed journal, got %d (%v)", len(events), err)
+	}
+	f, err := openLog(RunLogPath(prdPath, old))
+	if err != nil {
+		t.Fatalf("openLog failed: %v", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil || len(data) != 5000 {
+		t.Errorf("Expected 5000 bytes from the compressed log, got %d (%v)", len(data), err)
+	}
+
+	index, _ := ReadRunIndex(prdPath)
+	if len(index) != 2 || !index[0].Compressed || index[0].Size == 0 || index[0].Size >= 5000 {
+		t.Errorf("Expected the index to record the compressed size, got %+v", index)
+	}
+}
+
+func TestLogRetentionFromConfig(t *testing.T) {
+	r, err := LogRetentionFromConfig(config.LogsConfig{MaxAge: "720h", MaxSizeMB: 200, Compress: true})
+	if err != nil {
+		t.Fatalf("LogRetentionFromConfig failed: %v", err)
+	}
+	if r.MaxAge != 720*time.Hour || r.MaxSize != 200<<20 || !r.Compress {
+		t.Errorf("Unexpected retention: %+v", r)
+	}
+
+	if _, err := LogRetentionFromConfig(config.LogsConfig{MaxAge: "30 days"}); err == nil {
+		t.Error("Expected an error for an invalid max age")
+	}
+	if _, err := LogRetentionFromConfig(config.LogsConfig{MaxSizeMB: -1}); err == nil {
+		t.Error("Expected an error for a negative max size")
+	}
+}
+
+func TestLoop_RunWritesRunLogAndIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{
+		stdout: []string{
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+		},
+		onStart: markAllPassed(t, prdPath),
+	}}}
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(agent)
+	if _, err := collectEvents(t, l); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(RunLogPath(prdPath, 1))
+	if err != nil {
+		t.Fatalf("Expected a claude.log for run 1: %v", err)
+	}
+	if !strings.Contains(string(data), "ralph-status") {
+		t.Errorf("Expected the agent's output in the run's log, got:\n%s", data)
+	}
+	if _, err := os.Stat(LogPath(prdPath)); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be logged in the PRD directory")
+	}
+
+	index, err := ReadRunIndex(prdPath)
+	if err != nil || len(index) != 1 {
+		t.Fatalf("Expected 1 run in the index, got %+v (%v)", index, err)
+	}
+	info := index[0]
+	if info.ID != 1 || info.Ended.Before(info.Started) || info.Iterations != 1 || info.Size == 0 {
+		t.Errorf("Unexpected run info: %+v", info)
+	}
+	if len(info.Stories) != 1 || info.Stories[0] != "US-001" {
+		t.Errorf("Expected stories [US-001], got %v", info.Stories)
+	}
+}
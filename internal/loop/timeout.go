@@ -0,0 +1,118 @@
+package loop
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+// Retry reasons reported on EventRetrying.
+const (
+	RetryReasonCrashed = "crashed" // The agent exited with an error
+	RetryReasonStalled = "stalled" // The agent was killed by a timeout
+)
+
+// TimeoutConfig configures stall detection for agent iterations.
+type TimeoutConfig struct {
+	Inactivity time.Duration // Kill the agent after this long without output (0 = no limit)
+	Iteration  time.Duration // Kill the agent when one iteration runs this long (0 = no limit)
+}
+
+// DefaultTimeoutConfig returns the default timeout configuration.
+func DefaultTimeoutConfig() TimeoutConfig {
+	return TimeoutConfig{
+		Inactivity: 30 * time.Minute,
+		Iteration:  2 * time.Hour,
+	}
+}
+
+// TimeoutConfigFromConfig applies the timeouts set in the project config to base.
+// Unset values keep the value from base.
+func TimeoutConfigFromConfig(base TimeoutConfig, c config.TimeoutConfig) (TimeoutConfig, error) {
+	if c.Inactivity != "" {
+		d, err := time.ParseDuration(c.Inactivity)
+		if err != nil || d < 0 {
+			return base, fmt.Errorf("invalid inactivity timeout %q", c.Inactivity)
+		}
+		base.Inactivity = d
+	}
+	if c.Iteration != "" {
+		d, err := time.ParseDuration(c.Iteration)
+		if err != nil || d < 0 {
+			return base, fmt.Errorf("invalid iteration timeout %q", c.Iteration)
+		}
+		base.Iteration = d
+	}
+	return base, nil
+}
+
+// StallError is returned when an iteration is killed because the agent went
+// quiet or ran too long.
+type StallError struct {
+	Agent  string // Agent name
+	Reason string // What triggered the kill (e.g. "no output for 30m0s")
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("%s stalled: %s", e.Agent, e.Reason)
+}
+
+// watchInterval returns how often the watchdog checks an iteration.
+func (t TimeoutConfig) watchInterval() time.Duration {
+	interval := time.Second
+	for _, d := range []time.Duration{t.Inactivity, t.Iteration} {
+		if d > 0 && d/4 < interval {
+			interval = d / 4
+		}
+	}
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	return interval
+}
+
+// touch records that the agent produced output.
+func (l *Loop) touch() {
+	l.mu.Lock()
+	l.lastOutput = time.Now()
+	l.mu.Unlock()
+}
+
+// watchIteration kills proc when it exceeds the inactivity or iteration timeout.
+// The reason is sent on stalled before the process is killed. It returns when
+// done is closed.
+func (l *Loop) watchIteration(proc AgentProcess, agentName string, timeouts TimeoutConfig, done <-chan struct{}, stalled chan<- *StallError) {
+	if timeouts.Inactivity <= 0 && timeouts.Iteration <= 0 {
+		return
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(timeouts.watchInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			l.mu.Lock()
+			quiet := now.Sub(l.lastOutput)
+			l.mu.Unlock()
+
+			var reason string
+			switch {
+			case timeouts.Iteration > 0 && now.Sub(start) >= timeouts.Iteration:
+				reason = fmt.Sprintf("iteration ran longer than %s", timeouts.Iteration)
+			case timeouts.Inactivity > 0 && quiet >= timeouts.Inactivity:
+				reason = fmt.Sprintf("no output for %s", timeouts.Inactivity)
+			default:
+				continue
+			}
+
+			stalled <- &StallError{Agent: agentName, Reason: reason}
+			proc.Kill()
+			return
+		}
+	}
+}
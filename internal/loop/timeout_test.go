@@ -0,0 +1,178 @@
+package loop
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+)
+
+// hangingProcess writes its output and then blocks, like a hung agent, until killed.
+type hangingProcess struct {
+	stdout *io.PipeReader
+	w      *io.PipeWriter
+	killed chan struct{}
+	once   sync.Once
+}
+
+func newHangingProcess(lines []string) *hangingProcess {
+	r, w := io.Pipe()
+	p := &hangingProcess{stdout: r, w: w, killed: make(chan struct{})}
+	go func() {
+		for _, line := range lines {
+			if _, err := io.WriteString(w, line+"\n"); err != nil {
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func (p *hangingProcess) Stdout() io.Reader { return p.stdout }
+func (p *hangingProcess) Stderr() io.Reader { return strings.NewReader("") }
+func (p *hangingProcess) Wait() error {
+	<-p.killed
+	return errors.New("signal: killed")
+}
+func (p *hangingProcess) Kill() error {
+	p.once.Do(func() {
+		p.w.Close()
+		close(p.killed)
+	})
+	return nil
+}
+
+func TestLoop_InactivityTimeoutRetries(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{stdout: []string{`{"type":"system","subtype":"init"}`}, hang: true},
+		{onStart: markAllPassed(t, prdPath)},
+	}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 3)
+	l.SetAgent(agent)
+	l.SetRetryConfig(RetryConfig{MaxRetries: 1, Enabled: true})
+	l.SetTimeoutConfig(TimeoutConfig{Inactivity: 50 * time.Millisecond})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var retry *Event
+	for i := range events {
+		if events[i].Type == EventRetrying {
+			retry = &events[i]
+		}
+	}
+	if retry == nil {
+		t.Fatal("Expected a Retrying event after the stall")
+	}
+	if retry.Reason != RetryReasonStalled {
+		t.Errorf("Expected reason %q, got %q", RetryReasonStalled, retry.Reason)
+	}
+	if !strings.Contains(retry.Text, "no output for 50ms") {
+		t.Errorf("Expected stall detail in retry text, got %q", retry.Text)
+	}
+	if events[len(events)-1].Type != EventComplete {
+		t.Errorf("Expected loop to complete after retry, got %s", events[len(events)-1].Type)
+	}
+}
+
+func TestLoop_IterationTimeoutWithoutRetry(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{stdout: []string{`{"type":"system","subtype":"init"}`}, hang: true}}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 3)
+	l.SetAgent(agent)
+	l.DisableRetry()
+	// The inactivity timeout is far away, so only the iteration timeout can fire
+	l.SetTimeoutConfig(TimeoutConfig{Inactivity: time.Hour, Iteration: 50 * time.Millisecond})
+
+	_, err := collectEvents(t, l)
+	var stallErr *StallError
+	if !errors.As(err, &stallErr) {
+		t.Fatalf("Expected StallError, got %v", err)
+	}
+	if stallErr.Reason != "iteration ran longer than 50ms" {
+		t.Errorf("Unexpected stall reason: %q", stallErr.Reason)
+	}
+}
+
+func TestLoop_CrashRetryReason(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{
+		{waitErr: errors.New("exit status 1")},
+		{onStart: markAllPassed(t, prdPath)},
+	}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 3)
+	l.SetAgent(agent)
+	l.SetRetryConfig(RetryConfig{MaxRetries: 1, Enabled: true})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	for _, e := range events {
+		if e.Type == EventRetrying && e.Reason != RetryReasonCrashed {
+			t.Errorf("Expected reason %q, got %q", RetryReasonCrashed, e.Reason)
+		}
+	}
+}
+
+func TestTimeoutConfigFromConfig(t *testing.T) {
+	base := DefaultTimeoutConfig()
+
+	got, err := TimeoutConfigFromConfig(base, config.TimeoutConfig{Inactivity: "10m"})
+	if err != nil {
+		t.Fatalf("TimeoutConfigFromConfig failed: %v", err)
+	}
+	if got.Inactivity != 10*time.Minute || got.Iteration != base.Iteration {
+		t.Errorf("Unexpected timeouts: %+v", got)
+	}
+
+	got, err = TimeoutConfigFromConfig(base, config.TimeoutConfig{Iteration: "0"})
+	if err != nil {
+		t.Fatalf("TimeoutConfigFromConfig failed: %v", err)
+	}
+	if got.Iteration != 0 {
+		t.Errorf("Expected \"0\" to disable the iteration timeout, got %v", got.Iteration)
+	}
+
+	if _, err := TimeoutConfigFromConfig(base, config.TimeoutConfig{Inactivity: "a while"}); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
+
+func TestLoop_StallKillsAgentChildren(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	// The backgrounded sleep inherits stdout, so the iteration only ends
+	// promptly if it is killed along with the shell
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "test prompt", 3)
+	l.SetAgent(&CommandAgent{Command: "sh", Args: []string{"-c", "sleep 60 & echo started; wait"}, Output: OutputText})
+	l.DisableRetry()
+	l.SetTimeoutConfig(TimeoutConfig{Inactivity: 100 * time.Millisecond})
+
+	start := time.Now()
+	_, err := collectEvents(t, l)
+	var stallErr *StallError
+	if !errors.As(err, &stallErr) {
+		t.Fatalf("Expected StallError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= killGrace {
+		t.Errorf("Expected the iteration to end when the agent was killed, took %s", elapsed)
+	}
+}
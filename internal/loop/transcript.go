@@ -0,0 +1,172 @@
+package loop
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Transcript is what claude.log recorded for one iteration.
+type Transcript struct {
+	Run       int      // Run ID (0 for output logged before runs were numbered)
+	Iteration int      // Iteration number within the run
+	Stories   []string // Stories the iteration worked on
+	Events    []Event  // Agent output and loop messages, in order
+}
+
+// HasStory reports whether the iteration worked on the story.
+func (t *Transcript) HasStory(id string) bool {
+	for _, s := range t.Stories {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Transcript) addStory(id string) {
+	if id != "" && !t.HasStory(id) {
+		t.Stories = append(t.Stories, id)
+	}
+}
+
+// LogPath returns the path of the PRD's claude.log.
+func LogPath(prdPath string) string {
+	return filepath.Join(filepath.Dir(prdPath), "claude.log")
+}
+
+// StoryLogPath returns the path of the claude.log a story writes when it is
+// worked on in parallel.
+func StoryLogPath(prdPath, storyID string) string {
+	return filepath.Join(filepath.Dir(prdPath), "stories", storyID, "claude.log")
+}
+
+// ReadTranscripts reads a claude.log and splits it into iterations. A
+// compressed log is read transparently.
+func ReadTranscripts(path string) ([]Transcript, error) {
+	file, err := openLog(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseTranscripts(file)
+}
+
+// ParseTranscripts splits claude.log output into iterations, using the
+// "[run]" and "[iteration]" markers the loop writes. Output logged before
+// those markers existed is split wherever a new Claude session starts.
+// Stream-json lines are parsed with ParseLine; stderr, verification and
+// blocked-story lines become events of their own.
+func ParseTranscripts(r io.Reader) ([]Transcript, error) {
+	var (
+		transcripts []Transcript
+		cur         *Transcript
+		run         int
+		marked      bool // The current iteration was started by a marker
+		started     bool // The current iteration has seen a session start
+	)
+
+	begin := func(iter int) {
+		if iter == 0 {
+			// Continue the numbering of unmarked output
+			iter = 1
+			if n := len(transcripts); n > 0 && transcripts[n-1].Run == run {
+				iter = transcripts[n-1].Iteration + 1
+			}
+		}
+		transcripts = append(transcripts, Transcript{Run: run, Iteration: iter})
+		cur = &transcripts[len(transcripts)-1]
+		marked, started = false, false
+	}
+	add := func(event Event) {
+		if cur == nil {
+			begin(0)
+		}
+		event.Iteration = cur.Iteration
+		if event.Type == EventStoryStarted || event.Type == EventStoryBlocked {
+			cur.addStory(event.StoryID)
+		}
+		cur.Events = append(cur.Events, event)
+	}
+
+	scanner := bufio.NewScanner(r)
+	// Tool results can be large
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		tag, rest := splitLogTag(line)
+		switch tag {
+		case "run":
+			run, _ = strconv.Atoi(strings.TrimSpace(rest))
+			cur = nil
+		case "iteration":
+			fields := strings.Fields(rest)
+			iter := 0
+			if len(fields) > 0 {
+				iter, _ = strconv.Atoi(fields[0])
+				fields = fields[1:]
+			}
+			begin(iter)
+			marked = true
+			for _, id := range fields {
+				cur.addStory(id)
+			}
+		case "stderr":
+			if strings.TrimSpace(rest) != "" {
+				add(Event{Type: EventError, Text: rest})
+			}
+		case "verify":
+			if cmd, ok := strings.CutPrefix(rest, "$ "); ok {
+				add(Event{Type: EventToolStart, Tool: "Bash", ToolInput: map[string]interface{}{"command": cmd}})
+			} else if strings.TrimSpace(rest) != "" {
+				add(Event{Type: EventAssistantText, Text: rest})
+			}
+		case "blocked":
+			id, _, _ := strings.Cut(rest, " ")
+			add(Event{Type: EventStoryBlocked, StoryID: id, Text: "Blocked " + rest})
+		case "session":
+			// Session IDs are also on the init message
+		default:
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if !strings.HasPrefix(strings.TrimSpace(line), "{") {
+				// Plain text output from agents that don't stream JSON
+				add(Event{Type: EventAssistantText, Text: line})
+				continue
+			}
+			for _, event := range ParseLine(line) {
+				// A new session without a marker is a new iteration; with
+				// one, it's a retry of the same iteration
+				if event.Type == EventIterationStart && started && !marked {
+					begin(0)
+				}
+				add(event)
+				if event.Type == EventIterationStart {
+					started = true
+				}
+			}
+		}
+	}
+	return transcripts, scanner.Err()
+}
+
+// splitLogTag splits a "[tag] rest" line written by the loop. It returns an
+// empty tag for agent output.
+func splitLogTag(line string) (string, string) {
+	if !strings.HasPrefix(line, "[") {
+		return "", line
+	}
+	end := strings.Index(line, "]")
+	if end < 0 {
+		return "", line
+	}
+	switch tag := line[1:end]; tag {
+	case "run", "iteration", "stderr", "verify", "blocked", "session":
+		return tag, strings.TrimPrefix(line[end+1:], " ")
+	default:
+		return "", line
+	}
+}
@@ -0,0 +1,119 @@
+package loop
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTranscripts(t *testing.T) {
+	log := strings.Join([]string{
+		`[run] 2`,
+		`[iteration] 1 US-001`,
+		`{"type":"system","subtype":"init","session_id":"s1"}`,
+		`[session] s1`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"main.go"}}]}}`,
+		`[stderr] warning: something odd`,
+		// A retry that resumes the session stays in the same iteration
+		`{"type":"system","subtype":"init","session_id":"s1"}`,
+		`[verify] $ go test ./...`,
+		`[verify] FAIL`,
+		`[blocked] US-001 after 3 attempts: tests fail`,
+		`[iteration] 2 US-002 US-003`,
+		`not json output`,
+	}, "\n")
+
+	transcripts, err := ParseTranscripts(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseTranscripts failed: %v", err)
+	}
+	if len(transcripts) != 2 {
+		t.Fatalf("Expected 2 iterations, got %d", len(transcripts))
+	}
+
+	first := transcripts[0]
+	if first.Run != 2 || first.Iteration != 1 {
+		t.Errorf("Expected run 2 iteration 1, got run %d iteration %d", first.Run, first.Iteration)
+	}
+	if !first.HasStory("US-001") || len(first.Stories) != 1 {
+		t.Errorf("Expected stories [US-001], got %v", first.Stories)
+	}
+	var types []string
+	for _, e := range first.Events {
+		types = append(types, e.Type.String())
+		if e.Iteration != 1 {
+			t.Errorf("Expected events numbered with iteration 1, got %d", e.Iteration)
+		}
+	}
+	want := "IterationStart StoryStarted ToolStart Error IterationStart ToolStart AssistantText StoryBlocked"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("Expected events %q, got %q", want, got)
+	}
+	if first.Events[5].Tool != "Bash" || first.Events[5].ToolInput["command"] != "go test ./..." {
+		t.Errorf("Expected the verification command as a Bash tool call, got %+v", first.Events[5])
+	}
+
+	second := transcripts[1]
+	if second.Iteration != 2 || !second.HasStory("US-002") || !second.HasStory("US-003") {
+		t.Errorf("Expected iteration 2 on US-002 and US-003, got %+v", second)
+	}
+	if len(second.Events) != 1 || second.Events[0].Text != "not json output" {
+		t.Errorf("Expected plain output as text, got %+v", second.Events)
+	}
+}
+
+func TestParseTranscripts_Unmarked(t *testing.T) {
+	// Logs written before the markers are split on each new session
+	log := strings.Join([]string{
+		`{"type":"system","subtype":"init","session_id":"s1"}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+		`{"type":"system","subtype":"init","session_id":"s2"}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-002</ralph-status>"}]}}`,
+		`[run] 1`,
+		`[iteration] 1 US-002`,
+	}, "\n")
+
+	transcripts, err := ParseTranscripts(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("ParseTranscripts failed: %v", err)
+	}
+	if len(transcripts) != 3 {
+		t.Fatalf("Expected 3 iterations, got %d", len(transcripts))
+	}
+	for i, want := range []struct {
+		run, iter int
+		story     string
+	}{{0, 1, "US-001"}, {0, 2, "US-002"}, {1, 1, "US-002"}} {
+		got := transcripts[i]
+		if got.Run != want.run || got.Iteration != want.iter || !got.HasStory(want.story) {
+			t.Errorf("Iteration %d: expected run %d iteration %d on %s, got %+v", i, want.run, want.iter, want.story, got)
+		}
+	}
+}
+
+func TestLoop_RunMarksLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{
+		stdout: []string{
+			`{"type":"assistant","message":{"content":[{"type":"text","text":"<ralph-status>US-001</ralph-status>"}]}}`,
+		},
+		onStart: markAllPassed(t, prdPath),
+	}}}
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(agent)
+	if _, err := collectEvents(t, l); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	transcripts, err := ReadTranscripts(RunLogPath(prdPath, 1))
+	if err != nil {
+		t.Fatalf("ReadTranscripts failed: %v", err)
+	}
+	if len(transcripts) != 1 {
+		t.Fatalf("Expected 1 iteration, got %d", len(transcripts))
+	}
+	if got := transcripts[0]; got.Run != 1 || got.Iteration != 1 || !got.HasStory("US-001") {
+		t.Errorf("Expected run 1 iteration 1 on US-001, got %+v", got)
+	}
+}
@@ -0,0 +1,129 @@
+package loop
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Usage records token and cost accounting reported by the agent at the end of
+// an iteration. Usage values can be summed to get per-story and per-PRD totals.
+type Usage struct {
+	CostUSD             float64 `json:"costUsd"`
+	InputTokens         int     `json:"inputTokens"`
+	OutputTokens        int     `json:"outputTokens"`
+	CacheCreationTokens int     `json:"cacheCreationTokens"`
+	CacheReadTokens     int     `json:"cacheReadTokens"`
+	NumTurns            int     `json:"numTurns"`
+	DurationMs          int64   `json:"durationMs"`
+	Iterations          int     `json:"iterations"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.CostUSD += other.CostUSD
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationTokens += other.CacheCreationTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.NumTurns += other.NumTurns
+	u.DurationMs += other.DurationMs
+	u.Iterations += other.Iterations
+}
+
+// TotalTokens returns the sum of all input, output and cache tokens.
+func (u Usage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens + u.CacheCreationTokens + u.CacheReadTokens
+}
+
+// IsZero returns true if no usage has been recorded.
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// Summary returns a compact description such as "$1.23 · 45.6k tokens".
+func (u Usage) Summary() string {
+	return fmt.Sprintf("$%.2f · %s tokens", u.CostUSD, FormatTokens(u.TotalTokens()))
+}
+
+// FormatTokens formats a token count compactly (e.g. 950, 12.3k, 1.2M).
+func FormatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// UsageTotals is the persisted usage for a PRD across all runs.
+type UsageTotals struct {
+	Total   Usage            `json:"total"`
+	Stories map[string]Usage `json:"stories,omitempty"`
+}
+
+// Add records usage for a story. An empty storyID only counts toward the total.
+func (t *UsageTotals) Add(storyID string, u Usage) {
+	t.Total.Add(u)
+	if storyID == "" {
+		return
+	}
+	if t.Stories == nil {
+		t.Stories = make(map[string]Usage)
+	}
+	s := t.Stories[storyID]
+	s.Add(u)
+	t.Stories[storyID] = s
+}
+
+// UsagePath returns the path to usage.json next to the given prd.json.
+func UsagePath(prdPath string) string {
+	return filepath.Join(filepath.Dir(prdPath), "usage.json")
+}
+
+// LoadUsage reads the persisted usage totals for a PRD.
+// Returns empty totals when the file doesn't exist.
+func LoadUsage(prdPath string) (*UsageTotals, error) {
+	data, err := os.ReadFile(UsagePath(prdPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &UsageTotals{}, nil
+		}
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+
+	var totals UsageTotals
+	if err := json.Unmarshal(data, &totals); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file: %w", err)
+	}
+	return &totals, nil
+}
+
+// SaveUsage writes usage totals next to the given prd.json.
+func SaveUsage(prdPath string, totals *UsageTotals) error {
+	data, err := json.MarshalIndent(totals, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	// Write via a temp file so a crash never leaves a truncated usage.json
+	path := UsagePath(prdPath)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// RecordUsage adds usage for a story to the persisted totals for a PRD.
+func RecordUsage(prdPath, storyID string, u Usage) error {
+	totals, err := LoadUsage(prdPath)
+	if err != nil {
+		return err
+	}
+	totals.Add(storyID, u)
+	return SaveUsage(prdPath, totals)
+}
@@ -0,0 +1,109 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUsageAdd(t *testing.T) {
+	u := Usage{CostUSD: 0.5, InputTokens: 10, OutputTokens: 20, Iterations: 1}
+	u.Add(Usage{CostUSD: 0.25, InputTokens: 5, CacheReadTokens: 100, NumTurns: 3, DurationMs: 1000, Iterations: 1})
+
+	want := Usage{CostUSD: 0.75, InputTokens: 15, OutputTokens: 20, CacheReadTokens: 100, NumTurns: 3, DurationMs: 1000, Iterations: 2}
+	if u != want {
+		t.Errorf("Add() = %+v, want %+v", u, want)
+	}
+	if u.TotalTokens() != 135 {
+		t.Errorf("TotalTokens() = %d, want 135", u.TotalTokens())
+	}
+}
+
+func TestUsageSummary(t *testing.T) {
+	u := Usage{CostUSD: 1.234, InputTokens: 1500, OutputTokens: 500}
+	if got := u.Summary(); got != "$1.23 · 2.0k tokens" {
+		t.Errorf("Summary() = %q", got)
+	}
+	if !(Usage{}).IsZero() {
+		t.Error("expected zero usage to report IsZero")
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	tests := map[int]string{
+		0:         "0",
+		950:       "950",
+		12345:     "12.3k",
+		1_250_000: "1.2M",
+	}
+	for n, want := range tests {
+		if got := FormatTokens(n); got != want {
+			t.Errorf("FormatTokens(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	dir := t.TempDir()
+	prdPath := filepath.Join(dir, "prd.json")
+
+	// Missing file loads as empty totals
+	totals, err := LoadUsage(prdPath)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	if !totals.Total.IsZero() {
+		t.Errorf("expected empty totals, got %+v", totals.Total)
+	}
+
+	if err := RecordUsage(prdPath, "US-001", Usage{CostUSD: 1, Iterations: 1}); err != nil {
+		t.Fatalf("RecordUsage failed: %v", err)
+	}
+	if err := RecordUsage(prdPath, "US-001", Usage{CostUSD: 2, Iterations: 1}); err != nil {
+		t.Fatalf("RecordUsage failed: %v", err)
+	}
+	if err := RecordUsage(prdPath, "", Usage{CostUSD: 0.5, Iterations: 1}); err != nil {
+		t.Fatalf("RecordUsage failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "usage.json")); err != nil {
+		t.Fatalf("expected usage.json next to prd.json: %v", err)
+	}
+
+	totals, err = LoadUsage(prdPath)
+	if err != nil {
+		t.Fatalf("LoadUsage failed: %v", err)
+	}
+	if totals.Total.CostUSD != 3.5 || totals.Total.Iterations != 3 {
+		t.Errorf("unexpected total: %+v", totals.Total)
+	}
+	if totals.Stories["US-001"].CostUSD != 3 {
+		t.Errorf("unexpected story usage: %+v", totals.Stories["US-001"])
+	}
+	if len(totals.Stories) != 1 {
+		t.Errorf("expected 1 story entry, got %d", len(totals.Stories))
+	}
+}
+
+func TestLoopInstanceRecordUsage(t *testing.T) {
+	inst := &LoopInstance{}
+	inst.recordUsage(1, "", Usage{CostUSD: 1, Iterations: 1})
+	inst.recordUsage(2, "US-002", Usage{CostUSD: 2, Iterations: 1})
+	inst.recordUsage(2, "US-002", Usage{CostUSD: 0.5, Iterations: 1})
+
+	if inst.Usage.CostUSD != 3.5 {
+		t.Errorf("expected PRD cost 3.5, got %v", inst.Usage.CostUSD)
+	}
+	if inst.IterationUsage[2].CostUSD != 2.5 {
+		t.Errorf("expected iteration 2 cost 2.5, got %v", inst.IterationUsage[2].CostUSD)
+	}
+	if inst.StoryUsage["US-002"].CostUSD != 2.5 {
+		t.Errorf("expected story cost 2.5, got %v", inst.StoryUsage["US-002"].CostUSD)
+	}
+
+	snap := inst.snapshot()
+	snap.StoryUsage["US-002"] = Usage{}
+	if inst.StoryUsage["US-002"].CostUSD != 2.5 {
+		t.Error("expected snapshot maps to be copies")
+	}
+}
@@ -0,0 +1,187 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minicodemonkey/chief/embed"
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// maxVerifyOutputLines limits how much verification output is fed back to the agent.
+const maxVerifyOutputLines = 80
+
+// hasVerification returns true if any verification commands are configured.
+func hasVerification(v config.VerifyConfig) bool {
+	return len(v.Commands) > 0 || len(v.Stories) > 0
+}
+
+// passingStories returns the IDs of stories that currently have passes: true,
+// or nil when verification is disabled.
+func (l *Loop) passingStories() map[string]bool {
+	l.mu.Lock()
+	verify := l.verify
+	l.mu.Unlock()
+	if !hasVerification(verify) {
+		return nil
+	}
+
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		return nil
+	}
+	passed := make(map[string]bool)
+	for _, story := range p.UserStories {
+		if story.Passes {
+			passed[story.ID] = true
+		}
+	}
+	return passed
+}
+
+// verifyNewlyPassed runs the verification commands for every story the agent
+// marked as passing since passedBefore was taken. Stories that fail have their
+// passes flag reverted, and the failure is fed into the next iteration's prompt.
+func (l *Loop) verifyNewlyPassed(ctx context.Context, passedBefore map[string]bool) error {
+	if passedBefore == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	verify := l.verify
+	timeout := l.timeouts.Iteration
+	iter := l.iteration
+	l.mu.Unlock()
+
+	p, err := prd.LoadPRD(l.prdPath)
+	if err != nil {
+		// The completion check reports unreadable PRDs
+		return nil
+	}
+
+	reverted := false
+	for i := range p.UserStories {
+		story := &p.UserStories[i]
+		if !story.Passes || passedBefore[story.ID] {
+			continue
+		}
+		commands := verify.CommandsFor(story.ID)
+		if len(commands) == 0 {
+			continue
+		}
+
+		l.emit(Event{
+			Type:      EventVerificationStarted,
+			Iteration: iter,
+			StoryID:   story.ID,
+			Text:      fmt.Sprintf("Verifying %s...", story.ID),
+		})
+
+		command, output, err := l.runVerifyCommands(ctx, commands, timeout)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			l.mu.Lock()
+			delete(l.verifyFeedback, story.ID)
+			l.mu.Unlock()
+			l.emit(Event{
+				Type:      EventVerificationPassed,
+				Iteration: iter,
+				StoryID:   story.ID,
+				Text:      fmt.Sprintf("%s passed verification", story.ID),
+			})
+			continue
+		}
+
+		output = tailLines(output, maxVerifyOutputLines)
+		story.Passes = false
+		story.InProgress = false
+		reverted = true
+
+		l.mu.Lock()
+		if l.verifyFeedback == nil {
+			l.verifyFeedback = make(map[string]string)
+		}
+		l.verifyFeedback[story.ID] = embed.GetVerifyFailedPrompt(story.ID, command, output)
+		l.lastText = "Failed verification: " + command
+		l.mu.Unlock()
+
+		l.emit(Event{
+			Type:      EventVerificationFailed,
+			Iteration: iter,
+			StoryID:   story.ID,
+			Text:      fmt.Sprintf("%s failed verification: %s\n%s", story.ID, command, output),
+			Err:       err,
+		})
+	}
+
+	if reverted {
+		if err := p.Save(l.prdPath); err != nil {
+			return fmt.Errorf("failed to revert unverified stories: %w", err)
+		}
+	}
+	return nil
+}
+
+// runVerifyCommands runs commands in order in the work dir, stopping at the
+// first failure. It returns the failing command and its output.
+func (l *Loop) runVerifyCommands(ctx context.Context, commands []string, timeout time.Duration) (string, string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for _, command := range commands {
+		l.logLine("[verify] $ " + command)
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = l.effectiveWorkDir()
+		out, err := cmd.CombinedOutput()
+		for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+			l.logLine("[verify] " + line)
+		}
+		if err != nil {
+			return command, strings.TrimRight(string(out), "\n"), err
+		}
+	}
+	return "", "", nil
+}
+
+// verifyPrompt returns the agent prompt with any outstanding verification
+// failures appended.
+func (l *Loop) verifyPrompt(prompt string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.verifyFeedback) == 0 {
+		return prompt
+	}
+	ids := make([]string, 0, len(l.verifyFeedback))
+	for id := range l.verifyFeedback {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var b strings.Builder
+	b.WriteString(prompt)
+	for _, id := range ids {
+		b.WriteString("\n\n")
+		b.WriteString(l.verifyFeedback[id])
+	}
+	return b.String()
+}
+
+// tailLines returns the last n lines of s.
+func tailLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[len(lines)-n:], "\n")
+}
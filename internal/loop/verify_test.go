@@ -0,0 +1,117 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+func TestLoop_VerificationRevertsAndFeedsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+	markerPath := filepath.Join(tmpDir, "fixed")
+
+	agent := &fakeAgent{runs: []fakeRun{
+		// First attempt claims success but the check fails
+		{
+			stdout:  []string{`{"type":"assistant","message":{"content":[{"type":"text","text":"<chief-complete/>"}]}}`},
+			onStart: markAllPassed(t, prdPath),
+		},
+		// Second attempt fixes the problem
+		{onStart: func() {
+			if err := os.WriteFile(markerPath, nil, 0644); err != nil {
+				t.Errorf("Failed to write marker: %v", err)
+			}
+			markAllPassed(t, prdPath)()
+		}},
+	}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "base prompt", 5)
+	l.SetAgent(agent)
+	l.SetVerifyConfig(config.VerifyConfig{
+		Commands: []string{"echo checking", "test -f fixed || { echo 'FAIL: not fixed'; exit 1; }"},
+	})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	reqs := agent.Requests()
+	if len(reqs) != 2 {
+		t.Fatalf("Expected 2 agent runs, got %d", len(reqs))
+	}
+	if strings.Contains(reqs[0].Prompt, "Verification Failed") {
+		t.Error("Expected first prompt to have no verification feedback")
+	}
+	for _, want := range []string{"base prompt", "Verification Failed: US-001", "test -f fixed", "FAIL: not fixed"} {
+		if !strings.Contains(reqs[1].Prompt, want) {
+			t.Errorf("Expected second prompt to contain %q, got:\n%s", want, reqs[1].Prompt)
+		}
+	}
+
+	var types []string
+	for _, e := range events {
+		switch e.Type {
+		case EventVerificationStarted, EventVerificationPassed, EventVerificationFailed, EventComplete:
+			types = append(types, e.Type.String())
+		}
+	}
+	want := "VerificationStarted,VerificationFailed,VerificationStarted,VerificationPassed,Complete"
+	if got := strings.Join(types, ","); got != want {
+		t.Errorf("Expected events %s, got %s", want, got)
+	}
+
+	p, err := prd.LoadPRD(prdPath)
+	if err != nil {
+		t.Fatalf("Failed to load PRD: %v", err)
+	}
+	if !p.AllComplete() {
+		t.Error("Expected story to pass after successful verification")
+	}
+
+	logData, err := os.ReadFile(RunLogPath(prdPath, 1))
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if !strings.Contains(string(logData), "[verify] FAIL: not fixed") {
+		t.Error("Expected verification output to be recorded in claude.log")
+	}
+}
+
+func TestLoop_VerificationSkipsPreviouslyPassedStories(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	agent := &fakeAgent{runs: []fakeRun{{onStart: markAllPassed(t, prdPath)}}}
+
+	l := NewLoopWithWorkDir(prdPath, tmpDir, "base prompt", 5)
+	l.SetAgent(agent)
+	// Only a different story has a check, so US-001 is accepted as is
+	l.SetVerifyConfig(config.VerifyConfig{
+		Stories: map[string][]string{"US-999": {"exit 1"}},
+	})
+
+	events, err := collectEvents(t, l)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	for _, e := range events {
+		if e.Type == EventVerificationStarted {
+			t.Errorf("Expected no verification for US-001, got %+v", e)
+		}
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	if got := tailLines("a\nb\nc", 2); got != "b\nc" {
+		t.Errorf("tailLines() = %q, want %q", got, "b\nc")
+	}
+	if got := tailLines("a", 2); got != "a" {
+		t.Errorf("tailLines() = %q, want %q", got, "a")
+	}
+}
@@ -0,0 +1,60 @@
+package loop
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// WrapperError is returned when the wrapper command that launches the agent
+// (for example a sandbox like bubblewrap or firejail) fails, as opposed to
+// the agent itself. Wrapper failures are configuration problems and aren't
+// retried.
+type WrapperError struct {
+	Wrapper string // Wrapper command
+	Err     error
+	Stderr  string // Last line the wrapper wrote to stderr, if any
+}
+
+func (e *WrapperError) Error() string {
+	msg := fmt.Sprintf("agent wrapper %s failed: %v", e.Wrapper, e.Err)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *WrapperError) Unwrap() error {
+	return e.Err
+}
+
+// CheckWrapper verifies that a wrapper command template can be run.
+func CheckWrapper(wrapper []string) error {
+	if len(wrapper) == 0 {
+		return nil
+	}
+	if wrapper[0] == "" {
+		return fmt.Errorf("agent wrapper has an empty command")
+	}
+	if _, err := exec.LookPath(wrapper[0]); err != nil {
+		return &WrapperError{Wrapper: wrapper[0], Err: err}
+	}
+	return nil
+}
+
+// wrapCommand returns the command line that runs command inside the wrapper.
+// The placeholders {{WORK_DIR}} and {{PRD_DIR}} are substituted in the
+// wrapper, and the agent's command and arguments are appended to it.
+func wrapCommand(wrapper []string, workDir, prdDir, command string, args []string) (string, []string) {
+	if len(wrapper) == 0 {
+		return command, args
+	}
+	r := strings.NewReplacer("{{WORK_DIR}}", workDir, "{{PRD_DIR}}", prdDir)
+	wrapped := make([]string, 0, len(wrapper)+len(args))
+	for _, arg := range wrapper[1:] {
+		wrapped = append(wrapped, r.Replace(arg))
+	}
+	wrapped = append(wrapped, command)
+	wrapped = append(wrapped, args...)
+	return r.Replace(wrapper[0]), wrapped
+}
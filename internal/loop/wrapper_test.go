@@ -0,0 +1,93 @@
+package loop
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWrapCommand(t *testing.T) {
+	command, args := wrapCommand(nil, "/work", "/prd", "claude", []string{"-p", "hi"})
+	if command != "claude" || strings.Join(args, " ") != "-p hi" {
+		t.Errorf("Expected the command unchanged without a wrapper, got %s %v", command, args)
+	}
+
+	wrapper := []string{"bwrap", "--bind", "{{WORK_DIR}}", "{{WORK_DIR}}", "--bind", "{{PRD_DIR}}", "{{PRD_DIR}}", "--"}
+	command, args = wrapCommand(wrapper, "/work", "/prd", "claude", []string{"-p", "hi"})
+	if command != "bwrap" {
+		t.Errorf("Expected bwrap, got %s", command)
+	}
+	want := "--bind /work /work --bind /prd /prd -- claude -p hi"
+	if got := strings.Join(args, " "); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCheckWrapper(t *testing.T) {
+	if err := CheckWrapper(nil); err != nil {
+		t.Errorf("Expected no error without a wrapper, got %v", err)
+	}
+	if err := CheckWrapper([]string{"sh", "-c"}); err != nil {
+		t.Errorf("Expected sh to be found, got %v", err)
+	}
+
+	err := CheckWrapper([]string{"chief-missing-sandbox"})
+	var wrapperErr *WrapperError
+	if !errors.As(err, &wrapperErr) {
+		t.Fatalf("Expected a WrapperError for a missing wrapper, got %v", err)
+	}
+	if wrapperErr.Wrapper != "chief-missing-sandbox" {
+		t.Errorf("Expected the wrapper command in the error, got %q", wrapperErr.Wrapper)
+	}
+}
+
+func TestCommandAgent_StartWrapped(t *testing.T) {
+	workDir := t.TempDir()
+	agent := &CommandAgent{Command: "sh", Args: []string{"-c", `echo "$WRAPPED"`}, Output: OutputText}
+
+	proc, err := agent.Start(context.Background(), AgentRequest{
+		Prompt:  "p",
+		WorkDir: workDir,
+		PRDDir:  "/prds/main",
+		Wrapper: []string{"env", "WRAPPED={{WORK_DIR}}:{{PRD_DIR}}"},
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	out, _ := io.ReadAll(proc.Stdout())
+	io.ReadAll(proc.Stderr())
+	if err := proc.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	if got := strings.TrimSpace(string(out)); got != workDir+":/prds/main" {
+		t.Errorf("Expected the agent to run inside the wrapper, got %q", got)
+	}
+}
+
+func TestLoop_RunWrapperFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := createTestPRD(t, tmpDir, false)
+
+	l := NewLoop(prdPath, "test prompt", 3)
+	l.SetAgent(&CommandAgent{Command: "cat", Output: OutputText})
+	l.SetWrapper([]string{"sh", "-c", "echo 'sandbox: permission denied' >&2; exit 1", "sandbox"})
+	l.SetRetryConfig(RetryConfig{MaxRetries: 2, Enabled: true})
+
+	events, err := collectEvents(t, l)
+	var wrapperErr *WrapperError
+	if !errors.As(err, &wrapperErr) {
+		t.Fatalf("Expected a WrapperError, got %v", err)
+	}
+	if wrapperErr.Stderr != "sandbox: permission denied" {
+		t.Errorf("Expected the wrapper's stderr in the error, got %q", wrapperErr.Stderr)
+	}
+
+	for _, e := range events {
+		if e.Type == EventRetrying {
+			t.Error("Expected wrapper failures not to be retried")
+		}
+	}
+}
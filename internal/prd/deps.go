@@ -0,0 +1,151 @@
+package prd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks the story dependency graph. It returns an error if a story
+// depends on an unknown story or on itself, or if the dependencies form a cycle.
+// It also rejects agentArgs that would change the agent's permissions.
+func (p *PRD) Validate() error {
+	if err := checkAgentArgs("PRD", p.AgentArgs); err != nil {
+		return err
+	}
+	for _, story := range p.UserStories {
+		if err := checkAgentArgs("story "+story.ID, story.AgentArgs); err != nil {
+			return err
+		}
+	}
+
+	index := make(map[string]int, len(p.UserStories))
+	for i, story := range p.UserStories {
+		if _, ok := index[story.ID]; !ok {
+			index[story.ID] = i
+		}
+	}
+
+	for _, story := range p.UserStories {
+		for _, dep := range story.DependsOn {
+			if dep == story.ID {
+				return fmt.Errorf("story %s depends on itself", story.ID)
+			}
+			if _, ok := index[dep]; !ok {
+				return fmt.Errorf("story %s depends on unknown story %q", story.ID, dep)
+			}
+		}
+	}
+
+	// Depth-first search for cycles, keeping the current path for the message
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(p.UserStories))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		story := p.UserStories[i]
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for j, id := range path {
+				if id == story.ID {
+					start = j
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), story.ID)
+			return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " → "))
+		}
+
+		state[i] = visiting
+		path = append(path, story.ID)
+		for _, dep := range story.DependsOn {
+			if err := visit(index[dep]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = done
+		return nil
+	}
+	for i := range p.UserStories {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// permissionFlags are the agent flags that decide what the agent may do.
+// They may only come from .chief/config.yaml: the agent can edit prd.json,
+// so flags in it could override the configured permission profile.
+var permissionFlags = []string{
+	"--dangerously-skip-permissions",
+	"--allowedTools", "--allowed-tools",
+	"--disallowedTools", "--disallowed-tools",
+	"--add-dir",
+	"--permission-mode",
+	"--permission-prompt-tool",
+}
+
+// checkAgentArgs returns an error if args contain a permission flag.
+func checkAgentArgs(owner string, args []string) error {
+	for _, arg := range args {
+		for _, flag := range permissionFlags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				return fmt.Errorf("%s agentArgs can't contain %s; set permissions in .chief/config.yaml", owner, flag)
+			}
+		}
+	}
+	return nil
+}
+
+// BlockedBy returns the IDs of the stories that story depends on and that
+// haven't passed yet. Unknown IDs are reported as blocking.
+func (p *PRD) BlockedBy(story *UserStory) []string {
+	if len(story.DependsOn) == 0 {
+		return nil
+	}
+	passed := make(map[string]bool, len(p.UserStories))
+	for _, s := range p.UserStories {
+		if s.Passes {
+			passed[s.ID] = true
+		}
+	}
+	var blocking []string
+	for _, dep := range story.DependsOn {
+		if !passed[dep] {
+			blocking = append(blocking, dep)
+		}
+	}
+	return blocking
+}
+
+// IsBlocked returns true if any dependency of story hasn't passed yet.
+// Stories that have passed are never blocked.
+func (p *PRD) IsBlocked(story *UserStory) bool {
+	return !story.Passes && len(p.BlockedBy(story)) > 0
+}
+
+// ReadyStories returns the stories that haven't passed and aren't blocked,
+// lowest priority number first. These stories are independent of each
+// other's remaining work and can be worked on at the same time.
+func (p *PRD) ReadyStories() []*UserStory {
+	var ready []*UserStory
+	for i := range p.UserStories {
+		story := &p.UserStories[i]
+		if !story.Passes && !story.Blocked && !p.IsBlocked(story) {
+			ready = append(ready, story)
+		}
+	}
+	sort.SliceStable(ready, func(i, j int) bool {
+		return ready[i].Priority < ready[j].Priority
+	})
+	return ready
+}
@@ -0,0 +1,171 @@
+package prd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPRD_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		stories []UserStory
+		wantErr string
+	}{
+		{
+			name: "no dependencies",
+			stories: []UserStory{
+				{ID: "US-001"},
+				{ID: "US-002"},
+			},
+		},
+		{
+			name: "valid chain",
+			stories: []UserStory{
+				{ID: "US-001"},
+				{ID: "US-002", DependsOn: []string{"US-001"}},
+				{ID: "US-003", DependsOn: []string{"US-001", "US-002"}},
+			},
+		},
+		{
+			name: "unknown dependency",
+			stories: []UserStory{
+				{ID: "US-001", DependsOn: []string{"US-099"}},
+			},
+			wantErr: `story US-001 depends on unknown story "US-099"`,
+		},
+		{
+			name: "self dependency",
+			stories: []UserStory{
+				{ID: "US-001", DependsOn: []string{"US-001"}},
+			},
+			wantErr: "story US-001 depends on itself",
+		},
+		{
+			name: "cycle",
+			stories: []UserStory{
+				{ID: "US-001", DependsOn: []string{"US-003"}},
+				{ID: "US-002", DependsOn: []string{"US-001"}},
+				{ID: "US-003", DependsOn: []string{"US-002"}},
+			},
+			wantErr: "dependency cycle: US-001 → US-003 → US-002 → US-001",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PRD{Project: "Test", UserStories: tt.stories}
+			err := p.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error %q, got nil", tt.wantErr)
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("Expected error %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestPRD_ValidateRejectsPermissionFlags(t *testing.T) {
+	p := &PRD{Project: "Test", AgentArgs: []string{"--max-turns", "50"}, UserStories: []UserStory{{ID: "US-001"}}}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Expected other agentArgs to be allowed, got %v", err)
+	}
+
+	p.AgentArgs = []string{"--dangerously-skip-permissions"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "PRD agentArgs can't contain --dangerously-skip-permissions") {
+		t.Errorf("Expected the PRD's permission flag to be rejected, got %v", err)
+	}
+
+	p.AgentArgs = nil
+	p.UserStories[0].AgentArgs = []string{"--allowedTools=Bash(*)"}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "story US-001 agentArgs can't contain --allowedTools") {
+		t.Errorf("Expected the story's permission flag to be rejected, got %v", err)
+	}
+}
+
+func TestPRD_BlockedBy(t *testing.T) {
+	p := &PRD{
+		Project: "Test",
+		UserStories: []UserStory{
+			{ID: "US-001", Passes: true},
+			{ID: "US-002"},
+			{ID: "US-003", DependsOn: []string{"US-001", "US-002"}},
+			{ID: "US-004", Passes: true, DependsOn: []string{"US-002"}},
+		},
+	}
+
+	blocked := p.BlockedBy(&p.UserStories[2])
+	if len(blocked) != 1 || blocked[0] != "US-002" {
+		t.Errorf("Expected US-003 to be blocked by [US-002], got %v", blocked)
+	}
+	if !p.IsBlocked(&p.UserStories[2]) {
+		t.Error("Expected US-003 to be blocked")
+	}
+	if p.IsBlocked(&p.UserStories[1]) {
+		t.Error("Expected US-002 without dependencies not to be blocked")
+	}
+	if p.IsBlocked(&p.UserStories[3]) {
+		t.Error("Expected passed story US-004 not to be blocked")
+	}
+}
+
+func TestPRD_ReadyStories(t *testing.T) {
+	p := &PRD{
+		Project: "Test",
+		UserStories: []UserStory{
+			{ID: "US-001", Priority: 3},
+			{ID: "US-002", Priority: 1, Passes: true},
+			{ID: "US-003", Priority: 2, DependsOn: []string{"US-002"}},
+			{ID: "US-004", Priority: 1, DependsOn: []string{"US-001"}},
+		},
+	}
+
+	ready := p.ReadyStories()
+	var ids []string
+	for _, story := range ready {
+		ids = append(ids, story.ID)
+	}
+	if strings.Join(ids, ",") != "US-003,US-001" {
+		t.Errorf("Expected ready stories [US-003 US-001], got %v", ids)
+	}
+}
+
+func TestLoadPRD_InvalidDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	prdPath := filepath.Join(tmpDir, "prd.json")
+	content := `{"project":"Test","userStories":[{"id":"US-001","dependsOn":["US-002"]}]}`
+	if err := os.WriteFile(prdPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write PRD: %v", err)
+	}
+
+	_, err := LoadPRD(prdPath)
+	if err == nil {
+		t.Fatal("Expected error for unknown dependency, got nil")
+	}
+	if !strings.Contains(err.Error(), "unknown story") {
+		t.Errorf("Expected unknown story error, got %v", err)
+	}
+}
+
+func TestParseAndValidatePRD_DependencyCycle(t *testing.T) {
+	jsonStr := `{"project":"Test","userStories":[
+		{"id":"US-001","dependsOn":["US-002"]},
+		{"id":"US-002","dependsOn":["US-001"]}
+	]}`
+
+	_, err := parseAndValidatePRD(jsonStr)
+	if err == nil {
+		t.Fatal("Expected error for dependency cycle, got nil")
+	}
+	if !strings.Contains(err.Error(), "dependency cycle") {
+		t.Errorf("Expected dependency cycle error, got %v", err)
+	}
+}
@@ -55,9 +55,10 @@ var waitingJokes = []string{
 
 // ConvertOptions contains configuration for PRD conversion.
 type ConvertOptions struct {
-	PRDDir string // Directory containing prd.md
-	Merge  bool   // Auto-merge progress on conversion conflicts
-	Force  bool   // Auto-overwrite on conversion conflicts
+	PRDDir        string // Directory containing prd.md
+	Merge         bool   // Auto-merge progress on conversion conflicts
+	Force         bool   // Auto-overwrite on conversion conflicts
+	ClaudeCommand string // Claude binary to run (default: "claude")
 }
 
 // ProgressConflictChoice represents the user's choice when a progress conflict is detected.
@@ -104,8 +105,13 @@ func Convert(opts ConvertOptions) error {
 		hasProgress = HasProgress(existing)
 	}
 
+	claudeCmd := opts.ClaudeCommand
+	if claudeCmd == "" {
+		claudeCmd = "claude"
+	}
+
 	// Run Claude to convert prd.md → JSON string
-	rawJSON, err := runClaudeConversion(absPRDDir)
+	rawJSON, err := runClaudeConversion(claudeCmd, absPRDDir)
 	if err != nil {
 		return err
 	}
@@ -119,7 +125,7 @@ func Convert(opts ConvertOptions) error {
 		// Retry once: ask Claude to fix the invalid JSON
 		fmt.Println("Conversion produced invalid JSON, retrying...")
 		fmt.Printf("Raw output:\n---\n%s\n---\n", cleanedJSON)
-		fixedJSON, retryErr := runClaudeJSONFix(cleanedJSON, err)
+		fixedJSON, retryErr := runClaudeJSONFix(claudeCmd, cleanedJSON, err)
 		if retryErr != nil {
 			return fmt.Errorf("conversion retry failed: %w", retryErr)
 		}
@@ -181,7 +187,7 @@ func Convert(opts ConvertOptions) error {
 }
 
 // runClaudeConversion reads prd.md, sends content inline to Claude, and returns the JSON output.
-func runClaudeConversion(absPRDDir string) (string, error) {
+func runClaudeConversion(claudeCmd, absPRDDir string) (string, error) {
 	content, err := os.ReadFile(filepath.Join(absPRDDir, "prd.md"))
 	if err != nil {
 		return "", fmt.Errorf("failed to read prd.md: %w", err)
@@ -189,7 +195,7 @@ func runClaudeConversion(absPRDDir string) (string, error) {
 
 	prompt := embed.GetConvertPrompt(string(content))
 
-	cmd := exec.Command("claude", "-p", "--tools", "")
+	cmd := exec.Command(claudeCmd, "-p", "--tools", "")
 	cmd.Dir = absPRDDir
 	cmd.Stdin = strings.NewReader(prompt)
 
@@ -209,7 +215,7 @@ func runClaudeConversion(absPRDDir string) (string, error) {
 }
 
 // runClaudeJSONFix asks Claude to fix invalid JSON inline and returns the corrected output.
-func runClaudeJSONFix(badJSON string, validationErr error) (string, error) {
+func runClaudeJSONFix(claudeCmd, badJSON string, validationErr error) (string, error) {
 	fixPrompt := fmt.Sprintf(
 		"The following JSON is invalid. The error is: %s\n\n"+
 			"Fix the JSON (pay special attention to escaping double quotes inside string values with backslashes) "+
@@ -217,7 +223,7 @@ func runClaudeJSONFix(badJSON string, validationErr error) (string, error) {
 		validationErr.Error(), badJSON,
 	)
 
-	cmd := exec.Command("claude", "-p", fixPrompt)
+	cmd := exec.Command(claudeCmd, "-p", fixPrompt)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -246,6 +252,9 @@ func parseAndValidatePRD(jsonStr string) (*PRD, error) {
 	if len(prd.UserStories) == 0 {
 		return nil, fmt.Errorf("prd.json has no user stories")
 	}
+	if err := prd.Validate(); err != nil {
+		return nil, fmt.Errorf("prd.json has invalid dependencies: %w", err)
+	}
 	return &prd, nil
 }
 
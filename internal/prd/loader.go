@@ -17,6 +17,9 @@ func LoadPRD(path string) (*PRD, error) {
 	if err := json.Unmarshal(data, &p); err != nil {
 		return nil, fmt.Errorf("failed to parse PRD JSON: %w", err)
 	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid PRD: %w", err)
+	}
 
 	return &p, nil
 }
@@ -3,6 +3,7 @@ package prd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -254,6 +255,69 @@ func TestPRD_NextStory_InterruptedTakesPrecedence(t *testing.T) {
 	}
 }
 
+func TestPRD_NextStory_SkipsBlocked(t *testing.T) {
+	p := &PRD{
+		Project: "Test",
+		UserStories: []UserStory{
+			{ID: "US-001", Priority: 3, Passes: false},
+			{ID: "US-002", Priority: 1, Passes: false, DependsOn: []string{"US-001"}},
+			{ID: "US-003", Priority: 2, Passes: false, InProgress: true, DependsOn: []string{"US-002"}},
+		},
+	}
+
+	next := p.NextStory()
+	if next == nil {
+		t.Fatal("expected non-nil story")
+	}
+	if next.ID != "US-001" {
+		t.Errorf("expected unblocked story US-001, got %s", next.ID)
+	}
+
+	p.UserStories[0].Passes = true
+	next = p.NextStory()
+	if next == nil || next.ID != "US-002" {
+		t.Errorf("expected US-002 once its dependency passed, got %v", next)
+	}
+}
+
+func TestPRD_NextStory_AllBlocked(t *testing.T) {
+	p := &PRD{
+		Project: "Test",
+		UserStories: []UserStory{
+			{ID: "US-001", Passes: false, DependsOn: []string{"US-002"}},
+			{ID: "US-002", Passes: false, DependsOn: []string{"US-001"}},
+		},
+	}
+
+	if next := p.NextStory(); next != nil {
+		t.Errorf("expected nil when every story is blocked, got %v", next)
+	}
+}
+
+func TestPRD_NextStory_SkipsAttemptBlocked(t *testing.T) {
+	p := &PRD{
+		Project: "Test",
+		UserStories: []UserStory{
+			{ID: "US-001", Priority: 1, InProgress: true, Blocked: true, Attempts: 3},
+			{ID: "US-002", Priority: 2, DependsOn: []string{"US-001"}},
+			{ID: "US-003", Priority: 3},
+		},
+	}
+
+	next := p.NextStory()
+	if next == nil || next.ID != "US-003" {
+		t.Errorf("expected US-003 past the blocked story and its dependent, got %v", next)
+	}
+	if ready := p.ReadyStories(); len(ready) != 1 || ready[0].ID != "US-003" {
+		t.Errorf("expected only US-003 to be ready, got %v", ready)
+	}
+
+	p.UserStories[2].Passes = true
+	if next := p.NextStory(); next != nil {
+		t.Errorf("expected nil when only blocked stories remain, got %v", next)
+	}
+}
+
 func TestUserStory_Fields(t *testing.T) {
 	story := UserStory{
 		ID:                 "US-TEST",
@@ -303,3 +367,34 @@ func TestPRD_Save_PreservesInProgress(t *testing.T) {
 		t.Error("expected InProgress to be preserved as true")
 	}
 }
+
+func TestPRD_ModelAndAgentArgsFor(t *testing.T) {
+	p := &PRD{
+		Model:     "sonnet",
+		AgentArgs: []string{"--max-turns", "50"},
+		UserStories: []UserStory{
+			{ID: "US-001"},
+			{ID: "US-002", Model: "opus", AgentArgs: []string{"--max-turns", "100"}},
+		},
+	}
+
+	if got := p.ModelFor(&p.UserStories[0]); got != "sonnet" {
+		t.Errorf("expected PRD model sonnet, got %q", got)
+	}
+	if got := p.ModelFor(&p.UserStories[1]); got != "opus" {
+		t.Errorf("expected story model opus, got %q", got)
+	}
+	if got := p.ModelFor(nil); got != "sonnet" {
+		t.Errorf("expected PRD model for no story, got %q", got)
+	}
+
+	if got := strings.Join(p.AgentArgsFor(&p.UserStories[0]), " "); got != "--max-turns 50" {
+		t.Errorf("expected PRD args, got %q", got)
+	}
+	if got := strings.Join(p.AgentArgsFor(&p.UserStories[1]), " "); got != "--max-turns 50 --max-turns 100" {
+		t.Errorf("expected PRD args followed by story args, got %q", got)
+	}
+	if len(p.AgentArgs) != 2 {
+		t.Errorf("expected PRD args to be left unchanged, got %v", p.AgentArgs)
+	}
+}
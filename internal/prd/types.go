@@ -12,13 +12,24 @@ type UserStory struct {
 	Priority           int      `json:"priority"`
 	Passes             bool     `json:"passes"`
 	InProgress         bool     `json:"inProgress,omitempty"`
+	DependsOn          []string `json:"dependsOn,omitempty"`
+	Model              string   `json:"model,omitempty"`         // Agent model for this story (overrides the PRD's)
+	AgentArgs          []string `json:"agentArgs,omitempty"`     // Extra agent arguments, added after the PRD's
+	Attempts           int      `json:"attempts,omitempty"`      // Iterations spent on the story so far
+	Blocked            bool     `json:"blocked,omitempty"`       // Given up on after too many attempts; skipped until cleared
+	BlockedReason      string   `json:"blockedReason,omitempty"` // Why the story was blocked, from the agent's last message
 }
 
 // PRD represents a Product Requirements Document.
 type PRD struct {
-	Project     string      `json:"project"`
-	Description string      `json:"description"`
-	UserStories []UserStory `json:"userStories"`
+	Project         string      `json:"project"`
+	Description     string      `json:"description"`
+	UserStories     []UserStory `json:"userStories"`
+	Model           string      `json:"model,omitempty"`           // Agent model for every story (overrides the config)
+	AgentArgs       []string    `json:"agentArgs,omitempty"`       // Extra agent arguments for every story
+	Priority        int         `json:"priority,omitempty"`        // Start order when loops are queued by priority (lower first, 0 = last)
+	After           []string    `json:"after,omitempty"`           // PRDs that must complete before this one starts automatically
+	BranchFromAfter bool        `json:"branchFromAfter,omitempty"` // Base the worktree branch on the predecessor's branch instead of the default branch
 }
 
 // AllComplete returns true when all stories have passes: true.
@@ -36,14 +47,18 @@ func (p *PRD) AllComplete() bool {
 
 // NextStory returns the next story to work on.
 // It returns:
-//   - First story with inProgress: true (interrupted story), or
-//   - Lowest priority story with passes: false, or
-//   - nil if all stories are complete
+//   - First unblocked story with inProgress: true (interrupted story), or
+//   - Lowest priority unblocked story with passes: false, or
+//   - nil if all stories are complete or blocked
+//
+// A story is blocked while any story it depends on hasn't passed, or when
+// it has been marked blocked after too many attempts.
 func (p *PRD) NextStory() *UserStory {
 	// First, check for any in-progress story (interrupted)
 	for i := range p.UserStories {
-		if p.UserStories[i].InProgress {
-			return &p.UserStories[i]
+		story := &p.UserStories[i]
+		if story.InProgress && !story.Blocked && !p.IsBlocked(story) {
+			return story
 		}
 	}
 
@@ -51,7 +66,7 @@ func (p *PRD) NextStory() *UserStory {
 	var next *UserStory
 	for i := range p.UserStories {
 		story := &p.UserStories[i]
-		if !story.Passes {
+		if !story.Passes && !story.Blocked && !p.IsBlocked(story) {
 			if next == nil || story.Priority < next.Priority {
 				next = story
 			}
@@ -59,3 +74,22 @@ func (p *PRD) NextStory() *UserStory {
 	}
 	return next
 }
+
+// ModelFor returns the agent model for a story: the story's own model if set,
+// otherwise the PRD's. An empty string means the configured default.
+func (p *PRD) ModelFor(story *UserStory) string {
+	if story != nil && story.Model != "" {
+		return story.Model
+	}
+	return p.Model
+}
+
+// AgentArgsFor returns the extra agent arguments for a story: the PRD's
+// arguments followed by the story's.
+func (p *PRD) AgentArgsFor(story *UserStory) []string {
+	args := append([]string(nil), p.AgentArgs...)
+	if story != nil {
+		args = append(args, story.AgentArgs...)
+	}
+	return args
+}
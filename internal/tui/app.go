@@ -10,6 +10,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/daemon"
 	"github.com/minicodemonkey/chief/internal/git"
 	"github.com/minicodemonkey/chief/internal/loop"
 	"github.com/minicodemonkey/chief/internal/prd"
@@ -163,11 +164,13 @@ type App struct {
 	err           error
 
 	// Loop manager for parallel PRD execution
-	manager *loop.Manager
+	manager LoopManager
+	daemon  *daemon.Client // Set when attached to a chief serve daemon
 	maxIter int
 
 	// Activity tracking
 	lastActivity string
+	agentModel   string // Model of the current iteration, shown on the activity line
 
 	// File watching
 	watcher         *prd.Watcher
@@ -223,6 +226,9 @@ type App struct {
 	// Verbose mode - show raw Claude output
 	verbose bool
 
+	// Optional NDJSON sink for loop events
+	eventWriter *loop.JSONEventWriter
+
 	// Post-exit action - what to do after TUI exits
 	PostExitAction PostExitAction
 	PostExitPRD    string // PRD name for post-exit action
@@ -291,6 +297,11 @@ func NewAppWithOptions(prdPath string, maxIter int) (*App, error) {
 		cfg = config.Default()
 	}
 
+	// Fail early rather than on the first iteration if the sandbox is missing
+	if err := loop.CheckWrapper(cfg.Agent.Wrapper); err != nil {
+		return nil, fmt.Errorf("invalid agent wrapper: %w", err)
+	}
+
 	// Prune stale worktrees on startup (clean git's internal tracking)
 	if git.IsGitRepo(baseDir) {
 		_ = git.PruneWorktrees(baseDir)
@@ -300,13 +311,23 @@ func NewAppWithOptions(prdPath string, maxIter int) (*App, error) {
 	progressWatcher, _ := prd.NewProgressWatcher(prdPath)
 	progress, _ := prd.ParseProgress(prd.ProgressPath(prdPath))
 
-	// Create loop manager for parallel PRD execution
-	manager := loop.NewManager(maxIter)
-	manager.SetBaseDir(baseDir)
-	manager.SetConfig(cfg)
+	// Attach to the project's daemon if one is running, so its loops keep
+	// going when the TUI quits; otherwise run loops in this process
+	var manager LoopManager
+	client, err := daemon.Dial(daemon.SocketPath(baseDir))
+	if err == nil {
+		manager = client
+	} else {
+		local := loop.NewManager(maxIter)
+		local.SetBaseDir(baseDir)
+		local.SetConfig(cfg)
+		manager = local
+	}
 
 	// Register the initial PRD with the manager
-	manager.Register(prdName, prdPath)
+	if manager.GetInstance(prdName) == nil {
+		manager.Register(prdName, prdPath)
+	}
 
 	// Create tab bar for always-visible PRD tabs
 	tabBar := NewTabBar(baseDir, prdName, manager)
@@ -314,7 +335,7 @@ func NewAppWithOptions(prdPath string, maxIter int) (*App, error) {
 	// Create picker with manager reference (for creating new PRDs)
 	picker := NewPRDPicker(baseDir, prdName, manager)
 
-	return &App{
+	app := &App{
 		prd:           p,
 		prdPath:       prdPath,
 		prdName:       prdName,
@@ -323,6 +344,7 @@ func NewAppWithOptions(prdPath string, maxIter int) (*App, error) {
 		selectedIndex: 0,
 		maxIter:       maxIter,
 		manager:       manager,
+		daemon:        client,
 		watcher:         watcher,
 		progressWatcher: progressWatcher,
 		progress:        progress,
@@ -339,14 +361,23 @@ func NewAppWithOptions(prdPath string, maxIter int) (*App, error) {
 		completionScreen: NewCompletionScreen(),
 		settingsOverlay:  NewSettingsOverlay(),
 		quitConfirm:     NewQuitConfirmation(),
-	}, nil
+	}
+
+	// Show what happened in the last run, or the one still going in the daemon
+	running := false
+	if app.daemon != nil {
+		running = app.syncLoopState()
+	}
+	app.loadJournal(running)
+
+	return app, nil
 }
 
 // SetCompletionCallback sets a callback that is called when any PRD completes.
 func (a *App) SetCompletionCallback(fn func(prdName string)) {
 	a.onCompletion = fn
-	if a.manager != nil {
-		a.manager.SetCompletionCallback(fn)
+	if m := a.localManager(); m != nil {
+		m.SetCompletionCallback(fn)
 	}
 }
 
@@ -355,11 +386,36 @@ func (a *App) SetVerbose(v bool) {
 	a.verbose = v
 }
 
-// DisableRetry disables automatic retry on Claude crashes.
-func (a *App) DisableRetry() {
-	if a.manager != nil {
-		a.manager.DisableRetry()
+// SetEventWriter mirrors every loop event to w as NDJSON.
+func (a *App) SetEventWriter(w *loop.JSONEventWriter) {
+	a.eventWriter = w
+}
+
+// SetBudget sets per-run and per-story budgets, overriding the project config.
+// Attached to a daemon, the daemon's budgets apply instead, so setting any
+// returns ErrAttached.
+func (a *App) SetBudget(prdBudget, storyBudget loop.Budget) error {
+	m := a.localManager()
+	if m == nil {
+		if !prdBudget.IsZero() || !storyBudget.IsZero() {
+			return ErrAttached
+		}
+		return nil
 	}
+	m.SetBudget(prdBudget, storyBudget)
+	return nil
+}
+
+// DisableRetry disables automatic retry on Claude crashes.
+// Attached to a daemon, the daemon's setting applies instead and ErrAttached
+// is returned.
+func (a *App) DisableRetry() error {
+	m := a.localManager()
+	if m == nil {
+		return ErrAttached
+	}
+	m.DisableRetry()
+	return nil
 }
 
 // Init initializes the App.
@@ -377,12 +433,17 @@ func (a App) Init() tea.Cmd {
 		_ = a.progressWatcher.Start()
 	}
 
-	return tea.Batch(
+	cmds := []tea.Cmd{
 		tea.EnterAltScreen,
 		a.listenForPRDChanges(),
 		a.listenForManagerEvents(),
 		a.listenForProgressChanges(),
-	)
+	}
+	// A run picked up from the daemon keeps its elapsed time ticking
+	if a.state == StateRunning {
+		cmds = append(cmds, tickElapsed())
+	}
+	return tea.Batch(cmds...)
 }
 
 // listenForManagerEvents listens for events from all managed loops.
@@ -390,11 +451,19 @@ func (a *App) listenForManagerEvents() tea.Cmd {
 	if a.manager == nil {
 		return nil
 	}
+	eventWriter := a.eventWriter
+	attached := a.daemon != nil
 	return func() tea.Msg {
 		event, ok := <-a.manager.Events()
 		if !ok {
+			if attached {
+				return daemonDetachedMsg{}
+			}
 			return nil
 		}
+		if eventWriter != nil {
+			_ = eventWriter.Write(event)
+		}
 		return LoopEventMsg{PRDName: event.PRDName, Event: event.Event}
 	}
 }
@@ -437,6 +506,10 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case autoActionResultMsg:
 		return a.handleAutoActionResult(msg)
 
+	case daemonDetachedMsg:
+		a.lastActivity = "Lost connection to chief serve; restart chief to reattach"
+		return a, nil
+
 	case backgroundAutoActionResultMsg:
 		return a.handleBackgroundAutoAction(msg)
 
@@ -647,7 +720,7 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return a.pauseLoop()
 			}
 		case "x":
-			if a.state == StateRunning || a.state == StatePaused {
+			if a.state == StateRunning || a.state == StatePaused || a.isQueued(a.prdName) {
 				return a.stopLoopAndUpdate()
 			}
 
@@ -765,7 +838,7 @@ func (a *App) isAnotherPRDRunningInSameDir(prdName string) bool {
 		return false
 	}
 	for _, inst := range a.manager.GetAllInstances() {
-		if inst.Name != prdName && inst.State == loop.LoopStateRunning && inst.WorktreeDir == "" {
+		if inst.Name != prdName && (inst.State == loop.LoopStateRunning || inst.State == loop.LoopStateQueued) && inst.WorktreeDir == "" {
 			return true
 		}
 	}
@@ -787,15 +860,18 @@ func (a App) doStartLoop(prdName, prdDir string) (tea.Model, tea.Cmd) {
 		return a, nil
 	}
 
+	// Too many loops are running; the manager starts it later
+	if instance := a.manager.GetInstance(prdName); instance != nil && instance.State == loop.LoopStateQueued {
+		a.lastActivity = fmt.Sprintf("Queued %s (#%d): starts when another loop finishes", prdName, instance.QueuePosition)
+		if a.tabBar != nil {
+			a.tabBar.Refresh()
+		}
+		return a, nil
+	}
+
 	// Update state if this is the current PRD
 	if prdName == a.prdName {
-		a.state = StateRunning
-		a.startTime = time.Now()
-		a.lastActivity = "Starting loop..."
-		// Reset story timing state
-		a.storyTimings = nil
-		a.currentStoryID = ""
-		a.currentStoryStart = time.Time{}
+		a.beginRun()
 		return a, tickElapsed()
 	}
 
@@ -803,6 +879,26 @@ func (a App) doStartLoop(prdName, prdDir string) (tea.Model, tea.Cmd) {
 	return a, nil
 }
 
+// beginRun resets the dashboard for a run of the current PRD that just started.
+func (a *App) beginRun() {
+	a.state = StateRunning
+	a.startTime = time.Now()
+	a.lastActivity = "Starting loop..."
+	// Reset story timing state
+	a.storyTimings = nil
+	a.currentStoryID = ""
+	a.currentStoryStart = time.Time{}
+}
+
+// isQueued reports whether a PRD is waiting in the manager's run queue.
+func (a *App) isQueued(prdName string) bool {
+	if a.manager == nil {
+		return false
+	}
+	state, _, _ := a.manager.GetState(prdName)
+	return state == loop.LoopStateQueued
+}
+
 // pauseLoop sets the pause flag so the loop stops after the current iteration.
 func (a App) pauseLoop() (tea.Model, tea.Cmd) {
 	return a.pauseLoopForPRD(a.prdName)
@@ -850,17 +946,23 @@ func (a App) stopLoopAndUpdateForPRD(prdName string) (tea.Model, tea.Cmd) {
 	return a, nil
 }
 
-// stopAllLoops stops all running loops.
+// stopAllLoops stops all running loops. Attached to a daemon, it detaches
+// instead and the daemon's loops keep running.
 func (a *App) stopAllLoops() {
-	if a.manager != nil {
-		a.manager.StopAll()
+	if a.daemon != nil {
+		a.daemon.Close()
+		return
+	}
+	if m := a.localManager(); m != nil {
+		m.StopAll()
 	}
 }
 
 // tryQuit attempts to quit the app. If any loop is running, it shows the quit
-// confirmation dialog instead of quitting immediately.
+// confirmation dialog instead of quitting immediately. Attached to a daemon,
+// quitting only detaches, so there is nothing to confirm.
 func (a App) tryQuit() (tea.Model, tea.Cmd) {
-	if a.manager != nil && a.manager.IsAnyRunning() {
+	if a.daemon == nil && a.manager != nil && a.manager.IsAnyRunning() {
 		a.previousViewMode = a.viewMode
 		a.viewMode = ViewQuitConfirm
 		a.quitConfirm.Reset()
@@ -908,18 +1010,34 @@ func (a App) handleLoopEvent(prdName string, event loop.Event) (tea.Model, tea.C
 	// Only update iteration and log if this is the currently viewed PRD
 	isCurrentPRD := prdName == a.prdName
 
+	var autoActionCmd tea.Cmd
+
 	if isCurrentPRD {
+		// The manager starts queued and chained runs on its own, so follow the
+		// instance's state rather than guessing from the order of events
+		if a.state != StateRunning {
+			if instance := a.manager.GetInstance(prdName); instance != nil && instance.State == loop.LoopStateRunning {
+				a.beginRun()
+				a.startTime = instance.StartTime
+				autoActionCmd = tickElapsed()
+			}
+		}
 		a.iteration = event.Iteration
 		// Add event to log viewer
 		a.logViewer.AddEvent(event)
 	}
 
-	var autoActionCmd tea.Cmd
-
 	switch event.Type {
 	case loop.EventIterationStart:
 		if isCurrentPRD {
-			a.lastActivity = "Starting iteration..."
+			// Claude's init message repeats the event with the model it actually uses
+			if event.SessionID == "" {
+				a.lastActivity = "Starting iteration..."
+				a.agentModel = ""
+			}
+			if event.Model != "" {
+				a.agentModel = event.Model
+			}
 		}
 	case loop.EventAssistantText:
 		if isCurrentPRD {
@@ -967,6 +1085,28 @@ func (a App) handleLoopEvent(prdName string, event loop.Event) (tea.Model, tea.C
 			a.state = StatePaused
 			a.lastActivity = "Max iterations reached"
 		}
+	case loop.EventBudgetExceeded:
+		if isCurrentPRD {
+			a.state = StatePaused
+			a.lastActivity = event.Text
+		}
+	case loop.EventVerificationStarted, loop.EventVerificationPassed:
+		if isCurrentPRD {
+			a.lastActivity = event.Text
+		}
+	case loop.EventVerificationFailed:
+		if isCurrentPRD {
+			a.lastActivity = event.StoryID + " failed verification"
+		}
+	case loop.EventStoryMerged, loop.EventStoryFailed, loop.EventStoryBlocked:
+		if isCurrentPRD {
+			a.lastActivity = event.Text
+		}
+	case loop.EventAllStoriesBlocked, loop.EventNoProgress:
+		if isCurrentPRD {
+			a.state = StatePaused
+			a.lastActivity = event.Text
+		}
 	case loop.EventError:
 		if isCurrentPRD {
 			a.state = StateError
@@ -984,7 +1124,9 @@ func (a App) handleLoopEvent(prdName string, event loop.Event) (tea.Model, tea.C
 	// Reload PRD from disk only on meaningful state changes (not every event)
 	if isCurrentPRD {
 		switch event.Type {
-		case loop.EventStoryStarted, loop.EventComplete, loop.EventError, loop.EventMaxIterationsReached:
+		case loop.EventStoryStarted, loop.EventComplete, loop.EventError, loop.EventMaxIterationsReached, loop.EventBudgetExceeded,
+			loop.EventVerificationFailed, loop.EventStoryMerged, loop.EventStoryFailed, loop.EventStoryBlocked, loop.EventAllStoriesBlocked,
+			loop.EventNoProgress:
 			if p, err := prd.LoadPRD(a.prdPath); err == nil {
 				a.prd = p
 			}
@@ -997,7 +1139,9 @@ func (a App) handleLoopEvent(prdName string, event loop.Event) (tea.Model, tea.C
 		}
 
 		// Clear in-progress when the PRD completes or the loop stops
-		if event.Type == loop.EventComplete || event.Type == loop.EventError || event.Type == loop.EventMaxIterationsReached {
+		switch event.Type {
+		case loop.EventComplete, loop.EventError, loop.EventMaxIterationsReached, loop.EventBudgetExceeded, loop.EventAllStoriesBlocked,
+			loop.EventNoProgress:
 			a.clearInProgress()
 		}
 	}
@@ -1224,10 +1368,16 @@ func (a *App) cleanupWorktreeSetup() {
 
 // finalizeStoryTiming records the duration of the currently tracked story.
 func (a *App) finalizeStoryTiming() {
+	a.finalizeStoryTimingAt(time.Now())
+}
+
+// finalizeStoryTimingAt records the duration of the currently tracked story,
+// ending at end.
+func (a *App) finalizeStoryTimingAt(end time.Time) {
 	if a.currentStoryID == "" {
 		return
 	}
-	duration := time.Since(a.currentStoryStart)
+	duration := end.Sub(a.currentStoryStart)
 	title := a.currentStoryID
 	// Look up the story title from the PRD
 	for _, story := range a.prd.UserStories {
@@ -1245,6 +1395,48 @@ func (a *App) finalizeStoryTiming() {
 	a.currentStoryStart = time.Time{}
 }
 
+// loadJournal restores the log and story timings of the current PRD's latest
+// run from its event journal, so they survive a restart. If the run is still
+// going, the last story keeps being timed by live events.
+func (a *App) loadJournal(running bool) {
+	run := loop.LatestRun(a.prdPath)
+	if run == 0 {
+		return
+	}
+	events, err := loop.ReadJournal(loop.JournalPath(a.prdPath, run))
+	if err != nil || len(events) == 0 {
+		return
+	}
+
+	for _, event := range events {
+		a.logViewer.AddEvent(event)
+		switch event.Type {
+		case loop.EventStoryStarted:
+			a.finalizeStoryTimingAt(event.Time)
+			a.currentStoryID = event.StoryID
+			a.currentStoryStart = event.Time
+		case loop.EventComplete:
+			a.finalizeStoryTimingAt(event.Time)
+		}
+	}
+	if !running {
+		a.finalizeStoryTimingAt(events[len(events)-1].Time)
+	}
+}
+
+// logHint tells the user where to find the full output of the current PRD's
+// latest run.
+func (a *App) logHint() string {
+	logPath := loop.RunLogPath(a.prdPath, loop.LatestRun(a.prdPath))
+	if !loop.LogExists(logPath) {
+		logPath = loop.LogPath(a.prdPath)
+	}
+	if rel, err := filepath.Rel(a.baseDir, logPath); err == nil && !strings.HasPrefix(rel, "..") {
+		logPath = rel
+	}
+	return fmt.Sprintf("Run 'chief replay %s' or check %s for full error details.", a.prdName, logPath)
+}
+
 // showCompletionScreen configures and shows the completion screen for a PRD.
 // Returns a tea.Cmd if auto-actions need to be started, nil otherwise.
 func (a *App) showCompletionScreen(prdName string) tea.Cmd {
@@ -1274,6 +1466,7 @@ func (a *App) showCompletionScreen(prdName string) tea.Cmd {
 
 	totalDuration := a.GetElapsedTime()
 	a.completionScreen.Configure(prdName, completed, total, branch, commitCount, hasAutoActions, totalDuration, a.storyTimings)
+	a.completionScreen.SetUsage(a.GetUsage())
 	a.completionScreen.SetSize(a.width, a.height)
 	a.viewMode = ViewCompletion
 
@@ -1935,7 +2128,7 @@ func (a App) handlePickerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		entry := a.picker.GetSelectedEntry()
 		if entry != nil {
 			state := entry.LoopState
-			if state == loop.LoopStateRunning || state == loop.LoopStatePaused {
+			if state == loop.LoopStateRunning || state == loop.LoopStatePaused || state == loop.LoopStateQueued {
 				model, cmd := a.stopLoopAndUpdateForPRD(entry.Name)
 				a.picker.Refresh()
 				return model, cmd
@@ -2066,6 +2259,7 @@ func (a App) switchToPRD(name, prdPath string) (tea.Model, tea.Cmd) {
 		a.startTime = time.Time{}
 	}
 	a.lastActivity = "Switched to PRD: " + name
+	a.agentModel = ""
 	a.viewMode = ViewDashboard
 	a.picker.SetCurrentPRD(name)
 	a.tabBar.SetActiveByName(name)
@@ -2076,6 +2270,7 @@ func (a App) switchToPRD(name, prdPath string) (tea.Model, tea.Cmd) {
 	a.storyTimings = nil
 	a.currentStoryID = ""
 	a.currentStoryStart = time.Time{}
+	a.loadJournal(appState == StateRunning)
 
 	// Return with new watcher listeners (and elapsed tick if running)
 	cmds := []tea.Cmd{a.listenForPRDChanges(), a.listenForProgressChanges()}
@@ -2174,6 +2369,29 @@ func (a *App) GetElapsedTime() time.Duration {
 	return time.Since(a.startTime)
 }
 
+// GetUsage returns the cost and token usage of the current PRD's run.
+func (a *App) GetUsage() loop.Usage {
+	if a.manager == nil {
+		return loop.Usage{}
+	}
+	if instance := a.manager.GetInstance(a.prdName); instance != nil {
+		return instance.Usage
+	}
+	return loop.Usage{}
+}
+
+// GetProfile returns the permission profile of the current PRD's run, or an
+// empty string when it hasn't been started.
+func (a *App) GetProfile() string {
+	if a.manager == nil {
+		return ""
+	}
+	if instance := a.manager.GetInstance(a.prdName); instance != nil {
+		return instance.Profile
+	}
+	return ""
+}
+
 // GetCompletionPercentage returns the percentage of completed stories.
 func (a *App) GetCompletionPercentage() float64 {
 	if len(a.prd.UserStories) == 0 {
@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"errors"
+
+	"github.com/minicodemonkey/chief/internal/daemon"
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+// LoopManager runs and reports on the loops the TUI shows. It is either the
+// in-process *loop.Manager, or a *daemon.Client when the TUI is attached to
+// a chief serve daemon.
+type LoopManager interface {
+	Register(name, prdPath string) error
+	RegisterWithWorktree(name, prdPath, worktreeDir, branch string) error
+	UpdateWorktreeInfo(name, worktreeDir, branch string) error
+	ClearWorktreeInfo(name string, clearBranch bool) error
+	Start(name string) error
+	Pause(name string) error
+	Stop(name string) error
+	GetState(name string) (loop.LoopState, int, error)
+	GetInstance(name string) *loop.LoopInstance
+	GetAllInstances() []*loop.LoopInstance
+	IsAnyRunning() bool
+	Events() <-chan loop.ManagerEvent
+	SetMaxIterations(maxIter int)
+	SetMaxIterationsForInstance(name string, maxIter int) error
+}
+
+var (
+	_ LoopManager = (*loop.Manager)(nil)
+	_ LoopManager = (*daemon.Client)(nil)
+)
+
+// ErrAttached is returned by settings for loops the TUI runs itself when it
+// is attached to a daemon, whose own settings apply.
+var ErrAttached = errors.New("attached to chief serve, which runs loops with its own settings")
+
+// daemonDetachedMsg is sent when the connection to the daemon is lost.
+type daemonDetachedMsg struct{}
+
+// localManager returns the in-process loop manager, or nil when the TUI is
+// attached to a daemon.
+func (a *App) localManager() *loop.Manager {
+	m, _ := a.manager.(*loop.Manager)
+	return m
+}
+
+// IsAttached reports whether the TUI is attached to a chief serve daemon.
+func (a *App) IsAttached() bool {
+	return a.daemon != nil
+}
+
+// syncLoopState picks up a run of the current PRD that is already going in
+// the daemon. It reports whether the PRD is running.
+func (a *App) syncLoopState() bool {
+	instance := a.manager.GetInstance(a.prdName)
+	if instance == nil {
+		return false
+	}
+	switch instance.State {
+	case loop.LoopStateRunning:
+		a.state = StateRunning
+		a.startTime = instance.StartTime
+		a.lastActivity = "Attached to chief serve"
+	case loop.LoopStatePaused:
+		a.state = StatePaused
+	case loop.LoopStateStopped:
+		a.state = StateStopped
+	case loop.LoopStateComplete:
+		a.state = StateComplete
+	case loop.LoopStateError:
+		a.state = StateError
+		a.err = instance.Error
+	}
+	a.iteration = instance.Iteration
+	return instance.State == loop.LoopStateRunning
+}
@@ -0,0 +1,208 @@
+package tui
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minicodemonkey/chief/internal/config"
+	"github.com/minicodemonkey/chief/internal/daemon"
+	"github.com/minicodemonkey/chief/internal/loop"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
+
+// hangingAgent runs until the loop is stopped.
+type hangingAgent struct{}
+
+func (hangingAgent) Name() string { return "Hanging" }
+
+func (hangingAgent) Start(ctx context.Context, req loop.AgentRequest) (loop.AgentProcess, error) {
+	r, w := io.Pipe()
+	go func() {
+		<-ctx.Done()
+		w.Close()
+	}()
+	return &hangingProcess{stdout: r, ctx: ctx}, nil
+}
+
+func (hangingAgent) ParseLine(line string) []loop.Event { return loop.ParseLine(line) }
+
+type hangingProcess struct {
+	stdout io.Reader
+	ctx    context.Context
+}
+
+func (p *hangingProcess) Stdout() io.Reader { return p.stdout }
+func (p *hangingProcess) Stderr() io.Reader { return strings.NewReader("") }
+func (p *hangingProcess) Kill() error       { return nil }
+func (p *hangingProcess) Wait() error {
+	<-p.ctx.Done()
+	return p.ctx.Err()
+}
+
+// startTestDaemon serves a project with one PRD, auth, running.
+func startTestDaemon(t *testing.T) (string, *loop.Manager) {
+	t.Helper()
+	dir := t.TempDir()
+	prdPath := filepath.Join(dir, ".chief", "prds", "auth", "prd.json")
+	if err := os.MkdirAll(filepath.Dir(prdPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := &prd.PRD{Project: "auth", UserStories: []prd.UserStory{{ID: "US-001", Title: "Story", Priority: 1}}}
+	if err := p.Save(prdPath); err != nil {
+		t.Fatal(err)
+	}
+
+	m := loop.NewManager(10)
+	m.SetBaseDir(dir)
+	m.SetConfig(config.Default())
+	m.SetAgent(hangingAgent{})
+	m.Register("auth", prdPath)
+	s := daemon.NewServer(m, dir)
+	if err := s.Listen(); err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go s.Serve()
+	t.Cleanup(func() {
+		m.StopAll()
+		s.Close()
+	})
+
+	if err := m.Start("auth"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	return prdPath, m
+}
+
+func TestAppAttachesToDaemon(t *testing.T) {
+	prdPath, m := startTestDaemon(t)
+
+	app, err := NewAppWithOptions(prdPath, 10)
+	if err != nil {
+		t.Fatalf("NewAppWithOptions failed: %v", err)
+	}
+	defer app.stopWatcher()
+	if !app.IsAttached() {
+		t.Fatal("Expected the app to attach to the running daemon")
+	}
+	if app.state != StateRunning {
+		t.Errorf("Expected the daemon's run to show as running, got %s", app.state)
+	}
+	app.width = 200
+	if footer := app.renderFooter(); !strings.Contains(footer, "q: detach") {
+		t.Errorf("Expected the footer to offer detaching, got:\n%s", footer)
+	}
+
+	// Quitting detaches without asking, and the loop keeps running
+	model, cmd := app.tryQuit()
+	if cmd == nil || model.(App).viewMode == ViewQuitConfirm {
+		t.Fatal("Expected quitting to detach without confirmation")
+	}
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-app.manager.Events():
+			closed = !ok
+		case <-timeout:
+			t.Fatal("Expected the events channel to close on detach")
+		}
+	}
+	if state, _, _ := m.GetState("auth"); state != loop.LoopStateRunning {
+		t.Errorf("Expected auth to keep running in the daemon, got %s", state)
+	}
+}
+
+func TestAppAttachedRejectsLoopSettings(t *testing.T) {
+	prdPath, _ := startTestDaemon(t)
+
+	app, err := NewAppWithOptions(prdPath, 10)
+	if err != nil {
+		t.Fatalf("NewAppWithOptions failed: %v", err)
+	}
+	defer app.stopWatcher()
+	defer app.daemon.Close()
+
+	if err := app.DisableRetry(); err != ErrAttached {
+		t.Errorf("Expected DisableRetry to return ErrAttached, got %v", err)
+	}
+	if err := app.SetBudget(loop.Budget{MaxCostUSD: 5}, loop.Budget{}); err != ErrAttached {
+		t.Errorf("Expected SetBudget to return ErrAttached, got %v", err)
+	}
+	// No budget flags means nothing is ignored
+	if err := app.SetBudget(loop.Budget{}, loop.Budget{}); err != nil {
+		t.Errorf("Expected an empty budget to be accepted, got %v", err)
+	}
+}
+
+func TestAppWithoutDaemonRunsLoopsLocally(t *testing.T) {
+	dir := t.TempDir()
+	prdPath := filepath.Join(dir, ".chief", "prds", "auth", "prd.json")
+	os.MkdirAll(filepath.Dir(prdPath), 0755)
+	if err := (&prd.PRD{Project: "auth"}).Save(prdPath); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := NewAppWithOptions(prdPath, 10)
+	if err != nil {
+		t.Fatalf("NewAppWithOptions failed: %v", err)
+	}
+	defer app.stopWatcher()
+	if app.IsAttached() || app.localManager() == nil {
+		t.Error("Expected an in-process manager without a daemon")
+	}
+	if err := app.DisableRetry(); err != nil {
+		t.Errorf("Expected DisableRetry to apply locally, got %v", err)
+	}
+	if err := app.SetBudget(loop.Budget{MaxCostUSD: 5}, loop.Budget{}); err != nil {
+		t.Errorf("Expected SetBudget to apply locally, got %v", err)
+	}
+}
+
+func TestAppFollowsRunStartedByManager(t *testing.T) {
+	prdPath, m := startTestDaemon(t)
+
+	app, err := NewAppWithOptions(prdPath, 10)
+	if err != nil {
+		t.Fatalf("NewAppWithOptions failed: %v", err)
+	}
+	defer app.stopWatcher()
+	defer app.daemon.Close()
+
+	// As if the run had been queued or chained when the TUI looked: any event
+	// of the run shows it as running, not just the first iteration's start
+	app.state = StateReady
+	model, _ := app.handleLoopEvent("auth", loop.Event{Type: loop.EventToolStart, Iteration: 2, Tool: "Read"})
+	got := model.(App)
+	if got.state != StateRunning {
+		t.Fatalf("Expected the manager's run to show as running, got %s", got.state)
+	}
+	if instance := m.GetInstance("auth"); !got.startTime.Equal(instance.StartTime) {
+		t.Errorf("Expected the run's start time %v, got %v", instance.StartTime, got.startTime)
+	}
+}
+
+func TestAppIgnoresEventsWithoutRun(t *testing.T) {
+	dir := t.TempDir()
+	prdPath := filepath.Join(dir, ".chief", "prds", "auth", "prd.json")
+	os.MkdirAll(filepath.Dir(prdPath), 0755)
+	if err := (&prd.PRD{Project: "auth"}).Save(prdPath); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := NewAppWithOptions(prdPath, 10)
+	if err != nil {
+		t.Fatalf("NewAppWithOptions failed: %v", err)
+	}
+	defer app.stopWatcher()
+
+	// A first iteration's start doesn't mean the run is going: the manager
+	// doesn't have auth running
+	model, _ := app.handleLoopEvent("auth", loop.Event{Type: loop.EventIterationStart, Iteration: 1})
+	if state := model.(App).state; state != StateReady {
+		t.Errorf("Expected auth to stay ready, got %s", state)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/minicodemonkey/chief/internal/loop"
 )
 
 // AutoActionState represents the progress of an auto-action (push or PR).
@@ -41,6 +42,9 @@ type CompletionScreen struct {
 	totalDuration time.Duration
 	storyTimings  []StoryTiming
 
+	// Cost and token usage for the run
+	usage loop.Usage
+
 	// Confetti animation
 	confetti *Confetti
 
@@ -69,6 +73,7 @@ func (c *CompletionScreen) Configure(prdName string, completed, total int, branc
 	c.hasAutoActions = hasAutoActions
 	c.totalDuration = totalDuration
 	c.storyTimings = storyTimings
+	c.usage = loop.Usage{}
 	// Reset auto-action state
 	c.pushState = AutoActionIdle
 	c.pushError = ""
@@ -85,6 +90,11 @@ func (c *CompletionScreen) Configure(prdName string, completed, total int, branc
 	}
 }
 
+// SetUsage sets the cost and token usage shown on the completion screen.
+func (c *CompletionScreen) SetUsage(usage loop.Usage) {
+	c.usage = usage
+}
+
 // SetSize sets the screen dimensions.
 func (c *CompletionScreen) SetSize(width, height int) {
 	c.width = width
@@ -203,6 +213,16 @@ func (c *CompletionScreen) Render() string {
 		content.WriteString("\n")
 	}
 
+	// Cost and tokens
+	if !c.usage.IsZero() {
+		if c.totalDuration <= 0 {
+			content.WriteString("\n")
+		}
+		costStyle := lipgloss.NewStyle().Foreground(TextColor)
+		content.WriteString(costStyle.Render(fmt.Sprintf("Cost: %s  •  %d iterations", c.usage.Summary(), c.usage.Iterations)))
+		content.WriteString("\n")
+	}
+
 	// Per-story timings
 	if len(c.storyTimings) > 0 {
 		content.WriteString("\n")
@@ -305,6 +325,12 @@ func (c *CompletionScreen) calculateModalHeight() int {
 	if c.totalDuration > 0 {
 		durationLine = 2 // blank + duration text
 	}
+	if !c.usage.IsZero() {
+		durationLine++ // cost line
+		if c.totalDuration <= 0 {
+			durationLine++ // blank before cost
+		}
+	}
 
 	calculated := base + storyLines + autoLines + durationLine
 	maxHeight := c.height - 4
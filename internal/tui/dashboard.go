@@ -1,11 +1,13 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/minicodemonkey/chief/internal/loop"
 	"github.com/minicodemonkey/chief/internal/prd"
 )
 
@@ -148,8 +150,20 @@ func (a *App) renderHeader() string {
 
 	// Combine elements
 	leftPart := lipgloss.JoinHorizontal(lipgloss.Center, brand, "  ", state)
+	if profile := a.renderProfile("Profile: "); profile != "" {
+		leftPart = lipgloss.JoinHorizontal(lipgloss.Center, leftPart, "  ", profile)
+	}
+	if a.daemon != nil {
+		leftPart = lipgloss.JoinHorizontal(lipgloss.Center, leftPart, "  ", SubtitleStyle.Render("Attached"))
+	}
 	rightPart := lipgloss.JoinHorizontal(lipgloss.Center, iteration, "  ", elapsedStr)
 
+	// Cost and tokens (only once the agent has reported usage)
+	if usage := a.GetUsage(); !usage.IsZero() {
+		costStr := SubtitleStyle.Render("Cost: " + usage.Summary())
+		rightPart = lipgloss.JoinHorizontal(lipgloss.Center, rightPart, "  ", costStr)
+	}
+
 	// Create the full header line with proper spacing
 	spacing := strings.Repeat(" ", max(0, a.width-lipgloss.Width(leftPart)-lipgloss.Width(rightPart)-2))
 	headerLine := lipgloss.JoinHorizontal(lipgloss.Center, leftPart, spacing, rightPart)
@@ -169,6 +183,19 @@ func (a *App) renderHeader() string {
 	return lipgloss.JoinVertical(lipgloss.Left, headerLine, tabBarLine, border)
 }
 
+// renderProfile renders the permission profile of the current run, highlighting
+// runs without permission checks. It returns an empty string before a run starts.
+func (a *App) renderProfile(label string) string {
+	profile := a.GetProfile()
+	if profile == "" {
+		return ""
+	}
+	if profile == loop.ProfileUnrestricted {
+		return ProfileUnrestrictedStyle.Render(label + profile)
+	}
+	return ProfileStyle.Render(label + profile)
+}
+
 // renderTabBar renders the PRD tab bar.
 func (a *App) renderTabBar() string {
 	if a.tabBar == nil {
@@ -190,12 +217,19 @@ func (a *App) renderNarrowHeader() string {
 	stateStyle := GetStateStyle(a.state)
 	state := stateStyle.Render(fmt.Sprintf("[%s]", a.state.String()))
 
-	// Condensed iteration and time
+	// Condensed iteration, time and cost
 	elapsed := a.GetElapsedTime()
-	iterTime := SubtitleStyle.Render(fmt.Sprintf("#%d %s", a.iteration, formatDuration(elapsed)))
+	iterTimeStr := fmt.Sprintf("#%d %s", a.iteration, formatDuration(elapsed))
+	if usage := a.GetUsage(); !usage.IsZero() {
+		iterTimeStr += fmt.Sprintf(" $%.2f", usage.CostUSD)
+	}
+	iterTime := SubtitleStyle.Render(iterTimeStr)
 
 	// Combine elements
 	leftPart := lipgloss.JoinHorizontal(lipgloss.Center, brand, " ", state)
+	if profile := a.renderProfile(""); profile != "" {
+		leftPart = lipgloss.JoinHorizontal(lipgloss.Center, leftPart, " ", profile)
+	}
 	rightPart := iterTime
 
 	// Create the full header line with proper spacing
@@ -241,6 +275,10 @@ func (a *App) renderFooter() string {
 			shortcuts = []string{"d: diff", "e: edit", "t: log", "n: new", "l: list", "1-9: switch", "?: help", "q: quit"}
 		}
 	}
+	if a.daemon != nil {
+		// Quitting leaves the daemon's loops running
+		shortcuts[len(shortcuts)-1] = "q: detach"
+	}
 	shortcutsStr := footerStyle.Render(strings.Join(shortcuts, "  │  "))
 
 	// PRD name
@@ -311,7 +349,7 @@ func (a *App) renderNarrowActivityLine() string {
 	}
 
 	// More aggressive truncation for narrow mode
-	maxLen := a.width - 2
+	maxLen := a.width - 2 - a.agentModelWidth()
 	if len(activity) > maxLen && maxLen > 3 {
 		activity = activity[:maxLen-3] + "..."
 	}
@@ -319,7 +357,7 @@ func (a *App) renderNarrowActivityLine() string {
 	// Use the centralized activity style system
 	activityStyle := GetActivityStyle(a.state)
 
-	return activityStyle.Render(activity)
+	return activityStyle.Render(activity) + a.renderAgentModel()
 }
 
 // renderActivityLine renders the current activity status line.
@@ -330,7 +368,7 @@ func (a *App) renderActivityLine() string {
 	}
 
 	// Truncate if too long
-	maxLen := a.width - 4
+	maxLen := a.width - 4 - a.agentModelWidth()
 	if len(activity) > maxLen && maxLen > 3 {
 		activity = activity[:maxLen-3] + "..."
 	}
@@ -338,7 +376,21 @@ func (a *App) renderActivityLine() string {
 	// Use the centralized activity style system
 	activityStyle := GetActivityStyle(a.state)
 
-	return activityStyle.Render(activity)
+	return activityStyle.Render(activity) + a.renderAgentModel()
+}
+
+// renderAgentModel renders the model of the current iteration for the end of
+// the activity line, or an empty string when it isn't known.
+func (a *App) renderAgentModel() string {
+	if a.agentModel == "" {
+		return ""
+	}
+	return ActivityModelStyle.Render("· " + a.agentModel)
+}
+
+// agentModelWidth returns the width taken by renderAgentModel.
+func (a *App) agentModelWidth() int {
+	return lipgloss.Width(a.renderAgentModel())
 }
 
 // renderStoriesPanel renders the stories list panel.
@@ -362,7 +414,7 @@ func (a *App) renderStoriesPanel(width, height int) string {
 			break
 		}
 
-		icon := GetStatusIcon(story.Passes, story.InProgress)
+		icon := GetStoryStatusIcon(a.prd, &a.prd.UserStories[i])
 
 		// Truncate title to fit
 		maxTitleLen := width - 12 // Account for icon, ID, and spacing
@@ -432,12 +484,19 @@ func (a *App) renderDetailsPanel(width, height int) string {
 	content.WriteString("\n\n")
 
 	// Status and Priority with proper styling
-	statusIcon := GetStatusIcon(story.Passes, story.InProgress)
+	statusIcon := GetStoryStatusIcon(a.prd, story)
+	blockedBy := a.prd.BlockedBy(story)
 	var statusText string
 	var statusStyle lipgloss.Style
 	if story.Passes {
 		statusText = "Passed"
 		statusStyle = statusPassedStyle
+	} else if story.Blocked {
+		statusText = fmt.Sprintf("Blocked after %d attempts", story.Attempts)
+		statusStyle = statusFailedStyle
+	} else if len(blockedBy) > 0 {
+		statusText = "Blocked"
+		statusStyle = statusBlockedStyle
 	} else if story.InProgress {
 		statusText = "In Progress"
 		statusStyle = statusInProgressStyle
@@ -446,6 +505,17 @@ func (a *App) renderDetailsPanel(width, height int) string {
 		statusStyle = statusPendingStyle
 	}
 	content.WriteString(fmt.Sprintf("%s %s  │  Priority: %d\n", statusIcon, statusStyle.Render(statusText), story.Priority))
+	if !story.Passes && story.Blocked && story.BlockedReason != "" {
+		content.WriteString(statusFailedStyle.Render(wrapText("Reason: "+story.BlockedReason, width-4)))
+		content.WriteString("\n")
+	}
+	if !story.Passes && len(blockedBy) > 0 {
+		content.WriteString(statusBlockedStyle.Render("Blocked by: " + strings.Join(blockedBy, ", ")))
+		content.WriteString("\n")
+	} else if len(story.DependsOn) > 0 {
+		content.WriteString(lipgloss.NewStyle().Foreground(MutedColor).Render("Depends on: " + strings.Join(story.DependsOn, ", ")))
+		content.WriteString("\n")
+	}
 	content.WriteString(DividerStyle.Render(strings.Repeat("─", width-4)))
 	content.WriteString("\n\n")
 
@@ -503,11 +573,23 @@ func (a *App) renderErrorPanel(width, height int) string {
 	}
 	content.WriteString("\n\n")
 
+	// Classified cause
+	cause, causeHint := errorCause(a.err)
+	if cause != "" {
+		content.WriteString(labelStyle.Render("Cause: "))
+		content.WriteString(StateErrorStyle.Render(cause))
+		content.WriteString("\n\n")
+	}
+
 	// Log file hint
 	content.WriteString(DividerStyle.Render(strings.Repeat("─", width-4)))
 	content.WriteString("\n\n")
 	hintStyle := lipgloss.NewStyle().Foreground(WarningColor)
-	content.WriteString(hintStyle.Render("💡 Tip: Check claude.log in the PRD directory for full error details."))
+	if causeHint != "" {
+		content.WriteString(hintStyle.Render(wrapText("💡 "+causeHint, width-4)))
+		content.WriteString("\n")
+	}
+	content.WriteString(hintStyle.Render(wrapText("💡 Tip: "+a.logHint(), width-4)))
 	content.WriteString("\n\n")
 
 	// Retry instructions
@@ -526,6 +608,27 @@ func (a *App) renderErrorPanel(width, height int) string {
 	return panelStyle.Width(width).Height(height).Render(content.String())
 }
 
+// errorCause returns a label for what caused a loop error and a hint on how
+// to fix it. Both are empty when the cause isn't known.
+func errorCause(err error) (string, string) {
+	var agentErr *loop.AgentError
+	var wrapperErr *loop.WrapperError
+	var stallErr *loop.StallError
+	switch {
+	case errors.As(err, &agentErr):
+		hint := agentErr.Hint()
+		if agentErr.Kind == loop.FailureRateLimit && !agentErr.ResetAt.IsZero() {
+			hint = "The limit resets at " + agentErr.ResetAt.Format("Jan 2 15:04") + ". Press s to retry after that."
+		}
+		return agentErr.Kind.Label(), hint
+	case errors.As(err, &wrapperErr):
+		return "Agent wrapper", "Check agent.wrapper in .chief/config.yaml."
+	case errors.As(err, &stallErr):
+		return "Stalled", "Raise timeouts.inactivity or timeouts.iteration in .chief/config.yaml if the agent needs longer."
+	}
+	return "", ""
+}
+
 // renderEmptyPRDPanel renders a panel when there are no stories in the PRD.
 func (a *App) renderEmptyPRDPanel(width, height int) string {
 	var content strings.Builder
@@ -30,12 +30,13 @@ type LogEntry struct {
 // LogViewer manages the log viewport state.
 type LogViewer struct {
 	entries          []LogEntry
-	scrollPos        int    // Current scroll position (top line index)
-	height           int    // Viewport height (lines)
-	width            int    // Viewport width
-	autoScroll       bool   // Auto-scroll to bottom when new content arrives
-	lastReadFilePath string // Track the last Read tool's file path for syntax highlighting
-	totalLineCount   int    // Running total of all rendered lines (O(1) lookup)
+	scrollPos        int               // Current scroll position (top line index)
+	height           int               // Viewport height (lines)
+	width            int               // Viewport width
+	autoScroll       bool              // Auto-scroll to bottom when new content arrives
+	lastReadFilePath string            // Track the last Read tool's file path for syntax highlighting
+	readFilePaths    map[string]string // Read tool file paths by tool use ID, for interleaved calls
+	totalLineCount   int               // Running total of all rendered lines (O(1) lookup)
 }
 
 // NewLogViewer creates a new log viewer.
@@ -57,18 +58,31 @@ func (l *LogViewer) AddEvent(event loop.Event) {
 		StoryID:   event.StoryID,
 	}
 
-	// Track Read tool file paths for syntax highlighting
+	// Track Read tool file paths for syntax highlighting. One message can
+	// contain several tool calls, so match results to calls by ID when known.
 	if event.Type == loop.EventToolStart && event.Tool == "Read" {
 		if filePath, ok := event.ToolInput["file_path"].(string); ok {
-			l.lastReadFilePath = filePath
+			if event.ToolUseID != "" {
+				if l.readFilePaths == nil {
+					l.readFilePaths = make(map[string]string)
+				}
+				l.readFilePaths[event.ToolUseID] = filePath
+			} else {
+				l.lastReadFilePath = filePath
+			}
 		}
 	}
 
 	// For tool results, attach the file path and pre-compute syntax highlighting
-	if event.Type == loop.EventToolResult && l.lastReadFilePath != "" {
-		entry.FilePath = l.lastReadFilePath
-		l.lastReadFilePath = "" // Clear after consuming
-		if entry.Text != "" {
+	if event.Type == loop.EventToolResult {
+		if filePath, ok := l.readFilePaths[event.ToolUseID]; ok && event.ToolUseID != "" {
+			entry.FilePath = filePath
+			delete(l.readFilePaths, event.ToolUseID)
+		} else if event.ToolUseID == "" && l.lastReadFilePath != "" {
+			entry.FilePath = l.lastReadFilePath
+			l.lastReadFilePath = "" // Clear after consuming
+		}
+		if entry.FilePath != "" && entry.Text != "" {
 			entry.highlightedCode = l.highlightCode(entry.Text, entry.FilePath)
 		}
 	}
@@ -76,7 +90,10 @@ func (l *LogViewer) AddEvent(event loop.Event) {
 	// Filter out events we don't want to display
 	switch event.Type {
 	case loop.EventAssistantText, loop.EventToolStart, loop.EventToolResult,
-		loop.EventStoryStarted, loop.EventComplete, loop.EventError, loop.EventRetrying:
+		loop.EventStoryStarted, loop.EventComplete, loop.EventError, loop.EventRetrying,
+		loop.EventBudgetExceeded, loop.EventVerificationPassed, loop.EventVerificationFailed,
+		loop.EventStoryMerged, loop.EventStoryFailed, loop.EventStoryBlocked, loop.EventAllStoriesBlocked,
+		loop.EventNoProgress:
 		// Pre-render and cache lines
 		if l.width > 0 {
 			entry.cachedLines = l.renderEntry(entry)
@@ -277,6 +294,17 @@ func (l *LogViewer) Clear() {
 	l.scrollPos = 0
 	l.autoScroll = true
 	l.totalLineCount = 0
+	l.lastReadFilePath = ""
+	l.readFilePaths = nil
+}
+
+// Lines returns every rendered line of the log, for printing it in full.
+func (l *LogViewer) Lines() []string {
+	lines := make([]string, 0, l.totalLineCount)
+	for i := range l.entries {
+		lines = append(lines, l.entries[i].cachedLines...)
+	}
+	return lines
 }
 
 // Render renders only the visible portion of the log viewer.
@@ -361,6 +389,16 @@ func (l *LogViewer) renderEntry(entry LogEntry) []string {
 		return l.renderError(entry)
 	case loop.EventRetrying:
 		return l.renderRetrying(entry)
+	case loop.EventBudgetExceeded:
+		return l.renderBudgetExceeded(entry)
+	case loop.EventVerificationPassed, loop.EventVerificationFailed:
+		return l.renderVerification(entry)
+	case loop.EventStoryMerged, loop.EventStoryFailed:
+		return l.renderStoryMerge(entry)
+	case loop.EventStoryBlocked:
+		return l.renderStoryBlocked(entry)
+	case loop.EventAllStoriesBlocked, loop.EventNoProgress:
+		return l.renderLoopStopped(entry)
 	default:
 		return l.renderText(entry)
 	}
@@ -615,3 +653,73 @@ func (l *LogViewer) renderRetrying(entry LogEntry) []string {
 
 	return []string{retryStyle.Render("🔄 " + text)}
 }
+
+// renderBudgetExceeded renders a budget-exceeded message.
+func (l *LogViewer) renderBudgetExceeded(entry LogEntry) []string {
+	budgetStyle := lipgloss.NewStyle().
+		Foreground(WarningColor).
+		Bold(true)
+
+	text := entry.Text
+	if text == "" {
+		text = "Budget exceeded"
+	}
+
+	return []string{budgetStyle.Render("⏹ " + text)}
+}
+
+// renderStoryMerge renders the outcome of a story worked on in parallel.
+func (l *LogViewer) renderStoryMerge(entry LogEntry) []string {
+	if entry.Type == loop.EventStoryMerged {
+		mergeStyle := lipgloss.NewStyle().Foreground(SuccessColor)
+		return []string{mergeStyle.Render("⇢ " + entry.Text)}
+	}
+
+	failStyle := lipgloss.NewStyle().Foreground(WarningColor)
+	var result []string
+	for _, line := range strings.Split(wrapText("✗ "+entry.Text, l.width-4), "\n") {
+		result = append(result, failStyle.Render(line))
+	}
+	return result
+}
+
+// renderStoryBlocked renders a story being blocked after too many attempts.
+func (l *LogViewer) renderStoryBlocked(entry LogEntry) []string {
+	blockedStyle := lipgloss.NewStyle().Foreground(ErrorColor)
+	var result []string
+	for _, line := range strings.Split(wrapText(IconFailed+" "+entry.Text, l.width-4), "\n") {
+		result = append(result, blockedStyle.Render(line))
+	}
+	return result
+}
+
+// renderLoopStopped renders the loop stopping because there's nothing left it
+// can make progress on.
+func (l *LogViewer) renderLoopStopped(entry LogEntry) []string {
+	stopStyle := lipgloss.NewStyle().
+		Foreground(WarningColor).
+		Bold(true)
+	return []string{stopStyle.Render("⏹ " + entry.Text)}
+}
+
+// renderVerification renders a verification result, followed by the failure output if any.
+func (l *LogViewer) renderVerification(entry LogEntry) []string {
+	if entry.Type == loop.EventVerificationPassed {
+		passStyle := lipgloss.NewStyle().Foreground(SuccessColor)
+		return []string{passStyle.Render("✓ " + entry.Text)}
+	}
+
+	failStyle := lipgloss.NewStyle().
+		Foreground(ErrorColor).
+		Bold(true)
+	outputStyle := lipgloss.NewStyle().Foreground(MutedColor)
+
+	summary, output, _ := strings.Cut(entry.Text, "\n")
+	result := []string{failStyle.Render("✗ " + summary)}
+	if output != "" {
+		for _, line := range strings.Split(wrapText(output, l.width-6), "\n") {
+			result = append(result, outputStyle.Render("  "+line))
+		}
+	}
+	return result
+}
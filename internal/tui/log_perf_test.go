@@ -283,6 +283,33 @@ func TestAddEvent_PreComputesSyntaxHighlighting(t *testing.T) {
 	}
 }
 
+func TestAddEvent_MatchesReadResultsByToolUseID(t *testing.T) {
+	lv := NewLogViewer()
+	lv.SetSize(100, 30)
+
+	// Two Read calls from one assistant message, then their results
+	for _, call := range []struct{ id, path string }{{"toolu_1", "/a.go"}, {"toolu_2", "/b.py"}} {
+		event := makeToolStartEvent("Read", map[string]interface{}{"file_path": call.path})
+		event.ToolUseID = call.id
+		lv.AddEvent(event)
+	}
+	for _, id := range []string{"toolu_2", "toolu_1"} {
+		event := makeToolResultEvent("contents")
+		event.ToolUseID = id
+		lv.AddEvent(event)
+	}
+
+	if len(lv.entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(lv.entries))
+	}
+	if lv.entries[2].FilePath != "/b.py" {
+		t.Errorf("Expected first result to match toolu_2 (/b.py), got '%s'", lv.entries[2].FilePath)
+	}
+	if lv.entries[3].FilePath != "/a.go" {
+		t.Errorf("Expected second result to match toolu_1 (/a.go), got '%s'", lv.entries[3].FilePath)
+	}
+}
+
 func TestAddEvent_NoHighlightingForNonReadResults(t *testing.T) {
 	lv := NewLogViewer()
 	lv.SetSize(100, 30)
@@ -23,6 +23,7 @@ type PRDEntry struct {
 	InProgress  bool           // Whether any story is in progress
 	LoopState   loop.LoopState // Current loop state from manager
 	Iteration   int            // Current iteration if running
+	QueuePos    int            // Place in the run queue if queued
 	Branch      string         // Git branch for this PRD (empty = no branch)
 	WorktreeDir string         // Worktree directory (empty = current directory)
 	Orphaned    bool           // True if worktree exists on disk but no running PRD tracks it
@@ -69,14 +70,14 @@ type PRDPicker struct {
 	currentPRD    string        // Name of the currently active PRD
 	inputMode     bool          // Whether we're in input mode for new PRD name
 	inputValue    string        // The current input value for new PRD name
-	manager            *loop.Manager      // Reference to the loop manager for status updates
+	manager            LoopManager        // Reference to the loop manager for status updates
 	mergeResult        *MergeResult       // Result of the last merge operation (nil = none)
 	cleanConfirmation  *CleanConfirmation // Active clean confirmation dialog (nil = none)
 	cleanResult        *CleanResult       // Result of the last clean operation (nil = none)
 }
 
 // NewPRDPicker creates a new PRD picker.
-func NewPRDPicker(basePath string, currentPRDName string, manager *loop.Manager) *PRDPicker {
+func NewPRDPicker(basePath string, currentPRDName string, manager LoopManager) *PRDPicker {
 	p := &PRDPicker{
 		entries:       make([]PRDEntry, 0),
 		selectedIndex: 0,
@@ -91,7 +92,7 @@ func NewPRDPicker(basePath string, currentPRDName string, manager *loop.Manager)
 }
 
 // SetManager sets the loop manager reference.
-func (p *PRDPicker) SetManager(manager *loop.Manager) {
+func (p *PRDPicker) SetManager(manager LoopManager) {
 	p.manager = manager
 }
 
@@ -220,6 +221,7 @@ func (p *PRDPicker) loadPRDEntry(name, prdPath string) PRDEntry {
 		if instance := p.manager.GetInstance(name); instance != nil {
 			prdEntry.Branch = instance.Branch
 			prdEntry.WorktreeDir = instance.WorktreeDir
+			prdEntry.QueuePos = instance.QueuePosition
 		}
 	}
 
@@ -339,8 +341,8 @@ func (p *PRDPicker) CanClean() bool {
 	if entry == nil || entry.WorktreeDir == "" {
 		return false
 	}
-	// Disabled for running PRDs - user must stop first
-	return entry.LoopState != loop.LoopStateRunning
+	// Disabled for running and queued PRDs - user must stop first
+	return entry.LoopState != loop.LoopStateRunning && entry.LoopState != loop.LoopStateQueued
 }
 
 // StartCleanConfirmation opens the clean confirmation dialog for the selected entry.
@@ -685,6 +687,10 @@ func (p *PRDPicker) renderLoopStateIndicator(entry PRDEntry) string {
 	case loop.LoopStateStopped:
 		stoppedStyle := lipgloss.NewStyle().Foreground(MutedColor)
 		return stoppedStyle.Render("■")
+	case loop.LoopStateQueued:
+		// Show place in the run queue
+		queuedStyle := lipgloss.NewStyle().Foreground(MutedColor)
+		return queuedStyle.Render(fmt.Sprintf("⏳ #%d", entry.QueuePos))
 	default:
 		// Ready state - show story status
 		if entry.InProgress {
@@ -760,6 +766,8 @@ func (p *PRDPicker) buildFooterShortcuts() string {
 		return "s: start  │  " + mergeHint + cleanHint + base
 	case loop.LoopStateRunning:
 		return "p: pause  │  x: stop  │  " + base
+	case loop.LoopStateQueued:
+		return "x: unqueue  │  " + base
 	case loop.LoopStateComplete:
 		return mergeHint + cleanHint + base
 	default:
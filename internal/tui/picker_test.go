@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"unicode/utf8"
 
@@ -437,6 +438,28 @@ func TestCanCleanDisabledForRunningPRD(t *testing.T) {
 	}
 }
 
+func TestCanCleanDisabledForQueuedPRD(t *testing.T) {
+	p := &PRDPicker{
+		basePath: "/project",
+		entries: []PRDEntry{
+			{
+				Name:        "auth",
+				LoopState:   loop.LoopStateQueued,
+				QueuePos:    1,
+				Branch:      "chief/auth",
+				WorktreeDir: "/project/.chief/worktrees/auth",
+			},
+		},
+		selectedIndex: 0,
+	}
+	if p.CanClean() {
+		t.Error("expected CanClean() to return false for queued PRD")
+	}
+	if indicator := p.renderLoopStateIndicator(p.entries[0]); !strings.Contains(indicator, "#1") {
+		t.Errorf("expected queue position #1 in the state indicator, got: %s", indicator)
+	}
+}
+
 func TestCanCleanDisabledWithoutWorktree(t *testing.T) {
 	p := &PRDPicker{
 		basePath: "/project",
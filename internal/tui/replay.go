@@ -0,0 +1,177 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+// RenderTranscripts renders recorded iterations the way the log view shows
+// them, each under a heading. It returns the lines and the index of each
+// iteration's heading.
+func RenderTranscripts(transcripts []loop.Transcript, width int) ([]string, []int) {
+	var lines []string
+	var headings []int
+	for i, t := range transcripts {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		headings = append(headings, len(lines))
+		lines = append(lines, transcriptHeading(t, width))
+
+		log := NewLogViewer()
+		log.SetSize(width, 0)
+		for _, event := range t.Events {
+			log.AddEvent(event)
+		}
+		entries := log.Lines()
+		if len(entries) == 0 {
+			entries = []string{SubtitleStyle.Render("(no output)")}
+		}
+		lines = append(lines, entries...)
+	}
+	return lines, headings
+}
+
+// transcriptHeading renders the divider that introduces an iteration.
+func transcriptHeading(t loop.Transcript, width int) string {
+	title := fmt.Sprintf("Iteration %d", t.Iteration)
+	if t.Run > 0 {
+		title = fmt.Sprintf("Run %d · %s", t.Run, title)
+	}
+	if len(t.Stories) > 0 {
+		title += " · " + strings.Join(t.Stories, ", ")
+	}
+	heading := PanelTitleStyle.Render("── " + title + " ")
+	if fill := width - lipgloss.Width(heading); fill > 0 {
+		heading += DividerStyle.Render(strings.Repeat("─", fill))
+	}
+	return heading
+}
+
+// ReplayViewer is a pager for the transcripts of past iterations.
+type ReplayViewer struct {
+	title       string
+	transcripts []loop.Transcript
+	lines       []string
+	headings    []int
+	offset      int
+	width       int
+	height      int
+}
+
+// NewReplayViewer creates a pager for the given transcripts.
+func NewReplayViewer(title string, transcripts []loop.Transcript) ReplayViewer {
+	return ReplayViewer{title: title, transcripts: transcripts}
+}
+
+// Init implements tea.Model.
+func (r ReplayViewer) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (r ReplayViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		r.width = msg.Width
+		r.height = msg.Height
+		r.lines, r.headings = RenderTranscripts(r.transcripts, r.width-2)
+		r.offset = min(r.offset, r.maxOffset())
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return r, tea.Quit
+		case "j", "down":
+			r.offset++
+		case "k", "up":
+			r.offset--
+		case "ctrl+d", "pgdown", " ":
+			r.offset += r.bodyHeight() / 2
+		case "ctrl+u", "pgup":
+			r.offset -= r.bodyHeight() / 2
+		case "g", "home":
+			r.offset = 0
+		case "G", "end":
+			r.offset = r.maxOffset()
+		case "n":
+			for _, h := range r.headings {
+				if h > r.offset {
+					r.offset = h
+					break
+				}
+			}
+		case "p":
+			for i := len(r.headings) - 1; i >= 0; i-- {
+				if r.headings[i] < r.offset {
+					r.offset = r.headings[i]
+					break
+				}
+			}
+		}
+		r.offset = max(0, min(r.offset, r.maxOffset()))
+	}
+	return r, nil
+}
+
+// bodyHeight returns the number of transcript lines that fit on screen.
+func (r ReplayViewer) bodyHeight() int {
+	// Header, two dividers and the footer
+	return max(1, r.height-4)
+}
+
+func (r ReplayViewer) maxOffset() int {
+	return max(0, len(r.lines)-r.bodyHeight())
+}
+
+// current returns the index of the iteration at the top of the screen.
+func (r ReplayViewer) current() int {
+	cur := 0
+	for i, h := range r.headings {
+		if h <= r.offset {
+			cur = i
+		}
+	}
+	return cur
+}
+
+// View implements tea.Model.
+func (r ReplayViewer) View() string {
+	if r.width == 0 {
+		return ""
+	}
+
+	header := headerStyle.Render("chief replay") + SubtitleStyle.Render(r.title)
+	divider := DividerStyle.Render(strings.Repeat("─", r.width))
+
+	end := min(r.offset+r.bodyHeight(), len(r.lines))
+	body := make([]string, 0, r.bodyHeight())
+	for _, line := range r.lines[r.offset:end] {
+		body = append(body, " "+line)
+	}
+	for len(body) < r.bodyHeight() {
+		body = append(body, "")
+	}
+
+	shortcuts := footerStyle.Render(strings.Join([]string{"j/k: scroll", "n/p: next/prev iteration", "g/G: top/bottom", "q: quit"}, "  │  "))
+	position := footerStyle.Render(fmt.Sprintf("Iteration %d of %d", r.current()+1, len(r.transcripts)))
+	spacing := strings.Repeat(" ", max(0, r.width-lipgloss.Width(shortcuts)-lipgloss.Width(position)))
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		header,
+		divider,
+		strings.Join(body, "\n"),
+		divider,
+		shortcuts+spacing+position,
+	)
+}
+
+// RunReplay pages through the transcripts of past iterations.
+func RunReplay(title string, transcripts []loop.Transcript) error {
+	p := tea.NewProgram(NewReplayViewer(title, transcripts), tea.WithAltScreen())
+	_, err := p.Run()
+	return err
+}
@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/minicodemonkey/chief/internal/loop"
+)
+
+func replayTestTranscripts(n int) []loop.Transcript {
+	var transcripts []loop.Transcript
+	for i := 1; i <= n; i++ {
+		t := loop.Transcript{Run: 1, Iteration: i, Stories: []string{fmt.Sprintf("US-%03d", i)}}
+		for j := 0; j < 10; j++ {
+			t.Events = append(t.Events, loop.Event{Type: loop.EventAssistantText, Text: fmt.Sprintf("line %d", j)})
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts
+}
+
+func TestRenderTranscripts(t *testing.T) {
+	lines, headings := RenderTranscripts(replayTestTranscripts(2), 60)
+	if len(headings) != 2 {
+		t.Fatalf("Expected 2 headings, got %d", len(headings))
+	}
+	if !strings.Contains(lines[headings[1]], "Run 1 · Iteration 2 · US-002") {
+		t.Errorf("Expected the second heading, got %q", lines[headings[1]])
+	}
+	if !strings.Contains(lines[headings[0]+1], "line 0") {
+		t.Errorf("Expected events after the heading, got %q", lines[headings[0]+1])
+	}
+}
+
+func TestReplayViewer_Navigation(t *testing.T) {
+	var m tea.Model = NewReplayViewer("test", replayTestTranscripts(3))
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 10})
+
+	key := func(k string) {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+
+	key("n")
+	r := m.(ReplayViewer)
+	if r.offset != r.headings[1] || r.current() != 1 {
+		t.Errorf("Expected n to jump to the second iteration, got offset %d", r.offset)
+	}
+	if !strings.Contains(r.View(), "Iteration 2 of 3") {
+		t.Error("Expected the footer to show the current iteration")
+	}
+
+	key("p")
+	if r := m.(ReplayViewer); r.offset != 0 {
+		t.Errorf("Expected p to jump back to the first iteration, got offset %d", r.offset)
+	}
+
+	key("G")
+	r = m.(ReplayViewer)
+	if r.offset != r.maxOffset() || r.offset == 0 {
+		t.Errorf("Expected G to scroll to the bottom, got offset %d", r.offset)
+	}
+	key("j")
+	if m.(ReplayViewer).offset != r.offset {
+		t.Error("Expected scrolling to stop at the bottom")
+	}
+}
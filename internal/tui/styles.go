@@ -3,7 +3,10 @@
 // log viewer, PRD picker, help overlay, and consistent styling.
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/minicodemonkey/chief/internal/prd"
+)
 
 // Color palette - consistent colors used throughout the TUI
 var (
@@ -103,6 +106,7 @@ var (
 	statusPendingStyle    = lipgloss.NewStyle().Foreground(MutedColor)
 	statusFailedStyle     = lipgloss.NewStyle().Foreground(ErrorColor)
 	statusPausedStyle     = lipgloss.NewStyle().Foreground(WarningColor)
+	statusBlockedStyle    = lipgloss.NewStyle().Foreground(WarningColor)
 
 	// State badge styles (with bold for headers)
 	StateReadyStyle    = lipgloss.NewStyle().Bold(true).Foreground(MutedColor)
@@ -148,6 +152,13 @@ var (
 	ActivityErrorStyle    = lipgloss.NewStyle().Foreground(ErrorColor).Padding(0, 1)
 	ActivityCompleteStyle = lipgloss.NewStyle().Foreground(SuccessColor).Padding(0, 1)
 	ActivityMutedStyle    = lipgloss.NewStyle().Foreground(MutedColor).Padding(0, 1)
+	ActivityModelStyle    = lipgloss.NewStyle().Foreground(MutedColor)
+)
+
+// Permission profile styles
+var (
+	ProfileStyle             = lipgloss.NewStyle().Foreground(MutedColor)
+	ProfileUnrestrictedStyle = lipgloss.NewStyle().Bold(true).Foreground(WarningColor)
 )
 
 // Divider styles
@@ -204,6 +215,7 @@ const (
 	IconPending    = "○"
 	IconFailed     = "✗"
 	IconPaused     = "◐"
+	IconBlocked    = "⊘"
 )
 
 // Backward compatibility aliases
@@ -225,6 +237,19 @@ func GetStatusIcon(passed, inProgress bool) string {
 	return statusPendingStyle.Render(IconPending)
 }
 
+// GetStoryStatusIcon returns the icon for a story, showing stories that were
+// given up on after too many attempts as failed and stories that are waiting
+// on unpassed dependencies as blocked.
+func GetStoryStatusIcon(p *prd.PRD, story *prd.UserStory) string {
+	if story.Blocked && !story.Passes {
+		return statusFailedStyle.Render(IconFailed)
+	}
+	if p.IsBlocked(story) {
+		return statusBlockedStyle.Render(IconBlocked)
+	}
+	return GetStatusIcon(story.Passes, story.InProgress)
+}
+
 // GetStateStyle returns the appropriate style for an app state.
 func GetStateStyle(state AppState) lipgloss.Style {
 	switch state {
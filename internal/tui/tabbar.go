@@ -20,6 +20,7 @@ type TabEntry struct {
 	Completed int            // Number of completed stories
 	Total     int            // Total number of stories
 	Iteration int            // Current iteration if running
+	QueuePos  int            // Place in the run queue if queued
 	IsActive  bool           // Whether this is the currently viewed PRD
 }
 
@@ -29,12 +30,12 @@ type TabBar struct {
 	activeIndex int
 	width       int
 	baseDir     string
-	manager     *loop.Manager
+	manager     LoopManager
 	currentPRD  string
 }
 
 // NewTabBar creates a new tab bar.
-func NewTabBar(baseDir, currentPRD string, manager *loop.Manager) *TabBar {
+func NewTabBar(baseDir, currentPRD string, manager LoopManager) *TabBar {
 	t := &TabBar{
 		entries:    make([]TabEntry, 0),
 		baseDir:    baseDir,
@@ -117,6 +118,7 @@ func (t *TabBar) loadTabEntry(name, prdPath string) TabEntry {
 		}
 		if inst := t.manager.GetInstance(name); inst != nil {
 			tabEntry.Branch = inst.Branch
+			tabEntry.QueuePos = inst.QueuePosition
 		}
 	}
 
@@ -197,6 +199,8 @@ func (t *TabBar) renderTab(entry TabEntry, number int) string {
 		stateIndicator = " ✓"
 	case loop.LoopStateError:
 		stateIndicator = " ✗"
+	case loop.LoopStateQueued:
+		stateIndicator = fmt.Sprintf(" ⏳ #%d", entry.QueuePos)
 	default:
 		// Show progress for ready state
 		if entry.Total > 0 {
@@ -255,6 +259,8 @@ func (t *TabBar) renderTab(entry TabEntry, number int) string {
 		tabContent = lipgloss.NewStyle().Foreground(SuccessColor).Render(tabContent)
 	case loop.LoopStateError:
 		tabContent = lipgloss.NewStyle().Foreground(ErrorColor).Render(tabContent)
+	case loop.LoopStateQueued:
+		tabContent = lipgloss.NewStyle().Foreground(MutedColor).Render(tabContent)
 	default:
 		if entry.IsActive {
 			tabContent = lipgloss.NewStyle().Foreground(TextBrightColor).Render(tabContent)
@@ -308,6 +314,8 @@ func (t *TabBar) renderCompactTab(entry TabEntry, number int) string {
 		stateIndicator = "✓"
 	case loop.LoopStateError:
 		stateIndicator = "✗"
+	case loop.LoopStateQueued:
+		stateIndicator = fmt.Sprintf("⏳%d", entry.QueuePos)
 	}
 
 	// Active indicator
@@ -130,3 +130,20 @@ func TestRenderTabEmptyBranch(t *testing.T) {
 		t.Errorf("expected empty branch to not show empty brackets, got: %s", result)
 	}
 }
+
+func TestRenderTabQueuePosition(t *testing.T) {
+	tb := &TabBar{}
+
+	entry := TabEntry{
+		Name:      "auth",
+		LoopState: loop.LoopStateQueued,
+		QueuePos:  2,
+	}
+
+	if result := tb.renderTab(entry, 1); !strings.Contains(result, "#2") {
+		t.Errorf("expected tab to show queue position #2, got: %s", result)
+	}
+	if result := tb.renderCompactTab(entry, 1); !strings.Contains(result, "2") {
+		t.Errorf("expected compact tab to show queue position 2, got: %s", result)
+	}
+}